@@ -0,0 +1,103 @@
+package quickbolt
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.etcd.io/bbolt"
+)
+
+// Agg selects the aggregates computed by Aggregate.
+type Agg struct {
+	// Count counts the key-value pairs.
+	Count bool
+	// SumInt sums the values as decimal integers, as written for values of type int.
+	SumInt bool
+	// SumFloat sums the values as decimal floating-point numbers.
+	SumFloat bool
+	// MinKey finds the lowest key.
+	MinKey bool
+	// MaxKey finds the highest key.
+	MaxKey bool
+	// AvgValueLen averages the length of the values in bytes.
+	AvgValueLen bool
+}
+
+// Aggregates holds the results of Aggregate. Fields not selected by the Agg given to Aggregate are left zero.
+type Aggregates struct {
+	Count       int
+	SumInt      int64
+	SumFloat    float64
+	MinKey      []byte
+	MaxKey      []byte
+	AvgValueLen float64
+}
+
+// aggregate computes the selected aggregates over the key-value pairs at the given path within a single transaction.
+func aggregate(db *bbolt.DB, path [][]byte, agg Agg) (Aggregates, error) {
+	if db == nil {
+		return Aggregates{}, fmt.Errorf("aggregation at %s received nil db", path)
+	}
+
+	var a Aggregates
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		var count, valueLen int
+		var minKey, maxKey []byte
+
+		c := bkt.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+
+			count++
+			valueLen += len(v)
+			if minKey == nil {
+				minKey = k
+			}
+			maxKey = k
+
+			if agg.SumInt {
+				i, err := strconv.ParseInt(string(v), 10, 64)
+				if err != nil {
+					return fmt.Errorf("error while summing value of %s as an integer: %w", k, err)
+				}
+				a.SumInt += i
+			}
+
+			if agg.SumFloat {
+				f, err := strconv.ParseFloat(string(v), 64)
+				if err != nil {
+					return fmt.Errorf("error while summing value of %s as a float: %w", k, err)
+				}
+				a.SumFloat += f
+			}
+		}
+
+		if agg.Count {
+			a.Count = count
+		}
+		if agg.MinKey {
+			a.MinKey = copyBytes(minKey)
+		}
+		if agg.MaxKey {
+			a.MaxKey = copyBytes(maxKey)
+		}
+		if agg.AvgValueLen && count > 0 {
+			a.AvgValueLen = float64(valueLen) / float64(count)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Aggregates{}, fmt.Errorf("aggregation at %s experienced error: %w", path, err)
+	}
+
+	return a, nil
+}
@@ -0,0 +1,36 @@
+package quickbolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_Aggregate(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"scores"}
+	assert.Nil(t, db.Insert("b", 10, path))
+	assert.Nil(t, db.Insert("a", -4, path))
+	assert.Nil(t, db.Insert("c", 100, path))
+	assert.Nil(t, db.InsertBucket("z", path))
+
+	all := Agg{Count: true, SumInt: true, SumFloat: true, MinKey: true, MaxKey: true, AvgValueLen: true}
+	a, err := db.Aggregate(path, all)
+	assert.Nil(t, err)
+	assert.Equal(t, Aggregates{Count: 3, SumInt: 106, SumFloat: 106, MinKey: []byte("a"), MaxKey: []byte("c"), AvgValueLen: 7.0 / 3}, a)
+
+	a, err = db.Aggregate(path, Agg{Count: true})
+	assert.Nil(t, err)
+	assert.Equal(t, Aggregates{Count: 3}, a, "unselected aggregates must be left zero")
+
+	assert.Nil(t, db.Insert("d", "x", path))
+	_, err = db.Aggregate(path, Agg{SumInt: true})
+	assert.NotNil(t, err)
+
+	_, err = db.Aggregate([]string{"missing"}, all)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,165 @@
+package quickbolt
+
+import (
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// OpKind identifies the write performed by an Op.
+type OpKind int
+
+const (
+	// OpInsert writes a key-value pair, replacing any existing value.
+	OpInsert OpKind = iota
+	// OpUpsert merges a value into any existing value for its key.
+	OpUpsert
+	// OpDelete removes a key-value pair.
+	OpDelete
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case OpInsert:
+		return "insert"
+	case OpUpsert:
+		return "upsert"
+	case OpDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("OpKind(%d)", int(k))
+	}
+}
+
+// Op is a single write within a set of writes passed to Apply.
+//
+// Key and Value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+//
+// Path must be of type []string, [][]byte, []any, or Path.
+type Op struct {
+	Kind  OpKind
+	Key   any
+	Value any
+	Path  any
+	// Merge combines existing and new values for OpUpsert.
+	Merge MergeFunc
+}
+
+// InsertOp returns an Op writing the key-value pair to the given path.
+func InsertOp(key, value, bucketPath any) Op {
+	return Op{Kind: OpInsert, Key: key, Value: value, Path: bucketPath}
+}
+
+// UpsertOp returns an Op merging the value into any existing value for the key at the given path.
+//
+// If add is nil, the db's default merge func is used.
+func UpsertOp(key, value, bucketPath any, add MergeFunc) Op {
+	return Op{Kind: OpUpsert, Key: key, Value: value, Path: bucketPath, Merge: add}
+}
+
+// DeleteOp returns an Op deleting the key at the given path.
+func DeleteOp(key, bucketPath any) Op {
+	return Op{Kind: OpDelete, Key: key, Path: bucketPath}
+}
+
+// resolvedOp is an Op with its records and path resolved.
+type resolvedOp struct {
+	kind  OpKind
+	key   []byte
+	value []byte
+	path  [][]byte
+	merge MergeFunc
+	env   writeEnv
+}
+
+// resolveOp resolves the records and path of the given op, relative to the wrapper's scope.
+func (d *dbWrapper) resolveOp(o Op) (resolvedOp, error) {
+	p, err := d.resolveBucketPath(o.Path)
+	if err != nil {
+		return resolvedOp{}, newErrBucketPathResolution("error")
+	}
+
+	k, err := d.resolveKey(o.Key)
+	if err != nil {
+		return resolvedOp{}, newErrRecordResolution("key", o.Key)
+	}
+
+	r := resolvedOp{kind: o.Kind, key: k, path: p, merge: o.Merge}
+
+	switch o.Kind {
+	case OpInsert, OpUpsert:
+		if r.value, err = resolveRecord(o.Value); err != nil {
+			return resolvedOp{}, newErrRecordResolution("value", o.Value)
+		}
+	case OpDelete:
+	default:
+		return resolvedOp{}, fmt.Errorf("unknown op kind %s", o.Kind)
+	}
+
+	return r, nil
+}
+
+// apply performs every op within a single transaction.
+func apply(db *bbolt.DB, ops []resolvedOp) error {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		for i, o := range ops {
+			if err := applyOp(tx, o); err != nil {
+				return fmt.Errorf("%s of %s in %s at index %d experienced error: %w", o.kind, o.key, o.path, i, err)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("error while applying %d ops to db: %w", len(ops), err)
+	}
+
+	return nil
+}
+
+func applyOp(tx *bbolt.Tx, o resolvedOp) error {
+	bkt, err := getCreateBucket(tx, o.path)
+	if err != nil {
+		return fmt.Errorf("error while navigating path: %w", err)
+	}
+
+	if o.kind == OpDelete {
+		key := o.env.rules.lookupKey(o.key)
+		if err := o.env.rules.beforeDelete(tx, o.path, key, bkt.Get(key)); err != nil {
+			return err
+		}
+		if err := bkt.Delete(key); err != nil {
+			return err
+		}
+
+		o.env.metrics.observeWrite(tx, 0)
+		return nil
+	}
+
+	key, err := o.env.rules.storeKey(tx, o.path, o.key)
+	if err != nil {
+		return err
+	}
+
+	oldVal := bkt.Get(key)
+
+	val := o.value
+	if o.kind == OpUpsert && oldVal != nil {
+		if o.merge == nil {
+			return fmt.Errorf("merge func is nil and no default is set")
+		}
+		if val, err = o.merge(oldVal, val); err != nil {
+			return fmt.Errorf("error while adding %s and %s: %w", oldVal, o.value, err)
+		}
+	}
+
+	if err := o.env.rules.beforePut(tx, o.path, key, oldVal, val); err != nil {
+		return err
+	}
+	if err := bkt.Put(key, val); err != nil {
+		return fmt.Errorf("error while writing: %w", err)
+	}
+
+	o.env.metrics.observeWrite(tx, len(key)+len(val))
+	return nil
+}
@@ -0,0 +1,57 @@
+package quickbolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_Apply(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	concat := func(a, b []byte) ([]byte, error) {
+		return append(append([]byte{}, a...), b...), nil
+	}
+
+	assert.Nil(t, db.Insert("old@example.com", "alice", []string{"users", "by-email"}))
+
+	err = db.Apply([]Op{
+		InsertOp("alice", `{"email":"new@example.com"}`, []string{"users"}),
+		DeleteOp("old@example.com", []string{"users", "by-email"}),
+		InsertOp("new@example.com", "alice", []string{"users", "by-email"}),
+		UpsertOp("changes", "1", []string{"stats"}, concat),
+		UpsertOp("changes", "1", []string{"stats"}, concat),
+	})
+	assert.Nil(t, err)
+
+	v, err := db.GetValue("old@example.com", []string{"users", "by-email"}, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	v, err = db.GetValue("new@example.com", []string{"users", "by-email"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", string(v))
+
+	v, err = db.GetValue("changes", []string{"stats"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "11", string(v))
+
+	assert.Nil(t, db.SetUnique([]string{"users", "by-email"}))
+
+	err = db.Apply([]Op{
+		InsertOp("bob", "{}", []string{"users"}),
+		InsertOp("other@example.com", "alice", []string{"users", "by-email"}),
+	})
+	assert.NotNil(t, err)
+
+	v, err = db.GetValue("bob", []string{"users"}, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v, "failed apply must not leave partial writes")
+
+	assert.Nil(t, db.Apply([]Op{UpsertOp("k", "v", []string{"a"}, nil)}))
+	assert.NotNil(t, db.Apply([]Op{UpsertOp("k", "v", []string{"a"}, nil)}))
+	assert.NotNil(t, db.Apply([]Op{{Kind: OpKind(9), Key: "k", Path: []string{"a"}}}))
+}
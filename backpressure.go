@@ -0,0 +1,84 @@
+package quickbolt
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// BackpressureMode determines what a streaming method does when its buffer is full.
+type BackpressureMode int
+
+const (
+	// BackpressureTimeout waits for the buffer timeout, then fails the stream with an ErrTimeout.
+	BackpressureTimeout BackpressureMode = iota
+	// BackpressureBlock waits until the buffer has room or the Backpressure's context is done,
+	// in which case the stream fails with the context's error.
+	BackpressureBlock
+	// BackpressureDrop discards items that do not fit in the buffer, counting them in the Backpressure's Dropped counter.
+	BackpressureDrop
+)
+
+// Backpressure configures how streaming methods handle a full buffer.
+//
+// The zero value is BackpressureTimeout.
+type Backpressure struct {
+	Mode BackpressureMode
+	// Context ends a BackpressureBlock stream when done. If nil, the stream blocks indefinitely.
+	Context context.Context
+	// Dropped, if not nil, is incremented for each item discarded by BackpressureDrop.
+	Dropped *atomic.Uint64
+}
+
+// sendTo sends the item to the buffer, applying the wrapper's backpressure policy if the buffer is full.
+//
+// Path and key identify the item in the error returned if the send times out.
+func sendTo[T any](buffer chan T, item T, dbWrap dbWrapper, task string, path [][]byte, key []byte) error {
+	bp := dbWrap.backpressure
+
+	switch bp.Mode {
+	case BackpressureBlock:
+		ctx := bp.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		select {
+		case buffer <- item:
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("%s stopped while waiting to send to buffer: %w", task, ctx.Err())
+		}
+	case BackpressureDrop:
+		select {
+		case buffer <- item:
+		default:
+			if bp.Dropped != nil {
+				bp.Dropped.Add(1)
+			}
+		}
+		return nil
+	default:
+		start := time.Now()
+		timer := time.NewTimer(dbWrap.bufferTimeout)
+		defer timer.Stop()
+
+		select {
+		case buffer <- item:
+			return nil
+		case <-timer.C:
+			op := task
+			if dbWrap.op != nil {
+				op = dbWrap.op.name
+			}
+			dbWrap.metrics.observeSendTimeout(op, path)
+
+			err := newErrSendTimeout(task, path, key, time.Since(start), cap(buffer))
+			logMutex.Lock()
+			dbWrap.logErr(err).Msg("")
+			logMutex.Unlock()
+			return err
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package quickbolt
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_WithBackpressure(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a"}
+	for _, k := range []string{"k1", "k2", "k3"} {
+		assert.Nil(t, db.Insert(k, "v", path))
+	}
+
+	var dropped atomic.Uint64
+	buffer := make(chan []byte, 1)
+	assert.Nil(t, db.WithBackpressure(Backpressure{Mode: BackpressureDrop, Dropped: &dropped}).KeysAt(path, true, buffer))
+	assert.Equal(t, uint64(2), dropped.Load())
+	assert.Equal(t, "k1", string(<-buffer))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*20, cancel)
+	err = db.WithBackpressure(Backpressure{Mode: BackpressureBlock, Context: ctx}).KeysAt(path, true, make(chan []byte, 1))
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	db.SetBufferTimeout(time.Millisecond * 10)
+	err = db.KeysAt(path, true, make(chan []byte, 1))
+	var timeout ErrTimeout
+	assert.True(t, errors.As(err, &timeout), "the default policy must time out")
+	assert.Equal(t, `["a"]`, timeout.Path)
+	assert.Equal(t, "k2", timeout.Key)
+	assert.Equal(t, 1, timeout.Capacity)
+	assert.GreaterOrEqual(t, timeout.Elapsed, time.Millisecond*10)
+	assert.Contains(t, err.Error(), `key "k2" in ["a"]`)
+	assert.Equal(t, uint64(1), db.Stats().BufferTimeouts)
+
+	// Views share the db with their parent, even across compaction.
+	view := db.WithBackpressure(Backpressure{Mode: BackpressureBlock})
+	assert.Nil(t, view.Compact())
+	assert.Nil(t, view.Insert("k4", "v", path))
+	v, err := db.GetValue("k4", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v", string(v))
+}
@@ -0,0 +1,230 @@
+package quickbolt
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// BackupTarget receives the snapshots streamed by BackupTo.
+type BackupTarget interface {
+	// Put stores the snapshot read from r under the given name.
+	//
+	// Size is the length of the snapshot in bytes. Put must read r until EOF or return an error.
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+}
+
+// ChecksumVerifier is implemented by backup targets able to read back what they stored.
+//
+// BackupTo compares the checksum of each snapshot it streams to a ChecksumVerifier with the one reported after storing it.
+type ChecksumVerifier interface {
+	// Checksum returns the SHA-256 checksum of the snapshot stored under the given name.
+	Checksum(ctx context.Context, name string) ([]byte, error)
+}
+
+// PutObjectFunc adapts an object store upload func, such as one wrapping an S3 PutObject call, into a BackupTarget.
+type PutObjectFunc func(ctx context.Context, name string, r io.Reader, size int64) error
+
+func (f PutObjectFunc) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	return f(ctx, name, r, size)
+}
+
+// WriterTarget returns a BackupTarget that writes snapshots to w, ignoring their names.
+func WriterTarget(w io.Writer) BackupTarget {
+	return PutObjectFunc(func(ctx context.Context, name string, r io.Reader, size int64) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// FileTarget is a BackupTarget storing snapshots as files within a directory.
+type FileTarget struct {
+	Dir string
+}
+
+// Put writes the snapshot to a file in the target's directory.
+//
+// The file only appears under its name once completely written.
+func (t FileTarget) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	if err := os.MkdirAll(t.Dir, defaultDirMode); err != nil {
+		return fmt.Errorf("error while creating %s: %w", t.Dir, err)
+	}
+
+	f, err := os.CreateTemp(t.Dir, name+".partial-*")
+	if err != nil {
+		return fmt.Errorf("error while creating file for %s: %w", name, err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("error while writing %s: %w", name, err)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("error while syncing %s: %w", name, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error while closing %s: %w", name, err)
+	}
+
+	return os.Rename(f.Name(), filepath.Join(t.Dir, name))
+}
+
+// Checksum returns the SHA-256 checksum of the snapshot file with the given name.
+func (t FileTarget) Checksum(ctx context.Context, name string) ([]byte, error) {
+	f, err := os.Open(filepath.Join(t.Dir, name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+// BackupInfo describes a snapshot written by BackupTo.
+type BackupInfo struct {
+	Name string
+	Size int64
+	// Checksum is the SHA-256 checksum of the snapshot.
+	Checksum []byte
+	Time     time.Time
+}
+
+// BackupOption configures a backup.
+type BackupOption func(*backupOptions)
+
+type backupOptions struct {
+	name     string
+	progress func(written, total int64)
+}
+
+// WithBackupName sets the name the snapshot is stored under.
+//
+// The default is the db's filename with a UTC timestamp appended.
+func WithBackupName(name string) BackupOption {
+	return func(o *backupOptions) {
+		o.name = name
+	}
+}
+
+// WithProgress sets a func called as the snapshot is streamed with the number of bytes written so far and the snapshot's total size.
+func WithProgress(f func(written, total int64)) BackupOption {
+	return func(o *backupOptions) {
+		o.progress = f
+	}
+}
+
+// backupName returns the default snapshot name for the db file at the given path.
+func backupName(path string, t time.Time) string {
+	return fmt.Sprintf("%s.%s", filepath.Base(path), t.UTC().Format("20060102T150405.000000000Z"))
+}
+
+// backupTo streams a consistent snapshot of the db to the target.
+func backupTo(ctx context.Context, db *bbolt.DB, target BackupTarget, o backupOptions) (BackupInfo, error) {
+	if db == nil {
+		return BackupInfo{}, fmt.Errorf("backup received nil db")
+	} else if target == nil {
+		return BackupInfo{}, fmt.Errorf("backup received nil target")
+	}
+
+	info := BackupInfo{Name: o.name, Time: time.Now()}
+	if info.Name == "" {
+		info.Name = backupName(db.Path(), info.Time)
+	}
+
+	pr, pw := io.Pipe()
+	sizes := make(chan int64, 1)
+	written := make(chan error, 1)
+	h := sha256.New()
+
+	go func() {
+		err := db.View(func(tx *bbolt.Tx) error {
+			sizes <- tx.Size()
+
+			w := &backupWriter{ctx: ctx, w: pw, h: h, total: tx.Size(), progress: o.progress}
+			_, err := tx.WriteTo(w)
+			return err
+		})
+
+		// Closing the writer signals the end of the snapshot, or its failure, to the target.
+		pw.CloseWithError(err)
+		close(sizes)
+		written <- err
+	}()
+
+	size, ok := <-sizes
+	if !ok {
+		err := <-written
+		return BackupInfo{}, fmt.Errorf("backup of %s experienced error while starting transaction: %w", info.Name, err)
+	}
+	info.Size = size
+
+	// The pipe's reader is closed once Put returns so that the snapshot writer is never left blocked.
+	putErr := target.Put(ctx, info.Name, pr, size)
+	pr.CloseWithError(io.ErrClosedPipe)
+	writeErr := <-written
+
+	// bbolt doesn't wrap the errors of the writer it streams to, so cancellation is checked directly.
+	if err := ctx.Err(); err != nil && (putErr != nil || writeErr != nil) {
+		return BackupInfo{}, fmt.Errorf("backup of %s was canceled: %w", info.Name, err)
+	} else if putErr != nil {
+		return BackupInfo{}, fmt.Errorf("backup of %s experienced error while storing snapshot: %w", info.Name, putErr)
+	} else if writeErr != nil {
+		return BackupInfo{}, fmt.Errorf("backup of %s experienced error while streaming snapshot: %w", info.Name, writeErr)
+	}
+
+	info.Checksum = h.Sum(nil)
+
+	if v, ok := target.(ChecksumVerifier); ok {
+		stored, err := v.Checksum(ctx, info.Name)
+		if err != nil {
+			return BackupInfo{}, fmt.Errorf("backup of %s experienced error while verifying checksum: %w", info.Name, err)
+		} else if !bytes.Equal(stored, info.Checksum) {
+			return BackupInfo{}, fmt.Errorf("backup of %s experienced checksum mismatch: streamed %x, stored %x", info.Name, info.Checksum, stored)
+		}
+	}
+
+	return info, nil
+}
+
+// backupWriter passes a snapshot to the pipe read by a BackupTarget, hashing it and reporting progress along the way.
+type backupWriter struct {
+	ctx      context.Context
+	w        *io.PipeWriter
+	h        hash.Hash
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (b *backupWriter) Write(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := b.w.Write(p)
+	b.h.Write(p[:n])
+	b.written += int64(n)
+
+	if b.progress != nil && n > 0 {
+		b.progress(b.written, b.total)
+	}
+
+	return n, err
+}
@@ -0,0 +1,91 @@
+package quickbolt
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_BackupTo(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k", "v", []string{"a"}))
+
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+
+		var last, total int64
+		info, err := db.BackupTo(context.Background(), FileTarget{Dir: dir}, WithBackupName("snap.db"), WithProgress(func(w, tot int64) {
+			last, total = w, tot
+		}))
+		assert.Nil(t, err)
+		assert.Equal(t, "snap.db", info.Name)
+		assert.Equal(t, info.Size, last)
+		assert.Equal(t, info.Size, total)
+
+		restored, err := OpenWith("snap.db", dir)
+		assert.Nil(t, err)
+		defer restored.Close()
+
+		v, err := restored.GetValue("k", []string{"a"}, true)
+		assert.Nil(t, err)
+		assert.Equal(t, "v", string(v))
+
+		matches, err := filepath.Glob(filepath.Join(dir, "*.partial-*"))
+		assert.Nil(t, err)
+		assert.Empty(t, matches)
+	})
+
+	t.Run("writer", func(t *testing.T) {
+		var buf bytes.Buffer
+		info, err := db.BackupTo(context.Background(), WriterTarget(&buf))
+		assert.Nil(t, err)
+		assert.Equal(t, info.Size, int64(buf.Len()))
+
+		sum := sha256.Sum256(buf.Bytes())
+		assert.Equal(t, sum[:], info.Checksum)
+	})
+
+	t.Run("failing target", func(t *testing.T) {
+		_, err := db.BackupTo(context.Background(), PutObjectFunc(func(ctx context.Context, name string, r io.Reader, size int64) error {
+			return fmt.Errorf("upload refused")
+		}))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := db.BackupTo(ctx, WriterTarget(io.Discard))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		_, err := db.BackupTo(context.Background(), corruptingTarget{FileTarget{Dir: t.TempDir()}})
+		assert.NotNil(t, err)
+	})
+}
+
+// corruptingTarget stores snapshots with their first byte altered.
+type corruptingTarget struct {
+	FileTarget
+}
+
+func (c corruptingTarget) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b[0]++
+	return c.FileTarget.Put(ctx, name, bytes.NewReader(b), size)
+}
@@ -0,0 +1,84 @@
+// Package badgersource provides a quickbolt.Source reading from a Badger database directory.
+//
+// It is a module of its own so that Badger's dependencies are only required by programs importing from Badger.
+package badgersource
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// KeyMapper returns the bucket path and key a Badger key is imported under.
+type KeyMapper func(key []byte) (path [][]byte, newKey []byte, err error)
+
+// IntoBucket returns a KeyMapper importing every key unchanged into the bucket at the given path.
+func IntoBucket(path [][]byte) KeyMapper {
+	return func(key []byte) ([][]byte, []byte, error) {
+		return path, key, nil
+	}
+}
+
+// Source reads the latest version of every key-value pair of a Badger database in key order.
+type Source struct {
+	db      *badger.DB
+	txn     *badger.Txn
+	it      *badger.Iterator
+	mapKey  KeyMapper
+	started bool
+	value   []byte
+}
+
+// Open opens the Badger database in the given directory as read-only.
+//
+// Each key is imported where mapKey places it. The Source must be closed once the import is done.
+func Open(dir string, mapKey KeyMapper) (*Source, error) {
+	if mapKey == nil {
+		return nil, fmt.Errorf("badger source received nil key mapper")
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(dir).WithReadOnly(true).WithLogger(nil))
+	if err != nil {
+		return nil, fmt.Errorf("error while opening badger at %s: %w", dir, err)
+	}
+
+	txn := db.NewTransaction(false)
+	return &Source{db: db, txn: txn, it: txn.NewIterator(badger.DefaultIteratorOptions), mapKey: mapKey}, nil
+}
+
+// Next returns the next entry, or io.EOF once every entry has been read.
+func (s *Source) Next() ([][]byte, []byte, []byte, error) {
+	if s.started {
+		s.it.Next()
+	} else {
+		s.it.Rewind()
+		s.started = true
+	}
+
+	if !s.it.Valid() {
+		return nil, nil, nil, io.EOF
+	}
+
+	item := s.it.Item()
+
+	value, err := item.ValueCopy(s.value[:0])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error while reading value of %s: %w", item.Key(), err)
+	}
+	s.value = value
+
+	path, key, err := s.mapKey(item.Key())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error while mapping key %s: %w", item.Key(), err)
+	}
+
+	return path, key, value, nil
+}
+
+// Close releases the Badger database.
+func (s *Source) Close() error {
+	s.it.Close()
+	s.txn.Discard()
+	return s.db.Close()
+}
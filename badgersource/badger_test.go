@@ -0,0 +1,55 @@
+package badgersource
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kindred87/quickbolt"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSource(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "badger")
+
+	bdb, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	assert.Nil(t, err)
+	wb := bdb.NewWriteBatch()
+	for i := 0; i < 2500; i++ {
+		assert.Nil(t, wb.Set([]byte(fmt.Sprintf("user:%04d", i)), []byte(fmt.Sprint(i))))
+	}
+	assert.Nil(t, wb.Set([]byte("config:theme"), []byte("light")))
+	assert.Nil(t, wb.Flush())
+	// Only the latest version of a key is imported.
+	assert.Nil(t, bdb.Update(func(txn *badger.Txn) error { return txn.Set([]byte("config:theme"), []byte("dark")) }))
+	assert.Nil(t, bdb.Close())
+
+	// Keys are split on their first colon into a bucket and key.
+	src, err := Open(dir, func(key []byte) ([][]byte, []byte, error) {
+		i := bytes.IndexByte(key, ':')
+		if i < 0 {
+			return nil, nil, fmt.Errorf("key has no prefix")
+		}
+		return [][]byte{key[:i]}, key[i+1:], nil
+	})
+	assert.Nil(t, err)
+	defer src.Close()
+
+	db, err := quickbolt.CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+	defer db.RemoveFile()
+
+	n, err := db.ImportFrom(src)
+	assert.Nil(t, err)
+	assert.Equal(t, 2501, n)
+
+	v, err := db.GetValue("2499", []string{"user"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "2499", string(v))
+
+	v, err = db.GetValue("theme", []string{"config"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "dark", string(v))
+}
@@ -1,14 +1,23 @@
 package quickbolt
 
 import (
+	"encoding"
 	"fmt"
+	"reflect"
 	"strconv"
+	"time"
 )
 
 // resolveBucketPath returns a [] byte slice representing a bucket path.
 //
-// The following types are supported: []string, [][]byte
+// The following types are supported: []string, [][]byte, []any, Path
 func resolveBucketPath(p interface{}) ([][]byte, error) {
+	return resolveNormalizedPath(p, nil)
+}
+
+// resolveNormalizedPath resolves the bucket path as resolveBucketPath does, passing segments given as strings
+// through normalize if it is not nil.
+func resolveNormalizedPath(p interface{}, normalize func(string) string) ([][]byte, error) {
 	if p == nil {
 		return nil, fmt.Errorf("path is nil")
 	}
@@ -18,10 +27,32 @@ func resolveBucketPath(p interface{}) ([][]byte, error) {
 	switch path := p.(type) {
 	case []string:
 		for _, s := range path {
+			if normalize != nil {
+				s = normalize(s)
+			}
 			resolved = append(resolved, []byte(s))
 		}
 	case [][]byte:
 		resolved = append(resolved, path...)
+	case []any:
+		// Segments are resolved like records so that buckets keyed by numeric IDs are named consistently.
+		for i, seg := range path {
+			r, err := resolveNormalizedKey(seg, normalize)
+			if err != nil {
+				return nil, fmt.Errorf("error while resolving segment %d: %w", i, err)
+			}
+			resolved = append(resolved, r)
+		}
+	case Path:
+		if path.err != nil {
+			return nil, path.err
+		}
+		for i, seg := range path.segments {
+			if normalize != nil && path.text[i] {
+				seg = []byte(normalize(string(seg)))
+			}
+			resolved = append(resolved, seg)
+		}
 	default:
 		return nil, newErrUnsupportedType("path")
 	}
@@ -29,6 +60,28 @@ func resolveBucketPath(p interface{}) ([][]byte, error) {
 	return resolved, nil
 }
 
+// resolveNormalizedKey resolves the key as resolveRecord does, passing keys given as strings
+// through normalize if it is not nil.
+func resolveNormalizedKey(key interface{}, normalize func(string) string) ([]byte, error) {
+	if s, ok := key.(string); ok && normalize != nil {
+		return []byte(normalize(s)), nil
+	}
+
+	return resolveRecord(key)
+}
+
+// Marshaler is implemented by types that resolve themselves to bytes when passed as keys, values, or path segments,
+// taking precedence over the other encodings described on DB.
+type Marshaler interface {
+	MarshalQuickbolt() ([]byte, error)
+}
+
+// recordTimeLayout is RFC 3339 with a fixed nine fractional digits, so that resolved times sort chronologically.
+const recordTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// resolveRecord resolves the given key, value, or path segment to bytes.
+//
+// The encodings are part of the on-disk format and must not change; they are documented on DB.
 func resolveRecord(r interface{}) ([]byte, error) {
 	if r == nil {
 		return nil, fmt.Errorf("record is nil")
@@ -37,21 +90,80 @@ func resolveRecord(r interface{}) ([]byte, error) {
 	var resolved []byte
 
 	switch record := r.(type) {
+	case Marshaler:
+		b, err := record.MarshalQuickbolt()
+		if err != nil {
+			return nil, fmt.Errorf("error while marshaling %T: %w", record, err)
+		}
+		resolved = b
 	case []byte:
 		resolved = append(resolved, record...)
 	case string:
 		resolved = []byte(record)
 	case int:
 		resolved = []byte(strconv.Itoa(record))
+	case int8, int16, int32, int64:
+		// Sized integers are widened so that, e.g., int32(5) and int64(5) resolve identically.
+		resolved = SortableInt64(reflect.ValueOf(record).Int())
+	case uint, uint8, uint16, uint32:
+		resolved = SortableUint64(reflect.ValueOf(record).Uint())
 	case uint64:
 		t, err := PerEndian(record)
 		if err != nil {
 			return nil, fmt.Errorf("error while resolving %d: %w", record, err)
 		}
 		resolved = t
+	case float32:
+		resolved = SortableFloat64(float64(record))
+	case float64:
+		resolved = SortableFloat64(record)
+	case bool:
+		resolved = strconv.AppendBool(nil, record)
+	case time.Time:
+		resolved = []byte(record.UTC().Format(recordTimeLayout))
+	case encoding.BinaryMarshaler:
+		b, err := record.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("error while marshaling %T: %w", record, err)
+		}
+		resolved = b
+	case encoding.TextMarshaler:
+		b, err := record.MarshalText()
+		if err != nil {
+			return nil, fmt.Errorf("error while marshaling %T: %w", record, err)
+		}
+		resolved = b
+	case fmt.Stringer:
+		resolved = []byte(record.String())
 	default:
 		return nil, newErrUnsupportedType("record")
 	}
 
 	return resolved, nil
 }
+
+// pathKey returns a string uniquely identifying the given bucket path, for use as a map key.
+func pathKey(path [][]byte) string {
+	return fmt.Sprintf("%q", path)
+}
+
+// copyBytes returns a copy of b that remains valid after the transaction it was read from ends.
+//
+// A nil slice is returned if b is nil.
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append(make([]byte, 0, len(b)), b...)
+}
+
+// copyPath returns a deep copy of the given bucket path.
+func copyPath(path [][]byte) [][]byte {
+	c := make([][]byte, len(path))
+	for i, p := range path {
+		c[i] = copyBytes(p)
+	}
+
+	return c
+}
@@ -1,12 +1,19 @@
 package quickbolt
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_resolveBucketPath(t *testing.T) {
 	target := [][]byte{[]byte("foo1"), []byte("foo2"), []byte("foo3")}
+	seven, err := PerEndian(7)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	type args struct {
 		p interface{}
@@ -18,6 +25,10 @@ func Test_resolveBucketPath(t *testing.T) {
 		wantErr bool
 	}{
 		{name: "Basic", args: args{p: []string{"foo1", "foo2", "foo3"}}, want: target, wantErr: false},
+		{name: "Path", args: args{p: P("foo1").Sub([]byte("foo2")).Sub("foo3")}, want: target, wantErr: false},
+		{name: "Mixed segments", args: args{p: []any{"foo", []byte("bar"), 7, uint64(7)}}, want: [][]byte{[]byte("foo"), []byte("bar"), []byte("7"), seven}, wantErr: false},
+		{name: "Invalid segment", args: args{p: []any{"foo", complex(1, 1)}}, wantErr: true},
+		{name: "Invalid Path", args: args{p: P("foo1", "", "foo3")}, wantErr: true},
 		{name: "Incorrect type", args: args{p: []int{1, 2, 3}}, wantErr: true},
 	}
 	for _, tt := range tests {
@@ -33,3 +44,143 @@ func Test_resolveBucketPath(t *testing.T) {
 		})
 	}
 }
+
+// quickboltID, binaryID, textID, and stringID are record types defining their own byte representations.
+type (
+	quickboltID int
+	binaryID    int
+	textID      int
+	stringID    int
+)
+
+func (id quickboltID) MarshalQuickbolt() ([]byte, error) {
+	if id < 0 {
+		return nil, errors.New("negative id")
+	}
+	return SortableInt64(int64(id)), nil
+}
+
+// MarshalBinary is ignored in favor of MarshalQuickbolt.
+func (id quickboltID) MarshalBinary() ([]byte, error) { return []byte{'b', byte(id)}, nil }
+
+func (id binaryID) MarshalBinary() ([]byte, error) {
+	if id < 0 {
+		return nil, errors.New("negative id")
+	}
+	return []byte{'b', byte(id)}, nil
+}
+
+// String is ignored in favor of MarshalBinary.
+func (id binaryID) String() string { return fmt.Sprintf("b%d", int(id)) }
+
+func (id textID) MarshalText() ([]byte, error) { return []byte(fmt.Sprintf("t%d", int(id))), nil }
+
+func (id stringID) String() string { return fmt.Sprintf("s%d", int(id)) }
+
+func Test_resolveRecord_Order(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  []any
+		parse func([]byte) (any, error)
+		want  []any
+	}{
+		{
+			name:  "signed",
+			keys:  []any{int64(10), int32(-3), int8(2), int16(-300), int64(0)},
+			parse: func(b []byte) (any, error) { return ParseSortableInt64(b) },
+			want:  []any{int64(-300), int64(-3), int64(0), int64(2), int64(10)},
+		},
+		{
+			name:  "unsigned",
+			keys:  []any{uint32(300), uint8(1), uint(70000), uint16(2)},
+			parse: func(b []byte) (any, error) { return ParseSortableUint64(b) },
+			want:  []any{uint64(1), uint64(2), uint64(300), uint64(70000)},
+		},
+		{
+			name:  "float",
+			keys:  []any{2.5, float32(-1.5), -0.25, 100.0, float32(0.5)},
+			parse: func(b []byte) (any, error) { return ParseSortableFloat64(b) },
+			want:  []any{-1.5, -0.25, 0.5, 2.5, 100.0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := CreateWith("foo.db", t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.RemoveFile()
+
+			path := []string{"a"}
+			for _, k := range tt.keys {
+				if err := db.Insert(k, "v", path); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var got []any
+			err = db.ForEachKey(path, func(k []byte) error {
+				v, err := tt.parse(k)
+				got = append(got, v)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys iterated as %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resolveRecord(t *testing.T) {
+	five, err := PerEndian(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		r       interface{}
+		want    []byte
+		wantErr bool
+	}{
+		{name: "bytes", r: []byte("a"), want: []byte("a")},
+		{name: "string", r: "a", want: []byte("a")},
+		{name: "int", r: -5, want: []byte("-5")},
+		{name: "int8", r: int8(-5), want: SortableInt64(-5)},
+		{name: "int16", r: int16(-5), want: SortableInt64(-5)},
+		{name: "int32", r: int32(-5), want: SortableInt64(-5)},
+		{name: "int64", r: int64(-5), want: SortableInt64(-5)},
+		{name: "uint", r: uint(5), want: SortableUint64(5)},
+		{name: "uint8", r: uint8(5), want: SortableUint64(5)},
+		{name: "uint16", r: uint16(5), want: SortableUint64(5)},
+		{name: "uint32", r: uint32(5), want: SortableUint64(5)},
+		{name: "uint64", r: uint64(5), want: five},
+		{name: "float32", r: float32(0.5), want: SortableFloat64(0.5)},
+		{name: "float64", r: 2.5e-10, want: SortableFloat64(2.5e-10)},
+		{name: "bool", r: true, want: []byte("true")},
+		{name: "time", r: time.Date(2024, 3, 1, 12, 0, 0, 5, time.FixedZone("", 3600)), want: []byte("2024-03-01T11:00:00.000000005Z")},
+		{name: "quickbolt marshaler", r: quickboltID(7), want: SortableInt64(7)},
+		{name: "quickbolt marshaler error", r: quickboltID(-1), wantErr: true},
+		{name: "binary marshaler", r: binaryID(7), want: []byte{'b', 7}},
+		{name: "text marshaler", r: textID(7), want: []byte("t7")},
+		{name: "stringer", r: stringID(7), want: []byte("s7")},
+		{name: "marshaler error", r: binaryID(-1), wantErr: true},
+		{name: "nil", r: nil, wantErr: true},
+		{name: "unsupported", r: complex(1, 1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRecord(tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolveRecord() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveRecord() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
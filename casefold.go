@@ -0,0 +1,188 @@
+package quickbolt
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"go.etcd.io/bbolt"
+)
+
+// foldCase returns the key with each rune replaced by a single representative of its case folding orbit,
+// such that keys differing only in case fold identically.
+//
+// The representative is the lower case of the orbit's smallest rune, so ASCII keys fold to lower case.
+// Bytes that are not valid UTF-8 are left unchanged.
+func foldCase(key []byte) []byte {
+	folded := make([]byte, 0, len(key))
+
+	for i := 0; i < len(key); {
+		r, size := utf8.DecodeRune(key[i:])
+		if r == utf8.RuneError && size <= 1 {
+			folded = append(folded, key[i])
+			i++
+			continue
+		}
+
+		smallest := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < smallest {
+				smallest = f
+			}
+		}
+
+		folded = utf8.AppendRune(folded, unicode.ToLower(smallest))
+		i += size
+	}
+
+	return folded
+}
+
+// lookupKey returns the key under which the given key is stored at the rules' path.
+//
+// A nil *bucketRules stores every key as given.
+func (r *bucketRules) lookupKey(key []byte) []byte {
+	if r == nil || !r.foldCase {
+		return key
+	}
+
+	return foldCase(key)
+}
+
+// storeKey returns the key under which the given key is to be stored at the given path,
+// recording the given key as the original of the stored one.
+//
+// A nil *bucketRules stores every key as given.
+func (r *bucketRules) storeKey(tx *bbolt.Tx, path [][]byte, key []byte) ([]byte, error) {
+	if r == nil || !r.foldCase {
+		return key, nil
+	}
+
+	folded := foldCase(key)
+
+	originals, err := getCreateIndexChild(tx, path, originalKeys)
+	if err != nil {
+		return nil, fmt.Errorf("error while navigating original key index: %w", err)
+	}
+
+	if err := originals.Put(folded, copyBytes(key)); err != nil {
+		return nil, fmt.Errorf("error while recording original key %s: %w", key, err)
+	}
+
+	return folded, nil
+}
+
+// deleteOriginalKey removes the record of the original of the given stored key.
+func deleteOriginalKey(tx *bbolt.Tx, path [][]byte, key []byte) error {
+	originals := getIndexChild(tx, path, originalKeys)
+	if originals == nil {
+		return nil
+	}
+
+	if err := originals.Delete(key); err != nil {
+		return fmt.Errorf("error while removing original key of %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// foldExistingKeys moves the pairs at the given path to their case-folded keys,
+// recording their original keys.
+//
+// ErrDuplicateValue is returned if two keys fold to the same key.
+func foldExistingKeys(db *bbolt.DB, path [][]byte, env writeEnv) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	return db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateBucket(tx, path)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		var moved [][2][]byte
+		seen := make(map[string][]byte)
+
+		c := bkt.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+
+			folded := foldCase(k)
+			if other, ok := seen[string(folded)]; ok {
+				return newErrDuplicateValue(fmt.Sprintf("key %s folded from %s and %s at %s", folded, other, k, path))
+			}
+			seen[string(folded)] = k
+
+			if string(folded) != string(k) {
+				moved = append(moved, [2][]byte{copyBytes(k), copyBytes(v)})
+			}
+		}
+
+		for _, e := range moved {
+			if err := env.rules.beforeDelete(tx, path, e[0], e[1]); err != nil {
+				return err
+			}
+			if err := bkt.Delete(e[0]); err != nil {
+				return fmt.Errorf("error while removing %s: %w", e[0], err)
+			}
+		}
+
+		for _, e := range moved {
+			k, err := env.rules.storeKey(tx, path, e[0])
+			if err != nil {
+				return err
+			}
+			if err := env.rules.beforePut(tx, path, k, nil, e[1]); err != nil {
+				return err
+			}
+			if err := bkt.Put(k, e[1]); err != nil {
+				return fmt.Errorf("error while writing %s: %w", k, err)
+			}
+
+			env.metrics.observeWrite(tx, len(k)+len(e[1]))
+		}
+
+		return nil
+	})
+}
+
+// originalKey returns the key as last written to the given path before case folding,
+// or the key itself if no original was recorded.
+//
+// If mustExist is true, an error will be returned if the key could not be found.
+func originalKey(db *bbolt.DB, key []byte, path [][]byte, mustExist bool) ([]byte, error) {
+	if db == nil {
+		return nil, fmt.Errorf("original key retrieval for %s received nil db", key)
+	}
+
+	var original []byte
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, mustExist)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil || bkt.Get(key) == nil {
+			if mustExist {
+				return newErrLocate(fmt.Sprintf("key %s at %s", key, path))
+			}
+			return nil
+		}
+
+		original = copyBytes(key)
+		if originals := getIndexChild(tx, path, originalKeys); originals != nil {
+			if o := originals.Get(key); o != nil {
+				original = copyBytes(o)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("original key retrieval for %s experienced error: %w", key, err)
+	}
+	return original, nil
+}
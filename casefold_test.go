@@ -0,0 +1,135 @@
+package quickbolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_foldCase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "Foo", want: "foo"},
+		{in: "FOO", want: "foo"},
+		{in: "ΣΊΣΥΦΟΣ", want: "σίσυφοσ"},
+		{in: "σίσυφος", want: "σίσυφοσ"},
+		{in: "K", want: "k"}, // Kelvin sign
+		{in: "a\xffB", want: "a\xffb"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, string(foldCase([]byte(tt.in))), tt.in)
+	}
+}
+
+func Test_dbWrapper_SetCaseInsensitive(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"users"}
+	assert.Nil(t, db.Insert("Alice", "1", path))
+	assert.Nil(t, db.Insert("bob", "2", path))
+
+	assert.Nil(t, db.SetCaseInsensitive(path))
+
+	v, err := db.GetValue("ALICE", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+
+	original, err := db.OriginalKey("alice", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("Alice"), original)
+
+	assert.Nil(t, db.Insert("BOB", "3", path))
+	assert.Nil(t, db.Upsert("Bob", "4", path, func(a, b []byte) ([]byte, error) { return append(a, b...), nil }))
+	v, err = db.GetValue("bob", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("34"), v)
+
+	original, err = db.OriginalKey("bob", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("Bob"), original)
+
+	keys, err := db.GetKeys("34", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("bob")}, keys)
+
+	assert.Nil(t, db.Apply([]Op{InsertOp("Carol", "5", path), DeleteOp("ALICE", path)}))
+	v, err = db.GetValue("alice", path, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+	original, err = db.OriginalKey("alice", path, false)
+	assert.Nil(t, err)
+	assert.Nil(t, original)
+
+	tx, err := db.BeginWrite()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Put("DAVE", "6", path))
+	v, err = tx.Get("dave", path)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("6"), v)
+	assert.Nil(t, tx.Commit())
+
+	assert.Nil(t, db.Delete("CAROL", path))
+	v, err = db.GetValue("carol", path, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	// Keys that fold together can't be made case-insensitive.
+	other := []string{"others"}
+	assert.Nil(t, db.Insert("X", "1", other))
+	assert.Nil(t, db.Insert("x", "2", other))
+	var dup ErrDuplicateValue
+	assert.ErrorAs(t, db.SetCaseInsensitive(other), &dup)
+	v, err = db.GetValue("X", other, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+}
+
+func Test_dbWrapper_SetCaseInsensitive_BulkWrites(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("Bar", "v", []string{"src"}))
+	for _, p := range []string{"imported", "copied", "mapped", "reencoded"} {
+		assert.Nil(t, db.SetCaseInsensitive([]string{p}))
+	}
+	assert.Nil(t, db.SetCaseInsensitive([]string{"grouped", "g"}))
+
+	_, err = db.ImportFrom(SliceSource([]PathedEntry{{Path: [][]byte{[]byte("imported")}, Key: []byte("Foo"), Value: []byte("v")}}))
+	assert.Nil(t, err)
+	_, err = db.CopyWhere([]string{"src"}, []string{"copied"}, func(k, v []byte) bool { return true })
+	assert.Nil(t, err)
+	_, err = db.MapBucket([]string{"src"}, []string{"mapped"}, func(k, v []byte) ([]byte, []byte, bool, error) { return nil, nil, false, nil })
+	assert.Nil(t, err)
+	_, err = db.GroupBy([]string{"src"}, []string{"grouped"}, func(k, v []byte) []byte { return []byte("g") })
+	assert.Nil(t, err)
+	assert.Nil(t, db.Insert("baz", "v", []string{"reencoded"}))
+	_, err = db.ReencodeKeys([]string{"reencoded"}, func(old []byte) ([]byte, error) { return []byte("Qux"), nil })
+	assert.Nil(t, err)
+
+	tests := []struct {
+		path         []string
+		key, written string
+	}{
+		{path: []string{"imported"}, key: "FOO", written: "Foo"},
+		{path: []string{"copied"}, key: "BAR", written: "Bar"},
+		{path: []string{"mapped"}, key: "BAR", written: "Bar"},
+		{path: []string{"grouped", "g"}, key: "BAR", written: "Bar"},
+		{path: []string{"reencoded"}, key: "QUX", written: "Qux"},
+	}
+	for _, tt := range tests {
+		orig, err := db.OriginalKey(tt.key, tt.path, true)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.written, string(orig), "original key at %v", tt.path)
+
+		assert.Nil(t, db.Insert(tt.key, "v2", tt.path))
+		n, err := db.CountAt(tt.path)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n, "keys at %v must fold together", tt.path)
+	}
+}
@@ -11,6 +11,41 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// NewBufferFor returns a channel for streaming the keys or values of the bucket at the given path,
+// buffered to hold every key paired with a value in the bucket, up to a capacity of 4096.
+// Nested buckets and their contents are not counted, as scans of the bucket do not stream them.
+//
+// A buffered channel lets a scan run ahead of a slow consumer instead of timing out while waiting on it.
+//
+// BucketPath must be of type []string, [][]byte, []any, or Path.
+func NewBufferFor(db DB, bucketPath any) (chan []byte, error) {
+	if db == nil {
+		c := withCallerInfo("buffer sizing", 2)
+		return nil, fmt.Errorf("%s received nil db", c)
+	}
+
+	exists, err := db.HasBucket(bucketPath)
+	if err != nil {
+		c := withCallerInfo("buffer sizing", 2)
+		return nil, fmt.Errorf("%s experienced error while checking for bucket: %w", c, err)
+	} else if !exists {
+		c := withCallerInfo("buffer sizing", 2)
+		return nil, fmt.Errorf("%s could not find bucket at %v", c, bucketPath)
+	}
+
+	n, err := db.CountAt(bucketPath)
+	if err != nil {
+		c := withCallerInfo("buffer sizing", 2)
+		return nil, fmt.Errorf("%s experienced error while counting keys: %w", c, err)
+	}
+
+	if n > maxBufferSize {
+		n = maxBufferSize
+	}
+
+	return make(chan []byte, n), nil
+}
+
 // CaptureBytes appends values from the given channel to the given slice.
 // The function executes until the channel is closed.
 //
@@ -306,10 +341,34 @@ func Convert[A any, B any](in chan A, convert func(A) (B, error), out chan B, ct
 // WorkLimit sets the limit of goroutines if >= 1.
 //
 // timeoutLog, if not nil, is written to if a buffer or concurrent operation timeout occurs.
+// Timeouts receiving from the input channel are also counted in the db's Stats.
 //
 // If a timeout is not given, quickbolt's default timeout will be used instead.
 // See quickbolt/common.go
 func DoEach[T any](in chan T, db DB, do func(T, chan T, DB) error, out chan T, workLimit int, ctx context.Context, timeoutLog io.Writer, timeout ...time.Duration) error {
+	return doEach("DoEach", in, db, do, out, workLimit, ctx, timeoutLog, timeout...)
+}
+
+// DoEachInto executes the provided function on each value received from the input channel,
+// allowing the function to send values of a different type than it receives.
+//
+// Do is provided the values received from the input channel, output channel, and database.
+//
+// WorkLimit sets the limit of goroutines if >= 1.
+//
+// timeoutLog, if not nil, is written to if a buffer or concurrent operation timeout occurs.
+// Timeouts receiving from the input channel are also counted in the db's Stats.
+//
+// If a timeout is not given, quickbolt's default timeout will be used instead.
+// See quickbolt/common.go
+func DoEachInto[I any, O any](in chan I, db DB, do func(I, chan O, DB) error, out chan O, workLimit int, ctx context.Context, timeoutLog io.Writer, timeout ...time.Duration) error {
+	return doEach("DoEachInto", in, db, do, out, workLimit, ctx, timeoutLog, timeout...)
+}
+
+// doEach implements DoEach and DoEachInto, and must be called directly by them so that their caller can be reported.
+//
+// Name is the name of the calling function, under which receive timeouts are counted in the db's Stats.
+func doEach[I any, O any](name string, in chan I, db DB, do func(I, chan O, DB) error, out chan O, workLimit int, ctx context.Context, timeoutLog io.Writer, timeout ...time.Duration) error {
 	if out != nil {
 		defer close(out)
 	}
@@ -320,13 +379,13 @@ func DoEach[T any](in chan T, db DB, do func(T, chan T, DB) error, out chan T, w
 	}
 
 	if in == nil {
-		c := withCallerInfo("channel do each", 2)
+		c := withCallerInfo("channel do each", 3)
 		return fmt.Errorf("%s received nil input channel", c)
 	} else if do == nil {
-		c := withCallerInfo("channel do each", 2)
+		c := withCallerInfo("channel do each", 3)
 		return fmt.Errorf("%s received nil do func", c)
 	} else if out == nil {
-		c := withCallerInfo("channel do each", 2)
+		c := withCallerInfo("channel do each", 3)
 		return fmt.Errorf("%s received nil output channel", c)
 	}
 
@@ -338,6 +397,12 @@ func DoEach[T any](in chan T, db DB, do func(T, chan T, DB) error, out chan T, w
 		ctx = context.Background()
 	}
 
+	// Writes made by the funcs are checked for nesting while one of the db's iterations feeds the input channel.
+	hold := func() func() { return func() {} }
+	if w, ok := db.(*dbWrapper); ok {
+		hold = func() func() { return w.holdFeed(in) }
+	}
+
 	for {
 		timer := time.NewTimer(timeout[0])
 		select {
@@ -355,7 +420,7 @@ func DoEach[T any](in chan T, db DB, do func(T, chan T, DB) error, out chan T, w
 				timer := time.NewTimer(timeout[0])
 				select {
 				case <-timer.C:
-					c := withCallerInfo("channel do each", 2)
+					c := withCallerInfo("channel do each", 3)
 					err := newErrTimeout(c, fmt.Sprintf("waiting to create new goroutine using %v", v))
 					if timeoutLog != nil {
 						logMutex.Lock()
@@ -364,14 +429,19 @@ func DoEach[T any](in chan T, db DB, do func(T, chan T, DB) error, out chan T, w
 					}
 					return err
 				default:
-					if eg.TryGo(func() error { return do(v, out, db) }) {
+					if eg.TryGo(func() error {
+						defer hold()()
+						return do(v, out, db)
+					}) {
 						break goroutineSpawn
 					}
 				}
 			}
 
 		case <-timer.C:
-			c := withCallerInfo("channel do each", 2)
+			metricsOf(db).observeReceiveTimeout(name)
+
+			c := withCallerInfo("channel do each", 3)
 			err := newErrTimeout(c, "waiting to receive from input channel")
 			if timeoutLog != nil {
 				logMutex.Lock()
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -149,3 +150,93 @@ func TestFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestDoEachInto(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	type account struct {
+		ID      string
+		Balance string
+	}
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, db.Insert(fmt.Sprintf("id%d", i), strconv.Itoa(i*100), []string{"balances"}))
+	}
+
+	keys := make(chan []byte)
+	accounts := make(chan account)
+
+	var eg errgroup.Group
+	eg.Go(func() error { return db.KeysAt([]string{"balances"}, true, keys) })
+	eg.Go(func() error {
+		return DoEachInto(keys, db, func(k []byte, out chan account, db DB) error {
+			v, err := db.GetValue(k, []string{"balances"}, true)
+			if err != nil {
+				return err
+			}
+			return Send(out, account{ID: string(k), Balance: string(v)}, nil, nil)
+		}, accounts, 4, nil, nil)
+	})
+
+	var got []account
+	assert.Nil(t, Capture(&got, accounts, nil, nil, nil))
+	assert.Nil(t, eg.Wait())
+
+	assert.Len(t, got, 10)
+	assert.Contains(t, got, account{ID: "id3", Balance: "300"})
+
+	err = DoEachInto[int, string](nil, db, func(int, chan string, DB) error { return nil }, make(chan string), 1, nil, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "channel_test.go", "errors must report the caller of DoEachInto")
+}
+
+func TestNewBufferFor(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.RunUpdate(func(tx *bbolt.Tx) error {
+		small, err := getCreateBucket(tx, [][]byte{[]byte("small")})
+		if err != nil {
+			return err
+		}
+		large, err := getCreateBucket(tx, [][]byte{[]byte("large")})
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 3; i++ {
+			small.Put([]byte(strconv.Itoa(i)), []byte("v"))
+		}
+		// Nested buckets and their contents must not inflate the buffer.
+		nested, err := small.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 10; i++ {
+			nested.Put([]byte(strconv.Itoa(i)), []byte("v"))
+		}
+		for i := 0; i < maxBufferSize+1; i++ {
+			large.Put([]byte(strconv.Itoa(i)), []byte("v"))
+		}
+		return nil
+	}))
+
+	buffer, err := NewBufferFor(db, []string{"small"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, cap(buffer))
+
+	// A fully buffered scan completes without a consumer.
+	assert.Nil(t, db.KeysAt([]string{"small"}, true, buffer))
+	assert.Len(t, buffer, 3)
+
+	buffer, err = NewBufferFor(db, []string{"large"})
+	assert.Nil(t, err)
+	assert.Equal(t, maxBufferSize, cap(buffer))
+
+	_, err = NewBufferFor(db, []string{"missing"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,68 @@
+package quickbolt
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes and decodes the values written and read by the typed helpers, PutObject, and GetObject.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes values as JSON. It is the default codec.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec encodes values via encoding/gob.
+//
+// Each value is encoded with its own type description, so values are larger than those of a shared gob stream.
+// Concrete types held in interface fields must be registered via gob.Register.
+type GobCodec struct{}
+
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// MsgpackCodec encodes values as MessagePack, which is more compact than JSON and faster to decode.
+//
+// Struct fields are named per their msgpack tags, e.g. `msgpack:"name"`.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// codecOf returns the codec used for values at the given path of the db.
+//
+// JSONCodec is used for DB implementations from outside this package.
+func codecOf(db DB, path [][]byte) Codec {
+	if c, ok := db.(interface{ codecFor([][]byte) Codec }); ok {
+		return c.codecFor(path)
+	}
+
+	return JSONCodec{}
+}
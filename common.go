@@ -1,13 +1,47 @@
 package quickbolt
 
 import (
+	"os"
 	"sync"
 	"time"
 )
 
 const (
-	rootBucket           = "root"
-	defaultBufferTimeout = time.Second * 1
+	rootBucket             = "root"
+	defaultBufferTimeout   = time.Second * 1
+	defaultFileMode        = os.FileMode(0600)
+	defaultDirMode         = os.FileMode(0700)
+	tempFilename           = "quickbolt.db"
+	indexBucket            = "index" // indexBucket is the top-level bucket holding value indexes, separate from the root.
+	indexEntries           = "\x00"  // indexEntries is the key of the bucket holding a path's index within the index tree.
+	expiryByTime           = "\x01"  // expiryByTime is the key of the bucket mapping write times to keys within the index tree.
+	expiryByKey            = "\x02"  // expiryByKey is the key of the bucket mapping keys to write times and value checksums within the index tree.
+	orderByKey             = "\x03"  // orderByKey is the key of the bucket holding keys in the order given by an OrderFunc within the index tree.
+	originalKeys           = "\x04"  // originalKeys is the key of the bucket mapping case-folded keys to their original keys within the index tree.
+	expireBatchSize        = 1000    // expireBatchSize is the number of keys ExpireBefore deletes per transaction.
+	metaBucket             = "meta"  // metaBucket is the top-level bucket holding quickbolt's own bookkeeping, separate from the root.
+	seedsBucket            = "seeds" // seedsBucket is the bucket within the meta bucket recording completed seeds.
+	locksBucket            = "locks" // locksBucket is the bucket within the meta bucket holding advisory locks.
+	lockPollInterval       = time.Millisecond * 10
+	importBatchSize        = 1000                   // importBatchSize is the number of entries ImportFrom writes per transaction.
+	reencodeBucket         = "reencode"             // reencodeBucket is the bucket within the meta bucket holding the progress of key re-encodings.
+	reencodeBatchSize      = 1000                   // reencodeBatchSize is the number of keys ReencodeKeys processes per transaction.
+	shardsBucket           = "shards"               // shardsBucket is the bucket within the meta bucket recording the shard count of each sharded bucket.
+	expiredBucket          = "expired"              // expiredBucket is the bucket within the meta bucket holding expired pairs awaiting delivery to OnExpire funcs.
+	expiringBucket         = "expiring"             // expiringBucket is the bucket within the meta bucket recording the paths registered via ExpireIndex.
+	leasesBucket           = "leases"               // leasesBucket is the bucket within the meta bucket holding leases and their attached keys.
+	slowOpThreshold        = time.Millisecond * 100 // slowOpThreshold is the duration at which an operation is recorded as slow.
+	slowOpLogSize          = 100                    // slowOpLogSize is the number of slow operations kept for DebugHandler.
+	debugPrefix            = "/debug/quickbolt/"
+	debugBrowseLimit       = 100    // debugBrowseLimit is the default number of entries DebugHandler's key browser lists per page.
+	mapsBucket             = "maps" // mapsBucket is the bucket within the meta bucket holding the progress of MapBucket transforms.
+	mapBatchSize           = 1000   // mapBatchSize is the number of entries MapBucket processes per transaction.
+	whereBatchSize         = 1000   // whereBatchSize is the number of matching entries DeleteWhere and UpdateWhere process per transaction.
+	groupBatchSize         = 1000   // groupBatchSize is the number of entries GroupBy processes per transaction.
+	maxBufferSize          = 4096   // maxBufferSize is the largest capacity NewBufferFor gives a channel.
+	compactSuffix          = ".compact"
+	compactTxMaxSize       = 65536
+	defaultCompactInterval = time.Minute
 )
 
 var (
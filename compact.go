@@ -0,0 +1,219 @@
+package quickbolt
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// CompactSchedule controls when automatic compaction may run.
+type CompactSchedule struct {
+	// Interval is the time between fragmentation checks.
+	//
+	// The default is 1 minute.
+	Interval time.Duration
+	// Quiet is how long the database must go without operations before compaction may start.
+	Quiet time.Duration
+}
+
+// autoCompactPolicy holds the settings for automatic compaction.
+type autoCompactPolicy struct {
+	threshold float64
+	schedule  CompactSchedule
+}
+
+// WithAutoCompact enables automatic compaction of the database.
+//
+// The ratio of free pages to total pages is checked per the schedule. If it exceeds the threshold,
+// the database is compacted and the compacted file swapped in place of the original.
+//
+// Compaction only begins during a quiet window, when no operations are in progress and none
+// have occurred within the schedule's Quiet duration.
+func WithAutoCompact(threshold float64, schedule CompactSchedule) Option {
+	if schedule.Interval <= 0 {
+		schedule.Interval = defaultCompactInterval
+	}
+
+	return func(o *options) {
+		o.autoCompact = &autoCompactPolicy{threshold: threshold, schedule: schedule}
+	}
+}
+
+// autoCompact periodically compacts the database per the given policy until background work is stopped.
+func (d *dbWrapper) autoCompact(policy autoCompactPolicy) {
+	ticker := time.NewTicker(policy.schedule.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.state.stop:
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, d.state.lastUse.Load())) < policy.schedule.Quiet {
+				continue
+			}
+
+			// TryLock is used so that compaction never stalls operations waiting on one another, such as
+			// a DoEach func reading from the db while an iteration holds a transaction open.
+			if !d.state.gate.TryLock() {
+				continue
+			}
+
+			if err := d.compactIfFragmented(policy.threshold); err != nil {
+				logMutex.Lock()
+				d.logger.Err(err).Str("operation", "Compact").Msg("automatic compaction")
+				logMutex.Unlock()
+				d.notifyErr("Compact", nil, err)
+			}
+
+			d.state.gate.Unlock()
+		}
+	}
+}
+
+// compactIfFragmented compacts the database if its ratio of free pages exceeds the threshold.
+//
+// The caller must hold the database exclusively.
+func (d *dbWrapper) compactIfFragmented(threshold float64) error {
+	ratio, err := freeRatio(d.db)
+	if err != nil {
+		return fmt.Errorf("error while measuring fragmentation: %w", err)
+	}
+
+	if ratio <= threshold {
+		return nil
+	}
+
+	return d.compact()
+}
+
+// freeRatio returns the ratio of free and pending pages to the total pages in the database.
+func freeRatio(db *bbolt.DB) (float64, error) {
+	if db == nil {
+		return 0, fmt.Errorf("db is nil")
+	}
+
+	var size int64
+	err := db.View(func(tx *bbolt.Tx) error {
+		size = tx.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error while reading db size: %w", err)
+	}
+
+	pages := size / int64(db.Info().PageSize)
+	if pages == 0 {
+		return 0, nil
+	}
+
+	stats := db.Stats()
+
+	return float64(stats.FreePageN+stats.PendingPageN) / float64(pages), nil
+}
+
+// VacuumStats describes the free space within the database file, for judging whether compaction is worthwhile.
+type VacuumStats struct {
+	// FreePages is the number of pages on the freelist, available for reuse by writes.
+	FreePages int
+	// PendingPages is the number of pages freed by writes but still held by open read transactions.
+	PendingPages int
+	// FreelistBytes is the size of the freelist itself.
+	FreelistBytes int
+	// FileBytes is the size of the database file.
+	FileBytes int64
+	// ReclaimableBytes estimates how much smaller the file would be after compaction.
+	ReclaimableBytes int64
+}
+
+// vacuumStats returns the free space statistics of the database.
+func vacuumStats(db *bbolt.DB) (VacuumStats, error) {
+	if db == nil {
+		return VacuumStats{}, fmt.Errorf("free space reporting received nil db")
+	}
+
+	var size int64
+	err := db.View(func(tx *bbolt.Tx) error {
+		size = tx.Size()
+		return nil
+	})
+	if err != nil {
+		return VacuumStats{}, fmt.Errorf("free space reporting experienced error while reading db size: %w", err)
+	}
+
+	file, err := dbFileSize(db)
+	if err != nil {
+		return VacuumStats{}, fmt.Errorf("free space reporting experienced error: %w", err)
+	}
+
+	stats := db.Stats()
+
+	// Compaction keeps only the pages in use, and the file is otherwise padded beyond them as its mapping grows.
+	reclaimable := file - (size - int64(stats.FreeAlloc))
+	if reclaimable < 0 {
+		reclaimable = 0
+	}
+
+	return VacuumStats{
+		FreePages:        stats.FreePageN,
+		PendingPages:     stats.PendingPageN,
+		FreelistBytes:    stats.FreelistInuse,
+		FileBytes:        file,
+		ReclaimableBytes: reclaimable,
+	}, nil
+}
+
+// compact rewrites the database into a new file and swaps it in place of the original.
+//
+// The caller must hold the database exclusively.
+func (d *dbWrapper) compact() error {
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	path := d.db.Path()
+	tmp := path + compactSuffix
+	os.Remove(tmp)
+
+	dst, err := openBolt(tmp, d.opts)
+	if err != nil {
+		return fmt.Errorf("error while opening compaction target %s: %w", tmp, err)
+	}
+
+	if err := bbolt.Compact(dst, d.db, compactTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("error while compacting %s: %w", path, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error while closing compaction target %s: %w", tmp, err)
+	}
+
+	if err := d.db.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error while closing %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return d.reopen(path, fmt.Errorf("error while replacing %s: %w", path, err))
+	}
+
+	return d.reopen(path, nil)
+}
+
+// reopen opens the database at the given path in place of the closed one, returning cause if reopening succeeds.
+func (d *dbWrapper) reopen(path string, cause error) error {
+	db, err := openBolt(path, d.opts)
+	if err != nil {
+		return fmt.Errorf("error while reopening %s: %w", path, err)
+	}
+
+	d.db = db
+
+	return cause
+}
@@ -0,0 +1,109 @@
+package quickbolt
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+// fragment fills a bucket and then deletes most of it, leaving the db with many free pages.
+func fragment(t *testing.T, db DB) {
+	path := [][]byte{[]byte("bulk")}
+	value := []byte(strings.Repeat("x", 1024))
+
+	err := db.RunUpdate(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateBucket(tx, path)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 2000; i++ {
+			if err := bkt.Put([]byte(strconv.Itoa(i)), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	err = db.RunUpdate(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateBucket(tx, path)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 1999; i++ {
+			if err := bkt.Delete([]byte(strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	return info.Size()
+}
+
+func Test_dbWrapper_Compact(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	fragment(t, db)
+	before := fileSize(t, db.Path())
+
+	assert.Nil(t, db.Compact())
+	assert.Less(t, fileSize(t, db.Path()), before)
+
+	v, err := db.GetValue("1999", []string{"bulk"}, true)
+	assert.Nil(t, err)
+	assert.Len(t, v, 1024)
+}
+
+func TestWithAutoCompact(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithAutoCompact(0.5, CompactSchedule{Interval: time.Millisecond * 10}))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	fragment(t, db)
+	before := fileSize(t, db.Path())
+
+	assert.Eventually(t, func() bool { return fileSize(t, db.Path()) < before }, time.Second*5, time.Millisecond*10)
+
+	v, err := db.GetValue("1999", []string{"bulk"}, true)
+	assert.Nil(t, err)
+	assert.Len(t, v, 1024)
+}
+
+func Test_dbWrapper_VacuumStats(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	fragment(t, db)
+
+	stats, err := db.VacuumStats()
+	assert.Nil(t, err)
+	assert.Greater(t, stats.FreePages+stats.PendingPages, 0)
+	assert.Equal(t, fileSize(t, db.Path()), stats.FileBytes)
+	assert.Greater(t, stats.ReclaimableBytes, int64(0))
+
+	assert.Nil(t, db.Compact())
+
+	after := fileSize(t, db.Path())
+	assert.InDelta(t, stats.FileBytes-stats.ReclaimableBytes, after, float64(stats.FileBytes)/10, "estimate should be near the compacted size")
+
+	compacted, err := db.VacuumStats()
+	assert.Nil(t, err)
+	assert.Less(t, compacted.ReclaimableBytes, stats.ReclaimableBytes/10)
+}
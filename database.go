@@ -1,125 +1,352 @@
 package quickbolt
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
 	"go.etcd.io/bbolt"
 )
 
+// DB is a bbolt database behind a streamlined API.
+//
+// Keys, values, and the segments of []any bucket paths are resolved to bytes as follows:
+//   - Types implementing Marshaler are written as returned by MarshalQuickbolt.
+//   - []byte and string are used as is.
+//   - int is written as a decimal string, e.g. -5 as "-5", which is the form Aggregate's sums and CaptureBytes's
+//     numeric slices parse.
+//   - int8, int16, int32, and int64 are written as by SortableInt64, so that they sort numerically.
+//   - uint, uint8, uint16, and uint32 are written as by SortableUint64, so that they sort numerically.
+//   - uint64 is written as 8 bytes in the host's byte order, as by PerEndian, which sorts numerically only on big-endian hosts.
+//   - float32 and float64 are written as by SortableFloat64, so that they sort numerically.
+//   - Bools are written as "true" or "false".
+//   - time.Time is written in UTC as RFC 3339 with nine fractional digits, so that times sort chronologically.
+//   - Other types implementing encoding.BinaryMarshaler, encoding.TextMarshaler, or fmt.Stringer are
+//     written as returned by the first of MarshalBinary, MarshalText, or String they implement.
+//
+// The encodings of int and uint64 predate the others and are kept for compatibility with existing files.
+// Keys of those types meant to iterate in numeric order should be given as int64 or via SortableUint64 instead.
 type DB interface {
 	// Upsert writes the key-value pair to the db at the given path.
 	// If the key is already present in the db, then the sum of the existing and given values via add() will be inserted instead.
 	//
-	// Key and value must be of type []byte, string, int, or uint64.
+	// Key and value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	//
 	// Buckets in the path are created if they do not already exist.
+	//
+	// If add is nil, the func set via SetDefaultMerge is used.
 	Upsert(key, value, bucketPath any, add func(a, b []byte) ([]byte, error)) error
+	// UpsertMany upserts every entry to the db at the given path within a single transaction,
+	// so either all of the entries are written or none are.
+	//
+	// Entries sharing a key are merged in order.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	UpsertMany(entries []Entry, bucketPath any, add MergeFunc) error
+	// Apply performs every op within a single transaction, so either all of them are written or none are.
+	//
+	// Ops are applied in order and may target any path. Use InsertOp, UpsertOp, and DeleteOp to create them.
+	Apply(ops []Op) error
 	// Insert writes the given key-value pair to the db at the given path.
 	//
-	// Key and value must be of type []byte, string, int, or uint64.
+	// Key and value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	//
 	// Buckets in the path are created if they do not already exist.
 	Insert(key, value, bucketPath any) error
 	// InsertValue writes the given value to the db at the given path using an automatically generated key.
-	// The key will be a string-converted integer.
+	// The key will be a string-converted integer, unless a generator was set via WithKeyGenerator.
 	//
-	// Value must be of type []byte, string, int, or uint64.
+	// Value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	//
 	// Buckets in the path are created if they do not already exist.
 	InsertValue(value, bucketPath any) error
+	// InsertValueWith writes the given value to the db at the given path using an automatically generated key,
+	// as configured by opts, and returns the key.
+	//
+	// Value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	//
+	// Buckets in the path are created if they do not already exist.
+	InsertValueWith(value, bucketPath any, opts InsertValueOpts) ([]byte, error)
+	// InsertValues writes the given values to the db at the given path in a single transaction using automatically
+	// generated keys, and returns the keys in the order of the values.
+	//
+	// The keys are generated as with InsertValue, from a contiguous block of the bucket's sequence
+	// that concurrent insertions cannot interleave with.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	//
+	// Buckets in the path are created if they do not already exist.
+	InsertValues(values [][]byte, bucketPath any) ([][]byte, error)
 	// InsertBucket creates a bucket of the given key in the db at the given path.
 	//
-	// Key must be of type []byte, string, int, or uint64.
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	//
 	// Buckets in the path are created uf they do not already exist.
 	InsertBucket(key, bucketPath any) error
 	// Delete removes the key-value pair in the db at the given path.
 	//
-	// Key must be of type []byte, string, int, or uint64.
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	Delete(key, bucketPath any) error
+	// DeleteKeys removes the keys in the db at the given path within a single transaction.
+	//
+	// The returned report holds an error for each key, in order, which is nil if the key was removed.
+	// Keys that cannot be resolved or are not present at the path, including keys naming buckets, are reported
+	// rather than aborting the deletion. The returned error is non-nil only if the transaction failed as a whole,
+	// in which case no keys were removed.
+	//
+	// Keys must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	DeleteKeys(keys []any, bucketPath any) ([]error, error)
 	// DeleteBucket removes the bucket in the db at the given path.
 	//
-	// Key must be of type []byte, string, int, or uint64.
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	DeleteBucket(key, bucketPath any) error
 	// DeleteValues removes all key-value pairs in the db at the given path where the value matches the one given.
 	//
-	// Value must be of type []byte, string, int, or uint64.
+	// Value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	DeleteValues(value, bucketPath any) error
-	// GetValue returns the value paired with the given key.
+	// GetValue returns a copy of the value paired with the given key.
 	// The returned value will be nil if the key could not be found.
 	//
-	// Key must be of type []byte, string, int, or uint64.
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	//
 	// If mustExist is true, an error will be returned if the key could not be found.
 	GetValue(key, bucketPath any, mustExist bool) ([]byte, error)
+	// GetValueWith returns the value paired with the given key, as configured by opts.
+	// The value is copied unless opts.NoCopy is set.
+	//
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	GetValueWith(key, bucketPath any, opts GetOpts) ([]byte, error)
+	// GetValueOK returns the value paired with the given key and whether the key was found.
+	//
+	// Unlike GetValue, a key paired with an empty value is reported as found.
+	//
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	GetValueOK(key, bucketPath any) ([]byte, bool, error)
 	// GetKey returns the key paired with the given value.
 	// The returned key will be nil if the value could not be found.
 	//
-	// Value must be of type []byte, string, int, or uint64.
+	// Value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	//
 	// If mustExist is true, an error will be returned if the value could not be found.
 	GetKey(value, bucketPath any, mustExist bool) ([]byte, error)
 	// GetKeys returns a slice of keys paired with the given value.
 	// The returned slice will be nil if the value could not be found.
 	//
-	// Value must be of type []byte, string, int, or uint64.
+	// Value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	//
 	// If mustExist is true, an error will be returned if the value could not be found.
 	GetKeys(value, bucketPath any, mustExist bool) ([][]byte, error)
+	// HasKey reports whether the key is paired with a value at the given path, without copying the value.
+	// Keys naming nested buckets are not reported.
+	//
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	HasKey(key, bucketPath any) (bool, error)
+	// HasBucket reports whether a bucket exists at the given path.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	HasBucket(bucketPath any) (bool, error)
+	// CountAt returns the number of key-value pairs at the given path, not counting nested buckets,
+	// or 0 if the bucket does not exist. The pairs are counted within the read transaction rather than streamed.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	CountAt(bucketPath any) (int, error)
 	// GetFirstKeyAt returns the first key at the given path.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	//
 	// If mustExist is true, an error will be returned if the key could not be found.
 	GetFirstKeyAt(bucketPath any, mustExist bool) ([]byte, error)
+	// GetFirstEntryAt returns the first key-value pair at the given path within a single transaction.
+	// Nested buckets are skipped, and a zero Entry is returned if the bucket holds no pairs.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	//
+	// If mustExist is true, an error will be returned if the pair could not be found.
+	GetFirstEntryAt(bucketPath any, mustExist bool) (Entry, error)
+	// GetLastEntryAt returns the last key-value pair at the given path within a single transaction.
+	// Nested buckets are skipped, and a zero Entry is returned if the bucket holds no pairs.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	//
+	// If mustExist is true, an error will be returned if the pair could not be found.
+	GetLastEntryAt(bucketPath any, mustExist bool) (Entry, error)
 	// ValuesAt returns the values for all the keys at the given path.
+	// The values sent are copies and remain valid after the scan ends.
 	//
-	// Key and val must be of type []byte, string, int, or uint64.
+	// Key and val must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	ValuesAt(bucketPath any, mustExist bool, buffer chan []byte) error
-	// KeysAt returns the keys at the given path.
+	// KeysAt returns the keys at the given path, skipping nested buckets; ChildrenAt includes them.
+	// The keys sent are copies and remain valid after the scan ends; ForEachKey avoids the copies
+	// for consumers that can do their work within the read transaction.
 	//
-	// Key and val must be of type []byte, string, int, or uint64.
+	// Key and val must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	KeysAt(bucketPath any, mustExist bool, buffer chan []byte) error
+	// KeysMatching returns the keys at the given path matched by the regular expression, in key order.
+	// The keys sent are copies and remain valid after the scan ends. Nested buckets are skipped.
+	//
+	// If the expression is anchored to the start of the key by a literal prefix, e.g. ^user:[0-9]+$,
+	// only the keys with that prefix are scanned. Otherwise, every key at the path is.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	KeysMatching(bucketPath any, re *regexp.Regexp, buffer chan []byte) error
+	// ChildrenAt returns the keys and nested bucket names at the given path in key order, tagging each
+	// as one or the other, so that a bucket's full contents can be enumerated in one pass.
+	// The names sent are copies and remain valid after the scan ends.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	ChildrenAt(bucketPath any, mustExist bool, buffer chan Child) error
 	// EntriesAt returns the key-value pairs at the given path.
+	// The pairs sent are copies and remain valid after the scan ends; ForEachEntry avoids the copies
+	// for consumers that can do their work within the read transaction.
 	//
-	// Key and val must be of type []byte, string, int, or uint64.
+	// Key and val must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	EntriesAt(bucketPath any, mustExist bool, buffer chan [2][]byte) error
+	// ForEachEntry calls fn with each key-value pair at the given path, in key order, from within a read transaction.
+	//
+	// Iteration stops at the first error fn returns, which is then returned wrapped.
+	// If fn returns ErrStop, iteration stops and nil is returned.
+	// Nothing is visited if the bucket does not exist.
+	//
+	// The given keys and values are only valid until fn returns and must be copied to be retained.
+	// fn must not write to the db, since the read transaction is held open while it runs.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	ForEachEntry(bucketPath any, fn func(k, v []byte) error) error
+	// Join sends the entries of the buckets at pathA and pathB that share keys to the buffer, in key order,
+	// reading both buckets once within a single read transaction. Nested buckets are skipped.
+	//
+	// With JoinInner, only keys present in both buckets are sent.
+	// With JoinLeft, every key in the first bucket is sent, with a nil B for keys missing from the second.
+	//
+	// PathA and pathB must be of type []string, [][]byte, []any, or Path.
+	Join(pathA, pathB any, buffer chan JoinedEntry, kind JoinKind) error
+	// Aggregate computes the aggregates selected by agg over the key-value pairs at the given path
+	// within a single read transaction. Nested buckets are skipped.
+	//
+	// An error is returned if SumInt or SumFloat is selected and a value cannot be parsed as a number.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	Aggregate(bucketPath any, agg Agg) (Aggregates, error)
+	// ForEachKey calls fn with each key at the given path, in key order, from within a read transaction.
+	// Keys of nested buckets are skipped.
+	//
+	// Iteration stops at the first error fn returns, which is then returned wrapped.
+	// If fn returns ErrStop, iteration stops and nil is returned.
+	// Nothing is visited if the bucket does not exist.
+	//
+	// The given keys are only valid until fn returns and must be copied to be retained.
+	// fn must not write to the db, since the read transaction is held open while it runs.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	ForEachKey(bucketPath any, fn func(k []byte) error) error
 	// BucketsAt returns the buckets at the given path.
+	// The bucket names sent are copies and remain valid after the scan ends.
 	//
-	// Key and val must be of type []byte, string, int, or uint64.
+	// Key and val must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
 	//
-	// BucketPath must be of type []string or [][]byte.
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
 	BucketsAt(bucketPath any, mustExist bool, buffer chan []byte) error
+	// BucketsAtRecursive returns the full path of every bucket nested under the given path, depth-first.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	BucketsAtRecursive(bucketPath any, mustExist bool, buffer chan [][]byte) error
+	// EntriesAtRecursive returns every key-value pair nested under the given path, depth-first,
+	// along with the path of the bucket containing each pair.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	EntriesAtRecursive(bucketPath any, mustExist bool, buffer chan PathedEntry) error
+	// Partitions splits the keys of the bucket at the given path into up to n contiguous ranges of roughly equal size,
+	// for scanning in parallel via EntriesInRange.
+	//
+	// The ranges are estimated from a sample of cursor seeks rather than by counting every key. Fewer than n ranges
+	// are returned if the bucket holds too few keys to split n ways.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	Partitions(bucketPath any, n int) ([]KeyRange, error)
+	// EntriesPage returns up to limit key-value pairs at the given path in key order, starting after the given key,
+	// or from the first key if after is nil. Nested buckets are skipped.
+	//
+	// Next is the key to pass as after to fetch the following page, or nil if there are no more pairs,
+	// so that pages can be served over HTTP without holding a transaction or channel open between requests.
+	// The pairs returned are copies and remain valid after the call.
+	//
+	// After must be nil or of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	EntriesPage(bucketPath, after any, limit int) (page [][2][]byte, next []byte, err error)
+	// EntriesInRange returns the key-value pairs at the given path whose keys fall within the range.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	EntriesInRange(bucketPath any, r KeyRange, buffer chan [2][]byte) error
+	// EntriesBetween returns the key-value pairs at the given path whose keys begin with a TimeKey
+	// from the given time, inclusive, to the other, exclusive, in chronological order.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	EntriesBetween(bucketPath any, from, to time.Time, buffer chan [2][]byte) error
+	// SizeOf returns the approximate size of the bucket at the given path, including everything nested under it.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	SizeOf(bucketPath any) (SizeBreakdown, error)
+	// Inspect writes a human-readable dump of the bucket at the given path and everything nested under it to w.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	Inspect(bucketPath any, w io.Writer, opts InspectOptions) error
+	// Lock acquires an advisory lock on the given key at the given path, waiting until no other holder has it
+	// or the context is done, in which case the context's error is returned.
+	//
+	// The lock expires after ttl, after which another caller may take it even if Unlock was not called.
+	// Locks are advisory: they do not prevent writes, and only coordinate callers that also use Lock.
+	//
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	Lock(ctx context.Context, key, bucketPath any, ttl time.Duration) (Unlock, error)
 	// RunView executes a custom view func on the database.
 	//
 	// Use the RootBucket method to get the database's root bucket.
@@ -128,14 +355,36 @@ type DB interface {
 	//
 	// Use the RootBucket method to get the database's root bucket.
 	RunUpdate(func(tx *bbolt.Tx) error) error
+	// RunBatch executes a custom update func on the database, coalescing it with those of concurrent
+	// RunBatch calls and the built-in writes into a single transaction, as Insert does.
+	//
+	// As with bbolt's DB.Batch, the func may be called more than once if another func in the batch fails,
+	// so it must be idempotent, and its effects are only committed once RunBatch returns nil.
+	//
+	// Use the RootBucket method to get the database's root bucket.
+	RunBatch(func(tx *bbolt.Tx) error) error
+	// BeginRead begins a read-only transaction, for control flows that cannot be expressed as a RunView func.
+	//
+	// The transaction must be ended with Rollback. A warning is logged if it is garbage collected first.
+	BeginRead() (ReadTx, error)
+	// BeginWrite begins a read-write transaction, for control flows that cannot be expressed as a RunUpdate func.
+	//
+	// The transaction must be ended with Commit or Rollback. A warning is logged if it is garbage collected first.
+	// Other writes, including those made by the goroutine holding the transaction, wait until it ends.
+	BeginWrite() (WriteTx, error)
 	// Close closes the database.
 	Close() error
 	// RemoveFile deletes the database.
+	//
+	// For databases made via CreateTemp, the enclosing temporary directory is deleted as well.
 	RemoveFile() error
 	// Size returns the Size struct for the database, used to get the file size of the db.
 	Size() Size
 	// Path returns the path of the database file.
 	Path() string
+	// Info describes how the database was opened, such as its file path, page size, and the options in effect,
+	// for printing as a diagnostic header.
+	Info() Info
 	// RootBucket returns the root bucket's identifier.
 	RootBucket() []byte
 	// AddLog provides a writer interface through which quickbolt will log buffer related errors via zerolog.
@@ -146,6 +395,339 @@ type DB interface {
 	//
 	// The default is 1 second.
 	SetBufferTimeout(time.Duration)
+	// SetDefaultMerge sets the merge func used by Upsert, UpsertMany, and UpsertOp when given a nil one.
+	//
+	// Without a default, upserts given a nil merge func fail for keys that already exist.
+	SetDefaultMerge(add MergeFunc)
+	// OnError sets a func called with every error returned by a DB method, along with the method's name
+	// and, for methods taking a bucket path, the resolved path. Errors from background work, such as
+	// automatic compaction, which are logged rather than returned, are passed to the func as well.
+	//
+	// The func is called on the goroutine that encountered the error, so it must be safe for concurrent use.
+	// A nil func removes the listener.
+	OnError(f func(op string, path [][]byte, err error))
+	// OnSizeExceeds calls f with the size of the database file each time it grows beyond the given number of bytes,
+	// so that services can alert or compact before the disk fills.
+	//
+	// The size is checked immediately and then every 10 seconds until the db is closed. The func is called once
+	// per crossing, and again only after the file has shrunk back to the threshold or below, e.g. via compaction.
+	OnSizeExceeds(bytes int64, f func(Size))
+	// LongReads returns the read transactions open longer than the age set via WithLongReadWarning, oldest first.
+	//
+	// Nil is returned if WithLongReadWarning was not given.
+	LongReads() []LongRead
+	// SetCodec sets the codec used by Get, Put, GetObject, and PutObject, unless overridden for a bucket via SetBucketCodec.
+	//
+	// The default is JSONCodec. JSONCodec, GobCodec, and MsgpackCodec are built in. A nil codec restores the default.
+	SetCodec(Codec)
+	// SetBucketCodec sets the codec used by Get, Put, GetObject, and PutObject for the bucket at the given path, overriding the db's codec.
+	//
+	// The override applies to the given bucket only, not to the buckets nested within it. A nil codec removes the override.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	SetBucketCodec(bucketPath any, c Codec) error
+	// PutObject writes the object, encoded via the codec set for the path, to the db at the given path.
+	// It is the non-generic counterpart of Put.
+	//
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	PutObject(key, obj, bucketPath any) error
+	// GetObject decodes the value paired with the given key at the given path into the object pointed to by into,
+	// via the codec set for the path. It is the non-generic counterpart of Get.
+	//
+	// An ErrLocate is returned if the key could not be found.
+	//
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	GetObject(key, bucketPath, into any) error
+	// RegisterValidator adds a validator for the key-value pairs written to the given path.
+	// Upsert, Insert, and InsertValue will return an error instead of writing if a validator rejects the pair.
+	//
+	// For Upsert, the validator receives the merged value.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	RegisterValidator(bucketPath any, validate func(k, v []byte) error) error
+	// SetUnique enables a unique-value constraint on the given path.
+	// Upsert, Insert, and InsertValue will return ErrDuplicateValue instead of writing a value already paired with another key.
+	//
+	// The constraint is backed by a value index, which is rebuilt from the bucket's current contents
+	// and also used by GetKey to locate values. Values are indexed by their SHA-256 digest,
+	// so empty values and values of any length are supported.
+	// ErrDuplicateValue is returned if the bucket already contains duplicate values.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	SetUnique(bucketPath any) error
+	// SetCaseInsensitive makes keys at the given path case-insensitive: keys are case-folded whenever they are
+	// written to or looked up at the path, so that "Foo" and "foo" name the same pair.
+	// The key as last written is kept and returned by OriginalKey, while iteration returns folded keys.
+	// Writes made via RunUpdate or RunBatch are not folded.
+	//
+	// Keys already in the bucket are moved to their folded keys. ErrDuplicateValue is returned if two of them
+	// fold to the same key, in which case nothing is moved.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	SetCaseInsensitive(bucketPath any) error
+	// OriginalKey returns the given key as last written to the given path, before case folding by SetCaseInsensitive.
+	// For paths whose keys are not folded, the key is returned as is.
+	// The returned key will be nil if the key could not be found.
+	//
+	// If mustExist is true, an error will be returned if the key could not be found.
+	//
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	OriginalKey(key, bucketPath any, mustExist bool) ([]byte, error)
+	// RegisterReference declares that values written to bucketPath must be keys in the bucket at targetPath.
+	// Upsert, Insert, and InsertValue will return ErrInvalidReference instead of writing a value lacking a matching key.
+	//
+	// Deletions in the target bucket are not checked. Use CheckReferences to find values left without a matching key.
+	//
+	// BucketPath and targetPath must be of type []string, [][]byte, []any, or Path.
+	RegisterReference(bucketPath, targetPath any) error
+	// ExpireIndex maintains an index of the write times of the keys at the given path,
+	// making KeysWrittenBefore and ExpireBefore range scans rather than walks over the whole bucket.
+	//
+	// Keys already in the bucket are indexed as if written at the time ExpireIndex is called.
+	// The registration is recorded in the db, so the index is maintained from the moment the db is next opened.
+	//
+	// Writes made via RunUpdate or RunBatch are not indexed. Pairs whose values have changed since they were
+	// indexed are skipped by KeysWrittenBefore and ExpireBefore, the latter reindexing them as written at the time
+	// it runs, but a pair rewritten with the same value keeps its indexed write time.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	ExpireIndex(bucketPath any) error
+	// RegisterOrder maintains an index of the keys at the given path in the order given by the func,
+	// for iteration via EntriesInOrder. NumericOrder, for instance, orders the keys generated by InsertValue by value.
+	//
+	// The index is rebuilt from the bucket's current keys, so RegisterOrder must be called again
+	// whenever the func changes, including after reopening the db with a new one.
+	// Writes made via RunUpdate or RunBatch are not indexed.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	RegisterOrder(bucketPath any, order OrderFunc) error
+	// EntriesInOrder returns the key-value pairs at the given path in the order registered via RegisterOrder.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	EntriesInOrder(bucketPath any, buffer chan [2][]byte) error
+	// KeysWrittenBefore returns the keys at the given path last written before the given time, oldest first.
+	//
+	// ExpireIndex must have been called for the path.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	KeysWrittenBefore(bucketPath any, t time.Time, buffer chan []byte) error
+	// ExpireBefore deletes the key-value pairs at the given path last written before the given time
+	// and returns the number deleted.
+	//
+	// ExpireIndex must have been called for the path. Pairs are deleted in batches, each within its own transaction.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	ExpireBefore(bucketPath any, t time.Time) (int, error)
+	// OnExpire registers a func called with each key-value pair ExpireBefore deletes from the given path.
+	//
+	// Delivery is at least once: expired pairs are queued within the db in the same transaction that deletes them,
+	// and are removed from the queue only after the func returns. Pairs expired while no func is registered in the
+	// current process, or left undelivered by a crash, are delivered when OnExpire is next called for the path.
+	// The func may therefore see a pair more than once.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	OnExpire(bucketPath any, f func(k, v []byte)) error
+	// Grant creates a lease that expires after ttl unless renewed with KeepAlive.
+	//
+	// When a lease expires or is revoked, every key attached to it is deleted. Expired leases are revoked
+	// by RevokeExpiredLeases, or in the background if the db was opened WithLeaseSweep.
+	Grant(ttl time.Duration) (LeaseID, error)
+	// AttachKey attaches the key at the given path to the lease, so that it is deleted with the lease.
+	//
+	// The key need not exist yet. It stays attached until the lease ends, even if rewritten in the meantime.
+	// An ErrLocate is returned if the lease does not exist or has expired.
+	//
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	AttachKey(id LeaseID, key, bucketPath any) error
+	// KeepAlive renews the lease, extending its expiry to the ttl it was granted with from now.
+	//
+	// An ErrLocate is returned if the lease does not exist or has expired.
+	KeepAlive(id LeaseID) error
+	// Revoke ends the lease immediately, deleting every key attached to it.
+	//
+	// An ErrLocate is returned if the lease does not exist.
+	Revoke(id LeaseID) error
+	// RevokeExpiredLeases revokes every expired lease, returning the number revoked.
+	RevokeExpiredLeases() (int, error)
+	// CheckReferences scans every bucket with registered references and sends each value lacking a matching key to the buffer.
+	//
+	// The buffer is closed once the scan is complete.
+	CheckReferences(buffer chan ReferenceViolation) error
+	// BackupTo streams a consistent snapshot of the database to the target, without writing it to a temporary file first.
+	//
+	// Writes may continue while the snapshot is streamed. If the target implements ChecksumVerifier,
+	// the stored snapshot is verified against the checksum of the streamed one.
+	BackupTo(ctx context.Context, target BackupTarget, opts ...BackupOption) (BackupInfo, error)
+	// ImportFrom writes every entry supplied by the source to the db and returns the number written.
+	//
+	// Entries are written in batches, each within its own transaction. If an error occurs,
+	// the entries of the batches committed before it remain in the db.
+	ImportFrom(src Source, opts ...ImportOption) (int, error)
+	// ReencodeKeys replaces every key in the bucket at the given path with the key returned by transform,
+	// returning the number of keys replaced. Nested buckets are left as is.
+	//
+	// Keys are processed in batches, each within its own transaction, and progress is recorded in the db.
+	// If the process is interrupted, calling ReencodeKeys again with the same transform resumes where it stopped.
+	// Until the re-encoding completes, the bucket may hold some of its pairs, none, or only some of their new keys.
+	//
+	// Writes to the bucket's pairs fail with an ErrAccess while the re-encoding is in progress, including after
+	// an interruption until ReencodeKeys is called again and completes, as the re-encoding would otherwise lose them.
+	// Writes made via RunUpdate or RunBatch are not checked, so their writers must be stopped beforehand.
+	//
+	// If transform fails, or maps two keys to the same new key or to the name of a nested bucket,
+	// an ErrReencode is returned and the bucket is left unchanged.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	ReencodeKeys(bucketPath any, transform func(old []byte) ([]byte, error)) (uint64, error)
+	// MapBucket writes each key-value pair in the bucket at srcPath, as transformed by fn, to the bucket at dstPath,
+	// returning the number of pairs written. Nested buckets are skipped and the source is left unchanged.
+	//
+	// Pairs are processed in batches, each within its own transaction, and progress is recorded in the db.
+	// If fn fails or the process is interrupted, calling MapBucket again with the same paths resumes after
+	// the last completed batch.
+	//
+	// The source and destination paths must differ.
+	//
+	// SrcPath and dstPath must be of type []string, [][]byte, []any, or Path.
+	MapBucket(srcPath, dstPath any, fn MapFunc) (uint64, error)
+	// CopyWhere copies the key-value pairs in the bucket at srcPath for which match returns true to the bucket at
+	// dstPath, returning the number copied. Nested buckets are skipped and the source is left unchanged.
+	//
+	// The copy is made within a single transaction, so it reflects a consistent snapshot of the source
+	// and either every matching pair is copied or none are.
+	//
+	// The given keys and values are only valid until match returns and must be copied to be retained.
+	//
+	// SrcPath and dstPath must be of type []string, [][]byte, []any, or Path.
+	CopyWhere(srcPath, dstPath any, match func(k, v []byte) bool) (int, error)
+	// DeleteWhere deletes the key-value pairs at the given path for which match returns true,
+	// returning the number deleted. Nested buckets are left as is.
+	//
+	// Matching pairs are deleted in batches, each within its own transaction. If an error occurs,
+	// the pairs deleted by earlier batches remain deleted and their number is returned with the error.
+	//
+	// The given keys and values are only valid until match returns and must be copied to be retained.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	DeleteWhere(bucketPath any, match func(k, v []byte) bool) (int, error)
+	// UpdateWhere replaces the value of each key-value pair at the given path for which match returns true
+	// with the value returned by transform, returning the number updated. Nested buckets are left as is.
+	//
+	// Matching pairs are updated in batches, each within its own transaction. If an error occurs,
+	// the pairs updated by earlier batches remain updated and their number is returned with the error.
+	//
+	// The values given to transform are copies and may be modified and returned.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	UpdateWhere(bucketPath any, match func(k, v []byte) bool, transform func(v []byte) ([]byte, error)) (int, error)
+	// GroupBy copies each key-value pair in the bucket at srcPath into the bucket nested under dstPath
+	// named by groupKey, returning the number of pairs copied. Pairs for which groupKey returns nil are skipped,
+	// as are nested buckets.
+	//
+	// The source is left unchanged so that the grouping can be checked before the source is deleted.
+	// The destination may be the source itself, in which case the groups are created alongside its pairs.
+	//
+	// Pairs are copied in batches, each within its own transaction. If an error occurs, the pairs copied by
+	// earlier batches remain and their number is returned with the error. Calling GroupBy again is safe,
+	// since pairs already copied are overwritten with the same values.
+	//
+	// The given keys and values are only valid until groupKey returns and must be copied to be retained.
+	//
+	// SrcPath and dstPath must be of type []string, [][]byte, []any, or Path.
+	GroupBy(srcPath, dstPath any, groupKey func(k, v []byte) []byte) (int, error)
+	// Compact rewrites the database into a new file without free pages and swaps it in place of the original.
+	//
+	// Compact waits for all in-progress operations to finish and blocks new ones until it is done,
+	// so it must not be called from within a RunView or RunUpdate func.
+	Compact() error
+	// Fork copies the database, as of a single read transaction, to a new file at the given path and opens it,
+	// so that the copy can diverge independently, e.g. for a dry run of bulk edits against real data.
+	// An error is returned if a file already exists at the path.
+	//
+	// The fork is opened with the db's options, and inherits its rules, buffer timeout, codec, default merge func,
+	// and scope. Changes made to the fork can be reviewed via its DiffFrom method.
+	Fork(dstPath string) (DB, error)
+	// DiffFrom sends the differences between the db and the parent, typically the db it was forked from,
+	// to the buffer in key order, depth-first. The contents of added or removed buckets are reported
+	// alongside the buckets themselves. Indexes and other bookkeeping are not compared.
+	//
+	// The comparison is made within a read transaction on each database, at the db's scope.
+	// The changes sent are copies and remain valid after the comparison ends.
+	DiffFrom(parent DB, buffer chan Change) error
+	// Reset removes every bucket and key in the database within a single transaction, along with their indexes,
+	// without closing or deleting the file. The open handle, options, and registered rules are kept, and the file
+	// keeps its size, its pages being reused by later writes.
+	//
+	// Quickbolt's own bookkeeping is kept as well, so held locks and leases stay valid, seeds already applied are not
+	// reapplied by a Seeder, and paths registered via ExpireIndex remain registered once the db is reopened.
+	//
+	// For a DB returned by Scope, only the contents of the scope's bucket are removed.
+	Reset() error
+	// RegisterPath records the path under the given name, for checking via ValidatePaths and retrieval via RegisteredPath,
+	// so that buckets created by typos in paths are caught.
+	//
+	// An ErrDuplicateValue is returned if the name is already registered to a different path.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	RegisterPath(name string, bucketPath any) error
+	// RegisteredPath returns the path registered under the given name via RegisterPath.
+	//
+	// The path includes the scope of the DB it was registered via, if any, so it is meant for use with an unscoped DB.
+	RegisteredPath(name string) (Path, bool)
+	// ValidatePaths checks that every path registered via RegisterPath exists, creating those that don't if create
+	// is true, and otherwise returning an ErrLocate naming the first missing.
+	//
+	// A warning is logged for, and the paths are returned of, the topmost buckets in the db that are neither along
+	// nor nested under a registered path.
+	ValidatePaths(create bool) ([]Path, error)
+	// AddInvariant registers the invariant, such as one made by DecodesAs, IndexOf, or NoEmptyBuckets,
+	// to be checked by every call to Verify. Its bucket paths are resolved when it is added.
+	AddInvariant(inv Invariant) error
+	// Verify checks the invariants registered via AddInvariant, followed by those given, against a single
+	// snapshot of the db, returning an ErrInvariantViolation describing the first violation found.
+	Verify(invariants ...Invariant) error
+	// VacuumStats reports the free pages within the database file and estimates the space compaction would reclaim.
+	VacuumStats() (VacuumStats, error)
+	// Stats returns the metrics collected for the database since it was opened.
+	Stats() Stats
+	// DebugHandler returns a read-only http.Handler serving the db's stats, also in the Prometheus text format, bucket size breakdowns,
+	// recent slow operations, and a key browser under /debug/quickbolt/.
+	//
+	// The handler is meant to be mounted alongside net/http/pprof, e.g. mux.Handle("/debug/quickbolt/", db.DebugHandler()).
+	// Bucket paths given to the handler are resolved within the db's scope, as for its other methods.
+	DebugHandler() http.Handler
+	// ServeSnapshot serves read-only queries over HTTP on the given address from a copy of the database,
+	// refreshed every interval, so that dashboards can query the data without opening transactions on the database
+	// or contending with its writers. Only the copy itself is taken within a read transaction.
+	//
+	// The copy is kept in a temporary file, removed once serving ends. The server offers the pages
+	// /info, /keys?path=a&path=b&after=k&limit=n, and /value?path=a&path=b&key=k, the latter two as JSON.
+	//
+	// ServeSnapshot blocks until the database is closed, returning nil, or the server fails.
+	ServeSnapshot(addr string, interval time.Duration) error
+	// WithBackpressure returns a DB whose streaming methods, such as KeysAt and EntriesAt, apply the given
+	// policy when their buffer is full.
+	//
+	// The returned DB shares the file, rules, and stats of this one, so a policy can be chosen per stream
+	// without affecting others. Settings changed on either afterward, such as the buffer timeout, are not shared.
+	WithBackpressure(bp Backpressure) DB
+	// Scope returns a DB whose methods treat every bucket path as relative to the given path,
+	// so that layered code can be handed a sub-tree of the db rather than a path prefix to thread through.
+	// Scopes nest, and the scoped DB shares the database, rules, and stats of this one.
+	//
+	// RunView, RunUpdate, RunBatch, and ApplySchema work on the whole db regardless of scope.
+	// If the path cannot be resolved, every method of the scoped DB taking a bucket path returns an error.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	Scope(bucketPath any) DB
 }
 
 // Create generates a database with the given filename and returns a DB interface encapsulating the database.
@@ -161,9 +743,25 @@ func Create(filename string, dir ...string) (DB, error) {
 		return nil, fmt.Errorf("error while resolving database path: %w", err)
 	}
 
+	return create(path, newOptions())
+}
+
+// CreateWith behaves like Create, applying the given options to the database.
+//
+// If dir is empty, the database will be created in the executable's directory.
+func CreateWith(filename, dir string, opts ...Option) (DB, error) {
+	path, err := dbPath(filename, dirArgs(dir)...)
+	if err != nil {
+		return nil, fmt.Errorf("error while resolving database path: %w", err)
+	}
+
+	return create(path, newOptions(opts...))
+}
+
+func create(path string, o options) (DB, error) {
 	os.Remove(path)
 
-	db, err := new(path)
+	db, err := new(path, o)
 	if err != nil {
 		return nil, fmt.Errorf("error while opening database: %w", err)
 	}
@@ -171,14 +769,52 @@ func Create(filename string, dir ...string) (DB, error) {
 	return db, nil
 }
 
-func new(path string) (DB, error) {
-	d, err := bbolt.Open(path, 0600, nil)
+func new(path string, o options) (*dbWrapper, error) {
+	if err := os.MkdirAll(filepath.Dir(path), o.dirMode); err != nil {
+		return nil, fmt.Errorf("error while creating directory for db at %s: %w", path, err)
+	}
+
+	d, err := openBolt(path, o)
 	if err != nil {
 		return nil, fmt.Errorf("error while opening db at %s: %w", path, err)
 	}
 
-	db := dbWrapper{db: d, bufferTimeout: defaultBufferTimeout}
-	db.logger = zerolog.New(os.Stdout)
+	if o.schema != nil {
+		if err := applySchema(d, *o.schema); err != nil {
+			d.Close()
+			return nil, fmt.Errorf("error while validating db at %s: %w", path, err)
+		}
+	}
+
+	db := dbWrapper{db: d, bufferTimeout: defaultBufferTimeout, opts: o, rules: newRuleRegistry(), state: newDBState(), metrics: newMetrics()}
+	db.logger = db.newLogger(os.Stdout)
+
+	expiring, err := expiryRegisteredPaths(d)
+	if err != nil {
+		d.Close()
+		return nil, fmt.Errorf("error while loading expiry indexes of db at %s: %w", path, err)
+	}
+	for _, p := range expiring {
+		db.rules.update(p, func(r *bucketRules) { r.expiry = true })
+	}
+
+	if o.autoCompact != nil {
+		go db.autoCompact(*o.autoCompact)
+	}
+
+	if o.leaseSweep > 0 {
+		go db.sweepLeases(o.leaseSweep)
+	}
+
+	if o.nestedTxDetection {
+		db.state.txHolders = &sync.Map{}
+		db.state.txFeeds = &sync.Map{}
+	}
+
+	if o.longRead > 0 {
+		db.state.reads = newReadTracker()
+		go db.watchReads(o.longRead)
+	}
 
 	return &db, nil
 }
@@ -195,7 +831,7 @@ func Open(filename string, dir ...string) (DB, error) {
 		return nil, fmt.Errorf("error while resolving database path: %w", err)
 	}
 
-	db, err := new(path)
+	db, err := new(path, newOptions())
 	if err != nil {
 		return nil, fmt.Errorf("error while opening database: %w", err)
 	}
@@ -203,275 +839,2476 @@ func Open(filename string, dir ...string) (DB, error) {
 	return db, nil
 }
 
-// dbWrapper is an encapsulation of a BBolt DB that implements the DB interface.
-type dbWrapper struct {
-	db            *bbolt.DB
-	logger        zerolog.Logger
-	bufferTimeout time.Duration
-}
-
-func (d dbWrapper) Upsert(key, val, path any, add func(a, b []byte) ([]byte, error)) error {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo("value upsert", 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
-	}
-
-	k, err := resolveRecord(key)
+// OpenWith behaves like Open, applying the given options to the database.
+//
+// If dir is empty, the database will be opened in the executable's directory.
+func OpenWith(filename, dir string, opts ...Option) (DB, error) {
+	path, err := dbPath(filename, dirArgs(dir)...)
 	if err != nil {
-		c := withCallerInfo("value upsert", 2)
-		return fmt.Errorf("%s %w", c, newErrRecordResolution("key", key))
+		return nil, fmt.Errorf("error while resolving database path: %w", err)
 	}
 
-	v, err := resolveRecord(val)
+	db, err := new(path, newOptions(opts...))
 	if err != nil {
-		c := withCallerInfo("value upsert", 2)
-		return fmt.Errorf("%s %w", c, newErrRecordResolution("value", val))
+		return nil, fmt.Errorf("error while opening database: %w", err)
 	}
 
-	return upsert(d.db, k, v, p, add)
+	return db, nil
 }
 
-func (d dbWrapper) Insert(key, val, path any) error {
-	p, err := resolveBucketPath(path)
+// CreateTemp generates a database inside a new temporary directory and returns a DB interface encapsulating the database.
+//
+// The directory is created via os.MkdirTemp using the given pattern.
+// Calling RemoveFile on the returned DB deletes the entire directory.
+func CreateTemp(pattern string, opts ...Option) (DB, error) {
+	o := newOptions(opts...)
+
+	dir, err := os.MkdirTemp("", pattern)
 	if err != nil {
-		c := withCallerInfo("key-value insertion", 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+		return nil, fmt.Errorf("error while creating temporary directory: %w", err)
 	}
 
-	k, err := resolveRecord(key)
-	if err != nil {
-		c := withCallerInfo("key-value insertion", 2)
-		return fmt.Errorf("%s %w", c, newErrRecordResolution("key", key))
+	if err := os.Chmod(dir, o.dirMode); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("error while setting permissions of %s: %w", dir, err)
 	}
 
-	v, err := resolveRecord(val)
+	db, err := new(filepath.Join(dir, tempFilename), o)
 	if err != nil {
-		c := withCallerInfo("key-value insertion", 2)
-		return fmt.Errorf("%s %w", c, newErrRecordResolution("value", val))
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("error while opening database: %w", err)
 	}
 
-	return insert(d.db, k, v, p)
+	db.tempDir = dir
+
+	return db, nil
 }
 
-func (d dbWrapper) InsertValue(val, path any) error {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo("value insertion", 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
-	}
+// dbWrapper is an encapsulation of a BBolt DB that implements the DB interface.
+type dbWrapper struct {
+	db            *bbolt.DB
+	logger        zerolog.Logger
+	bufferTimeout time.Duration
+	opts          options
+	tempDir       string // tempDir is the directory removed alongside the file, if set by CreateTemp.
+	rules         *ruleRegistry
+	state         *dbState
+	metrics       *metrics
+	op            *operation // op is the operation a copy of the wrapper was made for, if any.
+	codec         Codec      // codec is the codec used by the typed helpers, or nil for JSONCodec.
+	merge         MergeFunc  // merge is used by upserts given a nil merge func.
+	backpressure  Backpressure
+	parent        *dbWrapper // parent is the wrapper holding the bbolt database, if this wrapper is a view made by WithBackpressure.
+	// onError is the listener set via OnError, if any.
+	onError func(op string, path [][]byte, err error)
+	// scope prefixes the bucket paths given to the wrapper's methods, if this wrapper is a view made by Scope.
+	scope [][]byte
+	// scopeErr is the error encountered while resolving the scope, returned by every method taking a bucket path.
+	scopeErr error
+}
 
-	v, err := resolveRecord(val)
-	if err != nil {
-		c := withCallerInfo("value insertion", 2)
-		return fmt.Errorf("%s %w", c, newErrRecordResolution("value", val))
-	}
+// writeEnv returns the state consulted by write operations on the given path.
+func (d *dbWrapper) writeEnv(path [][]byte) writeEnv {
+	return writeEnv{rules: d.rules.forPath(path), metrics: d.metrics}
+}
 
-	return insertValue(d.db, v, p)
+// dbState holds the state shared by the operations on a dbWrapper.
+type dbState struct {
+	// gate is held for reading by operations using the bbolt database and for writing while it is swapped out.
+	gate     sync.RWMutex
+	lastUse  atomic.Int64 // lastUse is the time, in unix nanoseconds, an operation last released the database.
+	stop     chan struct{}
+	stopOnce sync.Once
+	reads    *readTracker // reads tracks open read transactions, if enabled via WithLongReadWarning.
+	// txHolders maps the IDs of goroutines holding transactions to the operations that opened them,
+	// if enabled via WithNestedTxDetection.
+	txHolders *sync.Map
+	// txFeeds maps the buffers being sent to from within transactions to the operations sending to them,
+	// if enabled via WithNestedTxDetection.
+	txFeeds *sync.Map
 }
 
-func (d dbWrapper) InsertBucket(key, path any) error {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo("bucket insertion", 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+func newDBState() *dbState {
+	return &dbState{stop: make(chan struct{})}
+}
+
+// acquire returns the bbolt database along with a func that must be called once the caller is done using it.
+//
+// The database will not be swapped out, such as during compaction, until every acquirer has released it.
+func (d *dbWrapper) acquire() (*bbolt.DB, func()) {
+	if d.parent != nil {
+		return d.parent.acquire()
 	}
 
-	k, err := resolveRecord(key)
-	if err != nil {
-		c := withCallerInfo("bucket insertion", 2)
-		return fmt.Errorf("%s %w", c, newErrRecordResolution("key", key))
+	if d.state == nil {
+		return d.db, func() {}
 	}
 
-	return insertBucket(d.db, k, p)
+	d.state.gate.RLock()
+
+	return d.db, func() {
+		d.state.lastUse.Store(time.Now().UnixNano())
+		d.state.gate.RUnlock()
+	}
 }
 
-func (d dbWrapper) Delete(key, path any) error {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo("key-value deletion", 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+// acquireExclusive waits for every acquirer to release the database, then returns it along with a func
+// that must be called once the caller is done using it.
+//
+// The caller may replace d.db before releasing.
+func (d *dbWrapper) acquireExclusive() (*bbolt.DB, func()) {
+	if d.parent != nil {
+		return d.parent.acquireExclusive()
 	}
 
-	k, err := resolveRecord(key)
-	if err != nil {
-		c := withCallerInfo("key-value deletion", 2)
-		return fmt.Errorf("%s %w", c, newErrRecordResolution("key", key))
+	if d.state == nil {
+		return d.db, func() {}
 	}
 
-	return delete(d.db, k, p)
+	d.state.gate.Lock()
+
+	return d.db, d.state.gate.Unlock
 }
 
-func (d dbWrapper) DeleteBucket(bucket, path any) error {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo("bucket deletion", 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+// stopBackground signals background work, such as automatic compaction, to end.
+func (d *dbWrapper) stopBackground() {
+	if d.state == nil {
+		return
 	}
 
-	b, err := resolveRecord(bucket)
+	d.state.stopOnce.Do(func() { close(d.state.stop) })
+}
+
+func (d *dbWrapper) Upsert(key, val, path any, add func(a, b []byte) ([]byte, error)) (err error) {
+	op := d.beginOp("Upsert")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
 	if err != nil {
-		c := withCallerInfo("bucket deletion", 2)
-		return fmt.Errorf("%s %w", c, newErrRecordResolution("bucket", bucket))
+		return fmt.Errorf("value upsert experienced %w", newErrBucketPathResolution("error"))
 	}
+	op.labelPath(p)
 
-	return deleteBucket(d.db, b, p)
-}
-
-func (d dbWrapper) DeleteValues(val, path any) error {
-	p, err := resolveBucketPath(path)
+	k, err := d.resolveKey(key)
 	if err != nil {
-		c := withCallerInfo("value deletion", 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+		return fmt.Errorf("value upsert %w", newErrRecordResolution("key", key))
 	}
 
 	v, err := resolveRecord(val)
 	if err != nil {
-		c := withCallerInfo("value deletion", 2)
-		return fmt.Errorf("%s %w", c, newErrRecordResolution("value", val))
+		return fmt.Errorf("value upsert %w", newErrRecordResolution("value", val))
 	}
 
-	return deleteValues(d.db, v, p)
-}
-
-func (d dbWrapper) GetValue(key, path any, mustExist bool) ([]byte, error) {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo("value retrieval", 2)
-		return nil, fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+	if add == nil {
+		add = d.merge
 	}
 
-	k, err := resolveRecord(key)
-	if err != nil {
-		c := withCallerInfo("value retrieval", 2)
-		return nil, fmt.Errorf("%s %w", c, newErrRecordResolution("key", key))
+	if err := d.waitForWrite(); err != nil {
+		return err
 	}
 
-	return getValue(d.db, k, p, mustExist)
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	err = upsert(db, k, v, p, d.holdingMerge(op, add), d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
+
+	return err
 }
 
-func (d dbWrapper) GetKey(val, path any, mustExist bool) ([]byte, error) {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo("key retrieval", 2)
-		return nil, fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
-	}
+func (d *dbWrapper) UpsertMany(entries []Entry, path any, add MergeFunc) (err error) {
+	op := d.beginOp("UpsertMany")
+	defer op.end(&err)
 
-	v, err := resolveRecord(val)
+	p, err := d.resolveBucketPath(path)
 	if err != nil {
-		c := withCallerInfo("key retrieval", 2)
-		return nil, fmt.Errorf("%s %w", c, newErrRecordResolution("value", val))
+		return fmt.Errorf("bulk upsert experienced %w", newErrBucketPathResolution("error"))
 	}
+	op.labelPath(p)
 
-	return getKey(d.db, v, p, mustExist)
-}
+	if add == nil {
+		add = d.merge
+	}
 
-func (d dbWrapper) GetKeys(val, path any, mustExist bool) ([][]byte, error) {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo("key retrieval", 2)
-		return nil, fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+	for i, e := range entries {
+		if e.Key == nil {
+			return fmt.Errorf("bulk upsert in %s received nil key at index %d", p, i)
+		} else if e.Value == nil {
+			return fmt.Errorf("bulk upsert in %s received nil value for %s", p, e.Key)
+		}
 	}
 
-	v, err := resolveRecord(val)
-	if err != nil {
-		c := withCallerInfo("key retrieval", 2)
-		return nil, fmt.Errorf("%s %w", c, newErrRecordResolution("value", val))
+	if err := d.waitForWrite(); err != nil {
+		return err
 	}
 
-	return getKeys(d.db, v, p, mustExist)
-}
+	db, release := d.acquire()
+	defer release()
 
-func (d dbWrapper) GetFirstKeyAt(path any, mustExist bool) ([]byte, error) {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("first key retrieval in %s", path), 2)
-		return nil, fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
-	}
+	start := time.Now()
+	err = upsertMany(db, entries, p, d.holdingMerge(op, add), d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
 
-	return getFirstKeyAt(d.db, p, mustExist)
+	return err
 }
 
-func (d dbWrapper) ValuesAt(path any, mustExist bool, buffer chan []byte) error {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("value iteration in %s", path), 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+func (d *dbWrapper) Apply(ops []Op) (err error) {
+	op := d.beginOp("Apply")
+	defer op.end(&err)
+
+	resolved := make([]resolvedOp, len(ops))
+	for i, o := range ops {
+		if o.Kind == OpUpsert && o.Merge == nil {
+			o.Merge = d.merge
+		}
+
+		r, err := d.resolveOp(o)
+		if err != nil {
+			return fmt.Errorf("op application at index %d experienced %w", i, err)
+		}
+		r.env = d.writeEnv(r.path)
+		resolved[i] = r
 	}
 
-	return valuesAt(d.db, p, mustExist, buffer, d)
-}
-
-func (d dbWrapper) KeysAt(path any, mustExist bool, buffer chan []byte) error {
-	p, err := resolveBucketPath(path)
-	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("key iteration in %s", path), 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+	if err := d.waitForWrite(); err != nil {
+		return err
 	}
 
-	return keysAt(d.db, p, mustExist, buffer, d)
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	err = apply(db, resolved)
+	d.metrics.observeLatency(start, err)
+
+	return err
 }
 
-func (d dbWrapper) EntriesAt(path any, mustExist bool, buffer chan [2][]byte) error {
-	p, err := resolveBucketPath(path)
+func (d *dbWrapper) Insert(key, val, path any) (err error) {
+	op := d.beginOp("Insert")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("key-value iteration in %s", path), 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+		return fmt.Errorf("key-value insertion experienced %w", newErrBucketPathResolution("error"))
 	}
+	op.labelPath(p)
 
-	return entriesAt(d.db, p, mustExist, buffer, d)
-}
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return fmt.Errorf("key-value insertion %w", newErrRecordResolution("key", key))
+	}
 
-func (d dbWrapper) BucketsAt(path any, mustExist bool, buffer chan []byte) error {
-	p, err := resolveBucketPath(path)
+	v, err := resolveRecord(val)
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("bucket iteration in %s", path), 2)
-		return fmt.Errorf("%s experienced %w", c, newErrBucketPathResolution("error"))
+		return fmt.Errorf("key-value insertion %w", newErrRecordResolution("value", val))
 	}
 
-	return bucketsAt(d.db, p, mustExist, buffer, d)
-}
+	if err := d.waitForWrite(); err != nil {
+		return err
+	}
 
-func (d dbWrapper) RunView(f func(tx *bbolt.Tx) error) error {
-	return d.db.View(f)
-}
+	db, release := d.acquire()
+	defer release()
 
-func (d dbWrapper) RunUpdate(f func(tx *bbolt.Tx) error) error {
-	return d.db.Update(f)
-}
+	start := time.Now()
+	err = insert(db, k, v, p, d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
 
-func (d dbWrapper) Close() error {
-	return closeDB(d.db)
+	return err
 }
 
-func (d dbWrapper) RemoveFile() error {
-	return removeFile(d.db)
-}
+func (d *dbWrapper) InsertValue(val, path any) (err error) {
+	op := d.beginOp("InsertValue")
+	defer op.end(&err)
 
-func (d dbWrapper) Size() Size {
-	if d.db == nil {
-		return sizeStore{}
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("value insertion experienced %w", newErrBucketPathResolution("error"))
 	}
+	op.labelPath(p)
 
-	stats, err := os.Stat(d.db.Path())
+	v, err := resolveRecord(val)
 	if err != nil {
-		return sizeStore{}
+		return fmt.Errorf("value insertion %w", newErrRecordResolution("value", val))
 	}
-	return newSizeStore(int(stats.Size() / 1048576))
+
+	if err := d.waitForWrite(); err != nil {
+		return err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	_, err = insertValue(db, v, p, InsertValueOpts{Generator: d.opts.keyGenerator}, d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
+
+	return err
+}
+
+func (d *dbWrapper) InsertValueWith(val, path any, opts InsertValueOpts) (_ []byte, err error) {
+	op := d.beginOp("InsertValueWith")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("value insertion experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	v, err := resolveRecord(val)
+	if err != nil {
+		return nil, fmt.Errorf("value insertion %w", newErrRecordResolution("value", val))
+	}
+
+	if opts.Generator == nil {
+		opts.Generator = d.opts.keyGenerator
+	}
+
+	if err := d.waitForWrite(); err != nil {
+		return nil, err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	key, err := insertValue(db, v, p, opts, d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
+
+	return key, err
+}
+
+func (d *dbWrapper) InsertValues(values [][]byte, path any) (_ [][]byte, err error) {
+	op := d.beginOp("InsertValues")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("batch value insertion experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if err := d.waitForWrite(); err != nil {
+		return nil, err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	keys, err := insertValues(db, values, p, d.opts.keyGenerator, d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("batch value insertion experienced error: %w", err)
+	}
+	return keys, nil
+}
+
+func (d *dbWrapper) InsertBucket(key, path any) (err error) {
+	op := d.beginOp("InsertBucket")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("bucket insertion experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return fmt.Errorf("bucket insertion %w", newErrRecordResolution("key", key))
+	}
+
+	if err := d.waitForWrite(); err != nil {
+		return err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	err = insertBucket(db, k, p, d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
+
+	return err
+}
+
+func (d *dbWrapper) Delete(key, path any) (err error) {
+	op := d.beginOp("Delete")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("key-value deletion experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return fmt.Errorf("key-value deletion %w", newErrRecordResolution("key", key))
+	}
+	k = d.rules.forPath(p).lookupKey(k)
+
+	if err := d.waitForWrite(); err != nil {
+		return err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	err = delete(db, k, p, d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
+
+	return err
+}
+
+func (d *dbWrapper) DeleteKeys(keys []any, path any) (_ []error, err error) {
+	op := d.beginOp("DeleteKeys")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("bulk deletion experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	rules := d.rules.forPath(p)
+	resolved := make([][]byte, len(keys))
+	report := make([]error, len(keys))
+	for i, key := range keys {
+		k, err := d.resolveKey(key)
+		if err != nil {
+			report[i] = fmt.Errorf("bulk deletion %w", newErrRecordResolution("key", key))
+			continue
+		}
+		resolved[i] = rules.lookupKey(k)
+	}
+
+	if err := d.waitForWrite(); err != nil {
+		return nil, err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	err = deleteKeys(db, resolved, p, report, d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (d *dbWrapper) DeleteBucket(bucket, path any) (err error) {
+	op := d.beginOp("DeleteBucket")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("bucket deletion experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	b, err := d.resolveKey(bucket)
+	if err != nil {
+		return fmt.Errorf("bucket deletion %w", newErrRecordResolution("bucket", bucket))
+	}
+
+	if err := d.waitForWrite(); err != nil {
+		return err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	err = deleteBucket(db, b, p, d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
+
+	return err
+}
+
+func (d *dbWrapper) DeleteValues(val, path any) (err error) {
+	op := d.beginOp("DeleteValues")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("value deletion experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	v, err := resolveRecord(val)
+	if err != nil {
+		return fmt.Errorf("value deletion %w", newErrRecordResolution("value", val))
+	}
+
+	if err := d.waitForWrite(); err != nil {
+		return err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	err = deleteValues(db, v, p, d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
+
+	return err
+}
+
+func (d *dbWrapper) GetValue(key, path any, mustExist bool) (_ []byte, err error) {
+	op := d.beginOp("GetValue")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("value retrieval experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("value retrieval %w", newErrRecordResolution("key", key))
+	}
+	k = d.rules.forPath(p).lookupKey(k)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return getValue(db, k, p, mustExist)
+}
+
+func (d *dbWrapper) GetValueWith(key, path any, opts GetOpts) (_ []byte, err error) {
+	op := d.beginOp("GetValueWith")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("value retrieval experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("value retrieval %w", newErrRecordResolution("key", key))
+	}
+	k = d.rules.forPath(p).lookupKey(k)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return getValueWith(db, k, p, opts)
+}
+
+func (d *dbWrapper) GetValueOK(key, path any) (_ []byte, _ bool, err error) {
+	op := d.beginOp("GetValueOK")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("value retrieval experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return nil, false, fmt.Errorf("value retrieval %w", newErrRecordResolution("key", key))
+	}
+	k = d.rules.forPath(p).lookupKey(k)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return getValueOK(db, k, p)
+}
+
+func (d *dbWrapper) GetKey(val, path any, mustExist bool) (_ []byte, err error) {
+	op := d.beginOp("GetKey")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("key retrieval experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	v, err := resolveRecord(val)
+	if err != nil {
+		return nil, fmt.Errorf("key retrieval %w", newErrRecordResolution("value", val))
+	}
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	if r := d.rules.forPath(p); r != nil && r.unique {
+		return getIndexedKey(db, v, p, mustExist)
+	}
+
+	return getKey(db, v, p, mustExist)
+}
+
+func (d *dbWrapper) GetKeys(val, path any, mustExist bool) (_ [][]byte, err error) {
+	op := d.beginOp("GetKeys")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("key retrieval experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	v, err := resolveRecord(val)
+	if err != nil {
+		return nil, fmt.Errorf("key retrieval %w", newErrRecordResolution("value", val))
+	}
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return getKeys(db, v, p, mustExist)
+}
+
+func (d *dbWrapper) HasKey(key, path any) (_ bool, err error) {
+	op := d.beginOp("HasKey")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return false, fmt.Errorf("key lookup for %s experienced %w", key, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return false, fmt.Errorf("key lookup %w", newErrRecordResolution("key", key))
+	}
+	k = d.rules.forPath(p).lookupKey(k)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return hasKey(db, k, p)
+}
+
+func (d *dbWrapper) HasBucket(path any) (_ bool, err error) {
+	op := d.beginOp("HasBucket")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return false, fmt.Errorf("bucket lookup for %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return hasBucket(db, p)
+}
+
+func (d *dbWrapper) CountAt(path any) (_ int, err error) {
+	op := d.beginOp("CountAt")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return 0, fmt.Errorf("counting in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return countAt(db, p)
+}
+
+func (d *dbWrapper) GetFirstKeyAt(path any, mustExist bool) (_ []byte, err error) {
+	op := d.beginOp("GetFirstKeyAt")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("first key retrieval in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return getFirstKeyAt(db, p, mustExist)
+}
+
+func (d *dbWrapper) GetFirstEntryAt(path any, mustExist bool) (_ Entry, err error) {
+	op := d.beginOp("GetFirstEntryAt")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return Entry{}, fmt.Errorf("first entry retrieval in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return getEdgeEntryAt(db, p, mustExist, false)
+}
+
+func (d *dbWrapper) GetLastEntryAt(path any, mustExist bool) (_ Entry, err error) {
+	op := d.beginOp("GetLastEntryAt")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return Entry{}, fmt.Errorf("last entry retrieval in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return getEdgeEntryAt(db, p, mustExist, true)
+}
+
+func (d *dbWrapper) ValuesAt(path any, mustExist bool, buffer chan []byte) (err error) {
+	op := d.beginOp("ValuesAt")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("value iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return valuesAt(db, p, mustExist, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) KeysAt(path any, mustExist bool, buffer chan []byte) (err error) {
+	op := d.beginOp("KeysAt")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("key iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return keysAt(db, p, mustExist, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) KeysMatching(path any, re *regexp.Regexp, buffer chan []byte) (err error) {
+	op := d.beginOp("KeysMatching")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("key matching in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return keysMatching(db, p, re, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) ChildrenAt(path any, mustExist bool, buffer chan Child) (err error) {
+	op := d.beginOp("ChildrenAt")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("child iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return childrenAt(db, p, mustExist, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) EntriesAt(path any, mustExist bool, buffer chan [2][]byte) (err error) {
+	op := d.beginOp("EntriesAt")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("key-value iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return entriesAt(db, p, mustExist, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) Join(pathA, pathB any, buffer chan JoinedEntry, kind JoinKind) (err error) {
+	op := d.beginOp("Join")
+	defer op.end(&err)
+
+	a, err := d.resolveBucketPath(pathA)
+	if err != nil {
+		return fmt.Errorf("join of %s experienced %w", pathA, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(a)
+
+	b, err := d.resolveBucketPath(pathB)
+	if err != nil {
+		return fmt.Errorf("join of %s experienced %w", pathB, newErrBucketPathResolution("error"))
+	}
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return join(db, a, b, kind, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) ForEachEntry(path any, fn func(k, v []byte) error) (err error) {
+	op := d.beginOp("ForEachEntry")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("key-value iteration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.holdTx(op)()
+
+	return forEachEntry(db, p, fn)
+}
+
+func (d *dbWrapper) Aggregate(path any, agg Agg) (_ Aggregates, err error) {
+	op := d.beginOp("Aggregate")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return Aggregates{}, fmt.Errorf("aggregation experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return aggregate(db, p, agg)
+}
+
+func (d *dbWrapper) ForEachKey(path any, fn func(k []byte) error) (err error) {
+	op := d.beginOp("ForEachKey")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("key iteration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.holdTx(op)()
+
+	return forEachKey(db, p, fn)
+}
+
+func (d *dbWrapper) BucketsAt(path any, mustExist bool, buffer chan []byte) (err error) {
+	op := d.beginOp("BucketsAt")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("bucket iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return bucketsAt(db, p, mustExist, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) BucketsAtRecursive(path any, mustExist bool, buffer chan [][]byte) (err error) {
+	op := d.beginOp("BucketsAtRecursive")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("recursive bucket iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return bucketsAtRecursive(db, p, mustExist, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) EntriesAtRecursive(path any, mustExist bool, buffer chan PathedEntry) (err error) {
+	op := d.beginOp("EntriesAtRecursive")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("recursive key-value iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return entriesAtRecursive(db, p, mustExist, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) Partitions(path any, n int) (_ []KeyRange, err error) {
+	op := d.beginOp("Partitions")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("partitioning experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return partitions(db, p, n)
+}
+
+func (d *dbWrapper) EntriesPage(path, after any, limit int) (_ [][2][]byte, _ []byte, err error) {
+	op := d.beginOp("EntriesPage")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("paged retrieval in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	var a []byte
+	if after != nil {
+		if a, err = d.resolveKey(after); err != nil {
+			return nil, nil, fmt.Errorf("paged retrieval %w", newErrRecordResolution("after key", after))
+		}
+	}
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return entriesPage(db, p, a, limit)
+}
+
+func (d *dbWrapper) EntriesInRange(path any, r KeyRange, buffer chan [2][]byte) (err error) {
+	op := d.beginOp("EntriesInRange")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("range iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return entriesInRange(db, p, r, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) EntriesBetween(path any, from, to time.Time, buffer chan [2][]byte) (err error) {
+	op := d.beginOp("EntriesBetween")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("time range iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return entriesInRange(db, p, timeRange(from, to), buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) SizeOf(path any) (_ SizeBreakdown, err error) {
+	op := d.beginOp("SizeOf")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return SizeBreakdown{}, fmt.Errorf("size estimation in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return sizeOf(db, p)
+}
+
+func (d *dbWrapper) Inspect(path any, w io.Writer, opts InspectOptions) (err error) {
+	op := d.beginOp("Inspect")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("inspection of %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return inspect(db, p, w, opts)
+}
+
+func (d *dbWrapper) Lock(ctx context.Context, key, path any, ttl time.Duration) (_ Unlock, err error) {
+	op := d.beginOp("Lock")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("locking experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("locking %w", newErrRecordResolution("key", key))
+	}
+
+	if ttl <= 0 {
+		return nil, fmt.Errorf("locking of %s received non-positive ttl %s", k, ttl)
+	}
+
+	token, err := newLockToken()
+	if err != nil {
+		return nil, fmt.Errorf("locking of %s experienced error while generating token: %w", k, err)
+	}
+
+	for {
+		// Access is acquired per attempt so that waiting for a lock doesn't hold up Compact or Close.
+		// The lock is only written to once it is seen to be free, so waiting costs no commits.
+		db, release := d.acquire()
+		untrack := d.trackRead(op)
+		wait, err := lockHeldFor(db, k, p)
+		untrack()
+		if err == nil && wait == 0 {
+			wait, err = tryLock(db, k, p, token, ttl)
+		}
+		release()
+
+		if err != nil {
+			return nil, err
+		} else if wait == 0 {
+			break
+		}
+
+		if wait > lockPollInterval {
+			wait = lockPollInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("locking of %s stopped while waiting for lock: %w", k, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	return func() (err error) {
+		op := d.beginOp("Unlock")
+		defer op.end(&err)
+
+		db, release := d.acquire()
+		defer release()
+
+		return unlock(db, k, p, token)
+	}, nil
+}
+
+func (d *dbWrapper) BackupTo(ctx context.Context, target BackupTarget, opts ...BackupOption) (_ BackupInfo, err error) {
+	op := d.beginOp("BackupTo")
+	defer op.end(&err)
+
+	var o backupOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return backupTo(ctx, db, target, o)
+}
+
+func (d *dbWrapper) ImportFrom(src Source, opts ...ImportOption) (_ int, err error) {
+	op := d.beginOp("ImportFrom")
+	defer op.end(&err)
+
+	if src == nil {
+		return 0, fmt.Errorf("import received nil source")
+	}
+
+	var o importOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+
+	envs := make(map[string]writeEnv)
+	envFor := func(path [][]byte) writeEnv {
+		key := pathKey(path)
+		env, ok := envs[key]
+		if !ok {
+			env = d.writeEnv(path)
+			envs[key] = env
+		}
+		return env
+	}
+
+	imported := 0
+	for {
+		batch, readErr := readImportBatch(src, importBatchSize)
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return imported, fmt.Errorf("import experienced error while reading source after %d entries: %w", imported+len(batch), readErr)
+		}
+
+		if len(batch) > 0 {
+			if err := d.waitForWrite(); err != nil {
+				return imported, err
+			}
+
+			db, release := d.acquire()
+			start := time.Now()
+			err := importBatch(db, batch, envFor)
+			d.metrics.observeLatency(start, err)
+			release()
+
+			if err != nil {
+				return imported, err
+			}
+			imported += len(batch)
+
+			if o.progress != nil {
+				o.progress(imported)
+			}
+		}
+
+		if readErr != nil {
+			return imported, nil
+		}
+	}
+}
+
+func (d *dbWrapper) ReencodeKeys(path any, transform func(old []byte) ([]byte, error)) (_ uint64, err error) {
+	op := d.beginOp("ReencodeKeys")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return 0, fmt.Errorf("key re-encoding experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if transform == nil {
+		return 0, fmt.Errorf("key re-encoding in %s received nil transform", p)
+	}
+
+	env := d.reencodeEnv(p)
+
+	for {
+		if err := d.waitForWrite(); err != nil {
+			return 0, err
+		}
+
+		db, release := d.acquire()
+		start := time.Now()
+		unhold := d.holdTx(op)
+		done, count, err := reencodeStep(db, p, transform, env)
+		unhold()
+		d.metrics.observeLatency(start, err)
+
+		if errors.As(err, &ErrReencode{}) {
+			if abandonErr := abandonReencode(db, p); abandonErr != nil {
+				err = fmt.Errorf("%w, and error while discarding progress: %s", err, abandonErr)
+			}
+		}
+		release()
+
+		if err != nil {
+			return 0, fmt.Errorf("key re-encoding in %s experienced error: %w", p, err)
+		} else if done {
+			return count, nil
+		}
+	}
+}
+
+func (d *dbWrapper) MapBucket(srcPath, dstPath any, fn MapFunc) (_ uint64, err error) {
+	op := d.beginOp("MapBucket")
+	defer op.end(&err)
+
+	src, err := d.resolveBucketPath(srcPath)
+	if err != nil {
+		return 0, fmt.Errorf("bucket mapping experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(src)
+
+	dst, err := d.resolveBucketPath(dstPath)
+	if err != nil {
+		return 0, fmt.Errorf("bucket mapping experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	if fn == nil {
+		return 0, fmt.Errorf("bucket mapping of %s received nil func", src)
+	} else if pathKey(src) == pathKey(dst) {
+		return 0, fmt.Errorf("bucket mapping of %s received the same source and destination", src)
+	}
+
+	env := d.writeEnv(dst)
+
+	for {
+		if err := d.waitForWrite(); err != nil {
+			return 0, err
+		}
+
+		// Access is acquired per batch so that a long mapping doesn't hold up Compact or Close.
+		db, release := d.acquire()
+		start := time.Now()
+		unhold := d.holdTx(op)
+		done, count, err := mapStep(db, src, dst, fn, env)
+		unhold()
+		d.metrics.observeLatency(start, err)
+		release()
+
+		if err != nil {
+			return 0, err
+		} else if done {
+			return count, nil
+		}
+	}
+}
+
+func (d *dbWrapper) CopyWhere(srcPath, dstPath any, match func(k, v []byte) bool) (_ int, err error) {
+	op := d.beginOp("CopyWhere")
+	defer op.end(&err)
+
+	src, err := d.resolveBucketPath(srcPath)
+	if err != nil {
+		return 0, fmt.Errorf("conditional copy experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(src)
+
+	dst, err := d.resolveBucketPath(dstPath)
+	if err != nil {
+		return 0, fmt.Errorf("conditional copy experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	if match == nil {
+		return 0, fmt.Errorf("conditional copy of %s received nil match func", src)
+	} else if pathKey(src) == pathKey(dst) {
+		return 0, fmt.Errorf("conditional copy of %s received the same source and destination", src)
+	}
+
+	if err := d.waitForWrite(); err != nil {
+		return 0, err
+	}
+
+	db, release := d.acquire()
+	defer release()
+	defer d.holdTx(op)()
+
+	start := time.Now()
+	n, err := copyWhere(db, src, dst, match, d.writeEnv(dst))
+	d.metrics.observeLatency(start, err)
+
+	return n, err
+}
+
+func (d *dbWrapper) DeleteWhere(path any, match func(k, v []byte) bool) (_ int, err error) {
+	op := d.beginOp("DeleteWhere")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return 0, fmt.Errorf("conditional deletion experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if match == nil {
+		return 0, fmt.Errorf("conditional deletion at %s received nil match func", p)
+	}
+
+	env := d.writeEnv(p)
+
+	total := 0
+	var after []byte
+	for {
+		if err := d.waitForWrite(); err != nil {
+			return total, err
+		}
+
+		// Access is acquired per batch so that a long deletion doesn't hold up Compact or Close.
+		db, release := d.acquire()
+		start := time.Now()
+		unhold := d.holdTx(op)
+		n, next, err := deleteWhereBatch(db, p, after, match, env)
+		unhold()
+		d.metrics.observeLatency(start, err)
+		release()
+
+		total += n
+		if err != nil {
+			return total, err
+		} else if next == nil {
+			return total, nil
+		}
+		after = next
+	}
+}
+
+func (d *dbWrapper) UpdateWhere(path any, match func(k, v []byte) bool, transform func(v []byte) ([]byte, error)) (_ int, err error) {
+	op := d.beginOp("UpdateWhere")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return 0, fmt.Errorf("conditional update experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if match == nil {
+		return 0, fmt.Errorf("conditional update at %s received nil match func", p)
+	} else if transform == nil {
+		return 0, fmt.Errorf("conditional update at %s received nil transform", p)
+	}
+
+	env := d.writeEnv(p)
+
+	total := 0
+	var after []byte
+	for {
+		if err := d.waitForWrite(); err != nil {
+			return total, err
+		}
+
+		// Access is acquired per batch so that a long update doesn't hold up Compact or Close.
+		db, release := d.acquire()
+		start := time.Now()
+		unhold := d.holdTx(op)
+		n, next, err := updateWhereBatch(db, p, after, match, transform, env)
+		unhold()
+		d.metrics.observeLatency(start, err)
+		release()
+
+		total += n
+		if err != nil {
+			return total, err
+		} else if next == nil {
+			return total, nil
+		}
+		after = next
+	}
+}
+
+func (d *dbWrapper) GroupBy(srcPath, dstPath any, groupKey func(k, v []byte) []byte) (_ int, err error) {
+	op := d.beginOp("GroupBy")
+	defer op.end(&err)
+
+	src, err := d.resolveBucketPath(srcPath)
+	if err != nil {
+		return 0, fmt.Errorf("grouping experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(src)
+
+	dst, err := d.resolveBucketPath(dstPath)
+	if err != nil {
+		return 0, fmt.Errorf("grouping experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	if groupKey == nil {
+		return 0, fmt.Errorf("grouping of %s received nil group key func", src)
+	}
+
+	total := 0
+	var after []byte
+	for {
+		if err := d.waitForWrite(); err != nil {
+			return total, err
+		}
+
+		// Access is acquired per batch so that a long grouping doesn't hold up Compact or Close.
+		db, release := d.acquire()
+		start := time.Now()
+		unhold := d.holdTx(op)
+		n, next, err := groupBatch(db, src, dst, after, groupKey, d.writeEnv)
+		unhold()
+		d.metrics.observeLatency(start, err)
+		release()
+
+		total += n
+		if err != nil {
+			return total, err
+		} else if next == nil {
+			return total, nil
+		}
+		after = next
+	}
+}
+
+func (d *dbWrapper) RunView(f func(tx *bbolt.Tx) error) (err error) {
+	op := d.beginOp("RunView")
+	defer op.end(&err)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.holdTx(op)()
+
+	return db.View(f)
+}
+
+func (d *dbWrapper) RunUpdate(f func(tx *bbolt.Tx) error) (err error) {
+	op := d.beginOp("RunUpdate")
+	defer op.end(&err)
+
+	if err := d.waitForWrite(); err != nil {
+		return err
+	}
+
+	db, release := d.acquire()
+	defer release()
+	defer d.holdTx(op)()
+
+	start := time.Now()
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if err := f(tx); err != nil {
+			return err
+		}
+
+		d.metrics.observeWrite(tx, 0)
+
+		return nil
+	})
+	d.metrics.observeLatency(start, err)
+
+	return err
+}
+
+func (d *dbWrapper) RunBatch(f func(tx *bbolt.Tx) error) (err error) {
+	op := d.beginOp("RunBatch")
+	defer op.end(&err)
+
+	if err := d.waitForWrite(); err != nil {
+		return err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	err = db.Batch(func(tx *bbolt.Tx) error {
+		// Batched funcs may run on a goroutine other than the caller's.
+		defer d.holdTx(op)()
+
+		if err := f(tx); err != nil {
+			return err
+		}
+
+		d.metrics.observeWrite(tx, 0)
+
+		return nil
+	})
+	d.metrics.observeLatency(start, err)
+
+	return err
+}
+
+func (d *dbWrapper) BeginRead() (_ ReadTx, err error) {
+	op := d.beginOp("BeginRead")
+	defer op.end(&err)
+
+	h, err := d.beginTx(op, false)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (d *dbWrapper) BeginWrite() (_ WriteTx, err error) {
+	op := d.beginOp("BeginWrite")
+	defer op.end(&err)
+
+	if err := d.waitForWrite(); err != nil {
+		return nil, err
+	}
+
+	h, err := d.beginTx(op, true)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (d *dbWrapper) Close() (err error) {
+	op := d.beginOp("Close")
+	defer op.end(&err)
+
+	if err := d.checkNested("closing"); err != nil {
+		return err
+	}
+
+	d.stopBackground()
+
+	db, release := d.acquireExclusive()
+	defer release()
+
+	return closeDB(db)
+}
+
+func (d *dbWrapper) RemoveFile() (err error) {
+	op := d.beginOp("RemoveFile")
+	defer op.end(&err)
+
+	if err := d.checkNested("removal"); err != nil {
+		return err
+	}
+
+	d.stopBackground()
+
+	db, release := d.acquireExclusive()
+	defer release()
+
+	if d.tempDir != "" {
+		return removeTempDir(db, d.tempDir)
+	}
+
+	return removeFile(db)
+}
+
+func (d *dbWrapper) Size() Size {
+	db, release := d.acquire()
+	defer release()
+
+	size, err := dbFileSize(db)
+	if err != nil {
+		return sizeStore{}
+	}
+	return newSizeStore(size)
+}
+
+func (d *dbWrapper) Path() string {
+	db, release := d.acquire()
+	defer release()
+
+	return db.Path()
+}
+
+func (d *dbWrapper) Info() Info {
+	return d.info()
+}
+
+func (d *dbWrapper) RootBucket() []byte {
+	return []byte(rootBucket)
+}
+
+func (d *dbWrapper) AddLog(w io.Writer) {
+	d.logger = d.holder().newLogger(w)
+}
+
+func (d *dbWrapper) SetBufferTimeout(t time.Duration) {
+	d.bufferTimeout = t
+}
+
+func (d *dbWrapper) SetDefaultMerge(add MergeFunc) {
+	d.merge = add
+}
+
+func (d *dbWrapper) OnError(f func(op string, path [][]byte, err error)) {
+	d.onError = f
+}
+
+func (d *dbWrapper) OnSizeExceeds(bytes int64, f func(Size)) {
+	h := d.holder()
+	if h.state == nil || f == nil {
+		return
+	}
+
+	go h.watchSize(bytes, f)
+}
+
+func (d *dbWrapper) LongReads() []LongRead {
+	h := d.holder()
+	if h.state == nil || h.state.reads == nil {
+		return nil
+	}
+
+	return h.state.reads.longReads(h.opts.longRead, false)
+}
+
+func (d *dbWrapper) SetCodec(c Codec) {
+	d.codec = c
+}
+
+func (d *dbWrapper) SetBucketCodec(path any, c Codec) (err error) {
+	op := d.beginOp("SetBucketCodec")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("codec registration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if d.rules == nil {
+		d.rules = newRuleRegistry()
+	}
+
+	d.rules.update(p, func(r *bucketRules) { r.codec = c })
+
+	return nil
+}
+
+func (d *dbWrapper) PutObject(key, obj, path any) (err error) {
+	op := d.beginOp("PutObject")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("object write experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return fmt.Errorf("object write %w", newErrRecordResolution("key", key))
+	}
+
+	b, err := d.codecAt(p).Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("object write of %s at %s experienced error while encoding value: %w", k, p, err)
+	}
+
+	if err := d.waitForWrite(); err != nil {
+		return err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	err = insert(db, k, b, p, d.writeEnv(p))
+	d.metrics.observeLatency(start, err)
+
+	return err
+}
+
+func (d *dbWrapper) GetObject(key, path, into any) (err error) {
+	op := d.beginOp("GetObject")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("object retrieval experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return fmt.Errorf("object retrieval %w", newErrRecordResolution("key", key))
+	}
+	k = d.rules.forPath(p).lookupKey(k)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	// The value is a copy, so it is safe to decode once the read transaction has ended.
+	b, err := getValue(db, k, p, false)
+	if err != nil {
+		return err
+	} else if b == nil {
+		return newErrLocate(fmt.Sprintf("key %s at %s", k, p))
+	}
+
+	if err := d.codecAt(p).Unmarshal(b, into); err != nil {
+		return fmt.Errorf("object retrieval of %s at %s experienced error while decoding value: %w", k, p, err)
+	}
+
+	return nil
+}
+
+// codecFor returns the codec used by the typed helpers for the given path, relative to the wrapper's scope.
+func (d *dbWrapper) codecFor(path [][]byte) Codec {
+	return d.codecAt(d.scopePath(path))
+}
+
+// codecAt returns the codec used for the given path, already prefixed with the wrapper's scope.
+func (d *dbWrapper) codecAt(path [][]byte) Codec {
+	if r := d.rules.forPath(path); r != nil && r.codec != nil {
+		return r.codec
+	} else if d.codec != nil {
+		return d.codec
+	}
+
+	return JSONCodec{}
+}
+
+func (d *dbWrapper) RegisterValidator(path any, validate func(k, v []byte) error) (err error) {
+	op := d.beginOp("RegisterValidator")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("validator registration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if validate == nil {
+		return fmt.Errorf("validator registration received nil validate func")
+	}
+
+	if d.rules == nil {
+		d.rules = newRuleRegistry()
+	}
+
+	// The validator runs within the transactions of the writes it checks, which may be batched onto other goroutines.
+	writer := &operation{name: "a write validated by the func given to RegisterValidator", caller: op.caller}
+	checked := func(k, v []byte) error {
+		defer d.holdTx(writer)()
+		return validate(k, v)
+	}
+
+	d.rules.update(p, func(r *bucketRules) { r.validators = append(r.validators, checked) })
+
+	return nil
 }
 
-func (d dbWrapper) Path() string {
-	return d.db.Path()
+func (d *dbWrapper) SetUnique(path any) (err error) {
+	op := d.beginOp("SetUnique")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("unique constraint registration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if d.rules == nil {
+		d.rules = newRuleRegistry()
+	}
+
+	// The constraint is registered first so that writes racing with the rebuild maintain the index.
+	d.rules.update(p, func(r *bucketRules) { r.unique = true })
+
+	db, release := d.acquire()
+	defer release()
+
+	if err := rebuildUniqueIndex(db, p); err != nil {
+		d.rules.update(p, func(r *bucketRules) { r.unique = false })
+		return fmt.Errorf("unique constraint registration experienced error while building index: %w", err)
+	}
+
+	return nil
 }
 
-func (d dbWrapper) RootBucket() []byte {
-	return []byte(rootBucket)
+func (d *dbWrapper) SetCaseInsensitive(path any) (err error) {
+	op := d.beginOp("SetCaseInsensitive")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("case-insensitive key registration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if d.rules == nil {
+		d.rules = newRuleRegistry()
+	}
+
+	// Folding is registered first so that writes racing with the migration are folded.
+	d.rules.update(p, func(r *bucketRules) { r.foldCase = true })
+
+	db, release := d.acquire()
+	defer release()
+
+	if err := foldExistingKeys(db, p, d.writeEnv(p)); err != nil {
+		d.rules.update(p, func(r *bucketRules) { r.foldCase = false })
+		return fmt.Errorf("case-insensitive key registration experienced error while folding keys: %w", err)
+	}
+
+	return nil
 }
 
-func (d *dbWrapper) AddLog(w io.Writer) {
-	d.logger = zerolog.New(w)
+func (d *dbWrapper) OriginalKey(key, path any, mustExist bool) (_ []byte, err error) {
+	op := d.beginOp("OriginalKey")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("original key retrieval experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("original key retrieval %w", newErrRecordResolution("key", key))
+	}
+	k = d.rules.forPath(p).lookupKey(k)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return originalKey(db, k, p, mustExist)
 }
 
-func (d *dbWrapper) SetBufferTimeout(t time.Duration) {
-	d.bufferTimeout = t
+func (d *dbWrapper) RegisterReference(path, targetPath any) (err error) {
+	op := d.beginOp("RegisterReference")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("reference registration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	t, err := d.resolveBucketPath(targetPath)
+	if err != nil {
+		return fmt.Errorf("reference registration experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	if d.rules == nil {
+		d.rules = newRuleRegistry()
+	}
+
+	d.rules.update(p, func(r *bucketRules) {
+		if !containsPath(r.references, t) {
+			r.references = append(r.references, t)
+		}
+	})
+
+	return nil
+}
+
+func (d *dbWrapper) ExpireIndex(path any) (err error) {
+	op := d.beginOp("ExpireIndex")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("expiry index registration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if d.rules == nil {
+		d.rules = newRuleRegistry()
+	}
+
+	// The index is registered first so that writes racing with the backfill are indexed.
+	d.rules.update(p, func(r *bucketRules) { r.expiry = true })
+
+	db, release := d.acquire()
+	defer release()
+
+	if err := indexUnindexedKeys(db, p, time.Now()); err != nil {
+		d.rules.update(p, func(r *bucketRules) { r.expiry = false })
+		return fmt.Errorf("expiry index registration experienced error while building index: %w", err)
+	}
+
+	return nil
+}
+
+func (d *dbWrapper) RegisterOrder(path any, order OrderFunc) (err error) {
+	op := d.beginOp("RegisterOrder")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("order registration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if order == nil {
+		return fmt.Errorf("order registration for %s received nil order func", p)
+	}
+
+	if d.rules == nil {
+		d.rules = newRuleRegistry()
+	}
+
+	// The order is registered first so that writes racing with the rebuild are indexed.
+	var previous OrderFunc
+	d.rules.update(p, func(r *bucketRules) { previous, r.order = r.order, order })
+
+	db, release := d.acquire()
+	defer release()
+
+	if err := rebuildOrderIndex(db, p, order); err != nil {
+		d.rules.update(p, func(r *bucketRules) { r.order = previous })
+		return fmt.Errorf("order registration experienced error while building index: %w", err)
+	}
+
+	return nil
+}
+
+func (d *dbWrapper) EntriesInOrder(path any, buffer chan [2][]byte) (err error) {
+	op := d.beginOp("EntriesInOrder")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("ordered key-value iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if r := d.rules.forPath(p); r == nil || r.order == nil {
+		return fmt.Errorf("ordered key-value iteration in %s experienced error: no order is registered", p)
+	}
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return entriesInOrder(db, p, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) KeysWrittenBefore(path any, t time.Time, buffer chan []byte) (err error) {
+	op := d.beginOp("KeysWrittenBefore")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("expired key iteration in %s experienced %w", path, newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return keysWrittenBefore(db, p, t, buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) ExpireBefore(path any, t time.Time) (_ int, err error) {
+	op := d.beginOp("ExpireBefore")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return 0, fmt.Errorf("expiry experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	env := d.writeEnv(p)
+
+	total := 0
+	for {
+		if err := d.waitForWrite(); err != nil {
+			return total, err
+		}
+
+		db, release := d.acquire()
+		start := time.Now()
+		n, more, err := expireBatch(db, p, t, env)
+		d.metrics.observeLatency(start, err)
+
+		if err == nil && n > 0 && env.rules != nil && env.rules.onExpire != nil {
+			var dropped [][]byte
+			dropped, err = deliverExpired(db, p, env.rules.onExpire, d.forOp(op))
+			d.logDroppedExpired(op.name, p, dropped)
+		}
+		release()
+
+		total += n
+		if err != nil {
+			return total, err
+		} else if !more {
+			return total, nil
+		}
+	}
+}
+
+func (d *dbWrapper) OnExpire(path any, f func(k, v []byte)) (err error) {
+	op := d.beginOp("OnExpire")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("expiry func registration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if f == nil {
+		return fmt.Errorf("expiry func registration for %s received nil func", p)
+	}
+
+	if d.rules == nil {
+		d.rules = newRuleRegistry()
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := getCreateExpiredOutbox(tx, p)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("expiry func registration for %s experienced error while creating queue: %w", p, err)
+	}
+
+	d.rules.update(p, func(r *bucketRules) { r.onExpire = f })
+
+	dropped, err := deliverExpired(db, p, f, d.forOp(op))
+	d.logDroppedExpired(op.name, p, dropped)
+
+	return err
+}
+
+func (d *dbWrapper) Grant(ttl time.Duration) (_ LeaseID, err error) {
+	op := d.beginOp("Grant")
+	defer op.end(&err)
+
+	if ttl <= 0 {
+		return 0, fmt.Errorf("lease grant received non-positive ttl %s", ttl)
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	return grantLease(db, ttl)
+}
+
+func (d *dbWrapper) AttachKey(id LeaseID, key, path any) (err error) {
+	op := d.beginOp("AttachKey")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("lease attachment experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	k, err := d.resolveKey(key)
+	if err != nil {
+		return fmt.Errorf("lease attachment %w", newErrRecordResolution("key", key))
+	}
+	k = d.rules.forPath(p).lookupKey(k)
+
+	db, release := d.acquire()
+	defer release()
+
+	return attachKey(db, id, k, p)
+}
+
+func (d *dbWrapper) KeepAlive(id LeaseID) (err error) {
+	op := d.beginOp("KeepAlive")
+	defer op.end(&err)
+
+	db, release := d.acquire()
+	defer release()
+
+	return keepAlive(db, id)
+}
+
+func (d *dbWrapper) Revoke(id LeaseID) (err error) {
+	op := d.beginOp("Revoke")
+	defer op.end(&err)
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	_, err = revokeLease(db, id, false, d.writeEnv)
+	d.metrics.observeLatency(start, err)
+
+	return err
+}
+
+func (d *dbWrapper) RevokeExpiredLeases() (_ int, err error) {
+	op := d.beginOp("RevokeExpiredLeases")
+	defer op.end(&err)
+
+	db, release := d.acquire()
+	untrack := d.trackRead(op)
+	ids, err := expiredLeases(db)
+	untrack()
+	release()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, id := range ids {
+		// Access is acquired per lease so that a long sweep doesn't hold up Compact or Close.
+		db, release := d.acquire()
+		start := time.Now()
+		revoked, err := revokeLease(db, id, true, d.writeEnv)
+		d.metrics.observeLatency(start, err)
+		release()
+
+		if errors.Is(err, ErrLocate{}) {
+			continue
+		} else if err != nil {
+			return total, err
+		} else if revoked {
+			total++
+		}
+	}
+
+	return total, nil
+}
+
+func (d *dbWrapper) CheckReferences(buffer chan ReferenceViolation) (err error) {
+	op := d.beginOp("CheckReferences")
+	defer op.end(&err)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	return checkReferences(db, d.rules.all(), buffer, d.forOp(op))
+}
+
+func (d *dbWrapper) Compact() (err error) {
+	op := d.beginOp("Compact")
+	defer op.end(&err)
+
+	if err := d.checkNested("compaction"); err != nil {
+		return err
+	}
+
+	_, release := d.acquireExclusive()
+	defer release()
+
+	if err := d.holder().compact(); err != nil {
+		return fmt.Errorf("compaction experienced %w", err)
+	}
+
+	return nil
+}
+
+func (d *dbWrapper) Fork(dstPath string) (_ DB, err error) {
+	op := d.beginOp("Fork")
+	defer op.end(&err)
+
+	db, release := d.acquire()
+	untrack := d.trackRead(op)
+	err = forkTo(db, dstPath, d.opts)
+	untrack()
+	release()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := new(dstPath, d.opts)
+	if err != nil {
+		os.Remove(dstPath)
+		return nil, fmt.Errorf("forking experienced error while opening %s: %w", dstPath, err)
+	}
+
+	f.rules = d.rules.clone()
+	f.bufferTimeout = d.bufferTimeout
+	f.codec = d.codec
+	f.merge = d.merge
+	f.scope, f.scopeErr = d.scope, d.scopeErr
+
+	return f, nil
+}
+
+func (d *dbWrapper) DiffFrom(parent DB, buffer chan Change) (err error) {
+	op := d.beginOp("DiffFrom")
+	defer op.end(&err)
+
+	if parent == nil {
+		return fmt.Errorf("diffing received nil parent")
+	} else if buffer == nil {
+		return fmt.Errorf("diffing received nil channel")
+	} else if d.scopeErr != nil {
+		return fmt.Errorf("diffing experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(d.scope)
+
+	defer close(buffer)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+	defer d.feedTx(op, buffer)()
+
+	err = parent.RunView(func(ptx *bbolt.Tx) error {
+		return db.View(func(tx *bbolt.Tx) error {
+			old, err := getBucket(ptx, d.scope, false)
+			if err != nil {
+				return fmt.Errorf("error while navigating parent: %w", err)
+			}
+			changed, err := getBucket(tx, d.scope, false)
+			if err != nil {
+				return fmt.Errorf("error while navigating path: %w", err)
+			}
+
+			return diffTrees(old, changed, d.scope, buffer, d.forOp(op))
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("diffing at %s experienced error: %w", d.scope, err)
+	}
+
+	return nil
+}
+
+func (d *dbWrapper) Reset() (err error) {
+	op := d.beginOp("Reset")
+	defer op.end(&err)
+
+	if d.scopeErr != nil {
+		return fmt.Errorf("reset experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(d.scope)
+
+	if err := d.waitForWrite(); err != nil {
+		return err
+	}
+
+	db, release := d.acquire()
+	defer release()
+
+	start := time.Now()
+	err = reset(db, d.scope, d.writeEnv(d.scope))
+	d.metrics.observeLatency(start, err)
+
+	return err
+}
+
+func (d *dbWrapper) RegisterPath(name string, path any) (err error) {
+	op := d.beginOp("RegisterPath")
+	defer op.end(&err)
+
+	p, err := d.resolveBucketPath(path)
+	if err != nil {
+		return fmt.Errorf("path registration experienced %w", newErrBucketPathResolution("error"))
+	}
+	op.labelPath(p)
+
+	if err := d.rules.registerPath(name, p); err != nil {
+		return fmt.Errorf("path registration experienced error: %w", err)
+	}
+
+	return nil
+}
+
+func (d *dbWrapper) RegisteredPath(name string) (Path, bool) {
+	p, ok := d.rules.registeredPath(name)
+	if !ok {
+		return Path{}, false
+	}
+
+	return Path{segments: copyPath(p), text: make([]bool, len(p))}, true
+}
+
+func (d *dbWrapper) ValidatePaths(create bool) (_ []Path, err error) {
+	op := d.beginOp("ValidatePaths")
+	defer op.end(&err)
+
+	names, paths := d.rules.allPaths()
+
+	if create {
+		if err := d.waitForWrite(); err != nil {
+			return nil, err
+		}
+	}
+
+	db, release := d.acquire()
+	defer release()
+	if !create {
+		defer d.trackRead(op)()
+	}
+
+	unregistered, err := validatePaths(db, names, paths, create)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]Path, 0, len(unregistered))
+	for _, u := range unregistered {
+		p := Path{segments: u, text: make([]bool, len(u))}
+		found = append(found, p)
+
+		logMutex.Lock()
+		d.logger.Warn().Str("operation", op.name).Str("path", p.String()).Msg("path validation found bucket outside of registered paths")
+		logMutex.Unlock()
+	}
+
+	return found, nil
+}
+
+func (d *dbWrapper) AddInvariant(inv Invariant) (err error) {
+	op := d.beginOp("AddInvariant")
+	defer op.end(&err)
+
+	b, err := d.bind(inv)
+	if err != nil {
+		return fmt.Errorf("invariant registration experienced error: %w", err)
+	}
+
+	d.rules.addInvariant(b)
+
+	return nil
+}
+
+func (d *dbWrapper) Verify(invariants ...Invariant) (err error) {
+	op := d.beginOp("Verify")
+	defer op.end(&err)
+
+	bound := d.rules.allInvariants()
+	for _, inv := range invariants {
+		b, err := d.bind(inv)
+		if err != nil {
+			return fmt.Errorf("verification experienced error: %w", err)
+		}
+		bound = append(bound, b)
+	}
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	if err := verify(db, d, bound); err != nil {
+		return fmt.Errorf("verification experienced error: %w", err)
+	}
+	return nil
+}
+
+func (d *dbWrapper) VacuumStats() (_ VacuumStats, err error) {
+	op := d.beginOp("VacuumStats")
+	defer op.end(&err)
+
+	db, release := d.acquire()
+	defer release()
+	defer d.trackRead(op)()
+
+	return vacuumStats(db)
+}
+
+func (d *dbWrapper) Stats() Stats {
+	return d.metrics.snapshot()
+}
+
+func (d *dbWrapper) WithBackpressure(bp Backpressure) DB {
+	w := *d
+	w.backpressure = bp
+	w.parent = d.holder()
+	w.db = nil
+	return &w
+}
+
+// holder returns the wrapper holding the bbolt database, which is the wrapper itself unless it is a view.
+func (d *dbWrapper) holder() *dbWrapper {
+	if d.parent != nil {
+		return d.parent
+	}
+	return d
+}
+
+func (d *dbWrapper) Scope(bucketPath any) DB {
+	w := *d
+	w.parent = d.holder()
+	w.db = nil
+
+	p, err := d.resolveBucketPath(bucketPath)
+	if err != nil {
+		w.scopeErr = err
+	} else {
+		w.scope = p
+	}
+
+	return &w
+}
+
+func (d *dbWrapper) ServeSnapshot(addr string, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("snapshot serving received non-positive interval %s", interval)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("snapshot serving experienced error while listening on %s: %w", addr, err)
+	}
+
+	if err := d.serveSnapshot(ln, interval); err != nil {
+		return fmt.Errorf("snapshot serving on %s experienced error: %w", addr, err)
+	}
+
+	return nil
+}
+
+func (d *dbWrapper) DebugHandler() http.Handler {
+	return newDebugHandler(d)
 }
@@ -1,10 +1,17 @@
 package quickbolt
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
 )
 
 const (
@@ -47,3 +54,588 @@ func Test_dbWrapper_ValuesAtErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateWith_Permissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested")
+
+	db, err := CreateWith("foo.db", dir, WithFileMode(0640), WithDirMode(0750))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	info, err := os.Stat(db.Path())
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	info, err = os.Stat(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+}
+
+func TestCreateTemp(t *testing.T) {
+	db, err := CreateTemp("quickbolt-test-*")
+	assert.Nil(t, err)
+
+	dir := filepath.Dir(db.Path())
+	assert.Equal(t, os.TempDir(), filepath.Dir(dir))
+
+	assert.Nil(t, db.Insert("foo", "bar", []string{"baz"}))
+	assert.Nil(t, db.RemoveFile())
+
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_dbWrapper_BucketsAtRecursive(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k", "v", []string{"a", "b", "c"}))
+	assert.Nil(t, db.Insert("k", "v", []string{"a", "d"}))
+	assert.Nil(t, db.Insert("k", "v", []string{"e"}))
+
+	buffer := make(chan [][]byte)
+	go func() {
+		assert.Nil(t, db.BucketsAtRecursive([]string{"a"}, true, buffer))
+	}()
+
+	var got []string
+	for p := range buffer {
+		parts := make([]string, len(p))
+		for i, b := range p {
+			parts[i] = string(b)
+		}
+		got = append(got, strings.Join(parts, "/"))
+	}
+
+	assert.Equal(t, []string{"a/b", "a/b/c", "a/d"}, got)
+}
+
+func Test_dbWrapper_EntriesAtRecursive(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k1", "v1", []string{"a"}))
+	assert.Nil(t, db.Insert("k2", "v2", []string{"a", "b"}))
+	assert.Nil(t, db.Insert("k3", "v3", []string{"c"}))
+
+	buffer := make(chan PathedEntry)
+	go func() {
+		assert.Nil(t, db.EntriesAtRecursive([]string{"a"}, true, buffer))
+	}()
+
+	var got []string
+	for e := range buffer {
+		parts := make([]string, len(e.Path))
+		for i, b := range e.Path {
+			parts[i] = string(b)
+		}
+		got = append(got, strings.Join(parts, "/")+":"+string(e.Key)+"="+string(e.Value))
+	}
+
+	assert.Equal(t, []string{"a/b:k2=v2", "a:k1=v1"}, got)
+}
+
+func Test_dbWrapper_GetValueOK(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("empty", "", []string{"a"}))
+	assert.Nil(t, db.Insert("full", "v", []string{"a"}))
+	assert.Nil(t, db.Insert("k", "v", []string{"a", "nested"}))
+
+	tests := []struct {
+		name      string
+		key       string
+		path      []string
+		want      []byte
+		wantFound bool
+	}{
+		{name: "empty value", key: "empty", path: []string{"a"}, want: []byte{}, wantFound: true},
+		{name: "value", key: "full", path: []string{"a"}, want: []byte("v"), wantFound: true},
+		{name: "missing key", key: "missing", path: []string{"a"}},
+		{name: "bucket key", key: "nested", path: []string{"a"}},
+		{name: "missing bucket", key: "k", path: []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, found, err := db.GetValueOK(tt.key, tt.path)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.want, v)
+		})
+	}
+}
+
+func Test_dbWrapper_GetFirstEntryAt(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("b", "1", []string{"a"}))
+	assert.Nil(t, db.Insert("c", "2", []string{"a"}))
+	assert.Nil(t, db.InsertBucket("a0", []string{"a"}))
+	assert.Nil(t, db.InsertBucket("z", []string{"a"}))
+	assert.Nil(t, db.InsertBucket("empty", []string{"x"}))
+
+	e, err := db.GetFirstEntryAt([]string{"a"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, Entry{Key: []byte("b"), Value: []byte("1")}, e)
+
+	e, err = db.GetLastEntryAt([]string{"a"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, Entry{Key: []byte("c"), Value: []byte("2")}, e)
+
+	e, err = db.GetFirstEntryAt([]string{"x", "empty"}, false)
+	assert.Nil(t, err)
+	assert.Nil(t, e.Key)
+
+	_, err = db.GetLastEntryAt([]string{"x", "empty"}, true)
+	var locate ErrLocate
+	assert.ErrorAs(t, err, &locate)
+
+	_, err = db.GetFirstEntryAt([]string{"missing"}, true)
+	assert.NotNil(t, err)
+}
+
+func Test_dbWrapper_GetValueWith(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k", "v", []string{"a"}))
+
+	tests := []struct {
+		name    string
+		key     string
+		path    []string
+		opts    GetOpts
+		want    []byte
+		wantErr bool
+	}{
+		{name: "found", key: "k", path: []string{"a"}, want: []byte("v")},
+		{name: "no copy", key: "k", path: []string{"a"}, opts: GetOpts{NoCopy: true}, want: []byte("v")},
+		{name: "consistent", key: "k", path: []string{"a"}, opts: GetOpts{Consistent: true}, want: []byte("v")},
+		{name: "missing", key: "x", path: []string{"a"}},
+		{name: "default", key: "x", path: []string{"a"}, opts: GetOpts{Default: []byte("d")}, want: []byte("d")},
+		{name: "default for missing bucket", key: "k", path: []string{"b"}, opts: GetOpts{Default: []byte("d")}, want: []byte("d")},
+		{name: "must exist", key: "x", path: []string{"a"}, opts: GetOpts{MustExist: true, Default: []byte("d")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := db.GetValueWith(tt.key, tt.path, tt.opts)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, v)
+		})
+	}
+}
+
+func TestCreateWith_BoltOptions(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithInitialMmapSize(1<<24), WithNoGrowSync())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	bolt := db.(*dbWrapper).db
+	assert.True(t, bolt.NoGrowSync)
+
+	assert.Nil(t, db.Insert("k", "v", []string{"a"}))
+	assert.Nil(t, db.Compact())
+	assert.True(t, db.(*dbWrapper).db.NoGrowSync, "options must survive compaction")
+}
+
+func TestOpenWith_ReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	db, err := CreateWith("foo.db", dir)
+	assert.Nil(t, err)
+
+	assert.Nil(t, db.Insert("k", "v", []string{"a"}))
+
+	_, err = OpenWith("foo.db", dir, WithOpenTimeout(time.Millisecond*50))
+	assert.ErrorIs(t, err, bbolt.ErrTimeout, "opening must time out while another handle holds the file")
+
+	assert.Nil(t, db.Close())
+
+	ro1, err := OpenWith("foo.db", dir, WithReadOnly(), WithOpenTimeout(time.Second))
+	assert.Nil(t, err)
+	defer ro1.Close()
+
+	ro2, err := OpenWith("foo.db", dir, WithReadOnly(), WithOpenTimeout(time.Second))
+	assert.Nil(t, err, "read-only handles must share the file")
+	defer ro2.Close()
+
+	v, err := ro2.GetValue("k", []string{"a"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v", string(v))
+	assert.True(t, ro1.Info().ReadOnly)
+	assert.NotNil(t, ro1.Insert("k2", "v", []string{"a"}))
+
+	_, err = OpenWith("missing.db", dir, WithReadOnly())
+	assert.NotNil(t, err)
+}
+
+func TestCreateWith_NoSync(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithNoSync(), WithFreelistType(bbolt.FreelistMapType))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	info := db.Info()
+	assert.True(t, info.NoSync)
+	assert.Equal(t, string(bbolt.FreelistMapType), info.FreelistType)
+	assert.Nil(t, db.Insert("k", "v", []string{"a"}))
+}
+
+func TestCreateWith_PageSize(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := CreateWith("foo.db", dir, WithPageSize(16384))
+	assert.Nil(t, err)
+	assert.Equal(t, 16384, db.(*dbWrapper).db.Info().PageSize)
+	assert.Nil(t, db.Close())
+
+	db, err = OpenWith("foo.db", dir, WithPageSize(4096))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Equal(t, 16384, db.(*dbWrapper).db.Info().PageSize, "existing files keep their page size")
+}
+
+func TestCreateWith_StrictMode(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithStrictMode())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.True(t, db.(*dbWrapper).db.StrictMode)
+	assert.Nil(t, db.Insert("k", "v", []string{"a"}))
+	assert.Nil(t, db.Compact())
+	assert.True(t, db.(*dbWrapper).db.StrictMode)
+}
+
+func Test_dbWrapper_ImportFrom(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.SetUnique([]string{"b"}))
+
+	entries := []PathedEntry{
+		{Path: [][]byte{[]byte("a")}, Key: []byte("k1"), Value: []byte("v1")},
+		{Path: [][]byte{[]byte("a"), []byte("nested")}, Key: []byte("k2"), Value: []byte("v2")},
+		{Path: [][]byte{[]byte("b")}, Key: []byte("k3"), Value: []byte("v3")},
+	}
+
+	n, err := db.ImportFrom(SliceSource(entries))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	v, err := db.GetValue("k2", []string{"a", "nested"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", string(v))
+
+	n, err = db.ImportFrom(SliceSource([]PathedEntry{
+		{Path: [][]byte{[]byte("b")}, Key: []byte("k4"), Value: []byte("v3")},
+	}))
+	assert.NotNil(t, err, "imports must respect registered rules")
+	assert.Equal(t, 0, n)
+}
+
+func Test_dbWrapper_ForEachEntry(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k1", "v1", []string{"a"}))
+	assert.Nil(t, db.Insert("k2", "v2", []string{"a"}))
+	assert.Nil(t, db.Insert("k3", "v3", []string{"a", "nested"}))
+
+	var got []string
+	err = db.ForEachEntry([]string{"a"}, func(k, v []byte) error {
+		got = append(got, string(k)+"="+string(v))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"k1=v1", "k2=v2"}, got)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = db.ForEachEntry([]string{"a"}, func(k, v []byte) error {
+		calls++
+		return stop
+	})
+	assert.True(t, errors.Is(err, stop))
+	assert.Equal(t, 1, calls)
+
+	assert.Nil(t, db.ForEachEntry([]string{"missing"}, func(k, v []byte) error { return stop }))
+}
+
+func Test_dbWrapper_ForEachKey(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	for _, k := range []string{"k1", "k2", "k3"} {
+		assert.Nil(t, db.Insert(k, "v", []string{"a"}))
+	}
+	assert.Nil(t, db.InsertBucket("nested", []string{"a"}))
+
+	var got []string
+	err = db.ForEachKey([]string{"a"}, func(k []byte) error {
+		got = append(got, string(k))
+		if len(got) == 2 {
+			return ErrStop
+		}
+		return nil
+	})
+	assert.Nil(t, err, "ErrStop must end iteration without error")
+	assert.Equal(t, []string{"k1", "k2"}, got)
+
+	got = nil
+	assert.Nil(t, db.ForEachKey([]string{"a"}, func(k []byte) error {
+		got = append(got, string(k))
+		return nil
+	}))
+	assert.Equal(t, []string{"k1", "k2", "k3"}, got)
+}
+
+func Test_dbWrapper_KeysMatching(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	for _, k := range []string{"order:1", "user:1", "user:22", "user:x", "xuser:3"} {
+		assert.Nil(t, db.Insert(k, "v", []string{"a"}))
+	}
+	assert.Nil(t, db.InsertBucket("user:4", []string{"a"}))
+
+	match := func(expr string) []string {
+		buffer := make(chan []byte, 10)
+		assert.Nil(t, db.KeysMatching([]string{"a"}, regexp.MustCompile(expr), buffer))
+
+		var got []string
+		for k := range buffer {
+			got = append(got, string(k))
+		}
+		return got
+	}
+
+	assert.Equal(t, []string{"user:1", "user:22"}, match(`^user:[0-9]+$`))
+	assert.Equal(t, []string{"user:1", "user:22", "user:x", "xuser:3"}, match(`user:`))
+	assert.Equal(t, []string{"order:1", "user:1"}, match(`:1$`))
+	assert.Nil(t, match(`^missing`))
+}
+
+func Test_anchoredPrefix(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: `^user:[0-9]+$`, want: "user:"},
+		{expr: `\Aabc`, want: "abc"},
+		{expr: `abc`},
+		{expr: `(?m)^abc`},
+		{expr: `^a|b`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(anchoredPrefix(regexp.MustCompile(tt.expr))))
+		})
+	}
+}
+
+func Test_dbWrapper_CountAt(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k1", "v", []string{"a"}))
+	assert.Nil(t, db.Insert("k2", "", []string{"a"}))
+	assert.Nil(t, db.Insert("k", "v", []string{"a", "nested"}))
+
+	n, err := db.CountAt([]string{"a"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = db.CountAt([]string{"missing"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	tests := []struct {
+		name string
+		key  string
+		path []string
+		want bool
+	}{
+		{name: "key", key: "k1", path: []string{"a"}, want: true},
+		{name: "empty value", key: "k2", path: []string{"a"}, want: true},
+		{name: "bucket key", key: "nested", path: []string{"a"}},
+		{name: "missing key", key: "k3", path: []string{"a"}},
+		{name: "missing bucket", key: "k1", path: []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := db.HasKey(tt.key, tt.path)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, found)
+		})
+	}
+
+	found, err := db.HasBucket([]string{"a", "nested"})
+	assert.Nil(t, err)
+	assert.True(t, found)
+
+	found, err = db.HasBucket([]string{"a", "k1"})
+	assert.Nil(t, err)
+	assert.False(t, found)
+}
+
+func Test_dbWrapper_EntriesPage(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	for _, k := range []string{"k1", "k2", "k3", "k4", "k5"} {
+		assert.Nil(t, db.Insert(k, "v"+k, []string{"a"}))
+	}
+	assert.Nil(t, db.InsertBucket("k2b", []string{"a"}))
+
+	var keys []string
+	var after any
+	pages := 0
+	for {
+		page, next, err := db.EntriesPage([]string{"a"}, after, 2)
+		assert.Nil(t, err)
+		pages++
+
+		for _, e := range page {
+			keys = append(keys, string(e[0]))
+			assert.Equal(t, "v"+string(e[0]), string(e[1]))
+		}
+
+		if next == nil {
+			break
+		}
+		after = next
+	}
+	assert.Equal(t, []string{"k1", "k2", "k3", "k4", "k5"}, keys)
+	assert.Equal(t, 3, pages)
+
+	page, next, err := db.EntriesPage([]string{"a"}, "k4", 2)
+	assert.Nil(t, err)
+	assert.Len(t, page, 1)
+	assert.Nil(t, next)
+
+	page, _, err = db.EntriesPage([]string{"missing"}, nil, 2)
+	assert.Nil(t, err)
+	assert.Empty(t, page)
+
+	_, _, err = db.EntriesPage([]string{"a"}, nil, 0)
+	assert.NotNil(t, err)
+}
+
+func Test_dbWrapper_ChildrenAt(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k1", "v", []string{"a"}))
+	assert.Nil(t, db.Insert("k3", "v", []string{"a"}))
+	assert.Nil(t, db.InsertBucket("k2", []string{"a"}))
+
+	buffer := make(chan Child, 3)
+	assert.Nil(t, db.ChildrenAt([]string{"a"}, true, buffer))
+
+	var got []Child
+	for c := range buffer {
+		got = append(got, c)
+	}
+	assert.Equal(t, []Child{{Name: []byte("k1")}, {Name: []byte("k2"), IsBucket: true}, {Name: []byte("k3")}}, got)
+
+	assert.NotNil(t, db.ChildrenAt([]string{"missing"}, true, make(chan Child)))
+}
+
+func Test_dbWrapper_StreamedCopies(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a"}
+	assert.Nil(t, db.Insert("k", "v", path))
+	assert.Nil(t, db.InsertBucket("b", path))
+
+	entries := make(chan [2][]byte)
+	go func() { assert.Nil(t, db.EntriesAt(path, true, entries)) }()
+	keys := make(chan []byte)
+	go func() { assert.Nil(t, db.KeysAt(path, true, keys)) }()
+	values := make(chan []byte)
+	go func() { assert.Nil(t, db.ValuesAt(path, true, values)) }()
+	buckets := make(chan []byte)
+	go func() { assert.Nil(t, db.BucketsAt(path, true, buckets)) }()
+
+	var received [][]byte
+	for e := range entries {
+		received = append(received, e[0], e[1])
+	}
+	for _, c := range []chan []byte{keys, values, buckets} {
+		for b := range c {
+			received = append(received, b)
+		}
+	}
+	assert.NotEmpty(t, received)
+
+	// Writing to bytes referencing the read-only memory map would fault.
+	for _, b := range received {
+		for i := range b {
+			b[i] = 'x'
+		}
+	}
+
+	v, err := db.GetValue("k", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v", string(v))
+}
+
+func Test_dbWrapper_GetValueCopies(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a"}
+	assert.Nil(t, db.Insert("k", "v", path))
+
+	v, err := db.GetValue("k", path, true)
+	assert.Nil(t, err)
+	w, err := db.GetValueWith("k", path, GetOpts{})
+	assert.Nil(t, err)
+
+	// Compacting remaps the db, unmapping any memory the values referenced.
+	assert.Nil(t, db.Compact())
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, db.Insert(i, bytes.Repeat([]byte("x"), 1024), path))
+	}
+
+	assert.Equal(t, "v", string(v))
+	assert.Equal(t, "v", string(w))
+
+	// Writing to bytes referencing the read-only memory map would fault.
+	v[0], w[0] = 'x', 'x'
+}
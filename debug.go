@@ -0,0 +1,278 @@
+package quickbolt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// debugStats is the body served by DebugHandler's stats page.
+type debugStats struct {
+	Stats
+	AvgBatchSize     float64
+	AvgCommitLatency time.Duration
+	// FileBytes and FileMB are the size of the database file.
+	FileBytes int64
+	FileMB    int
+	// Buckets is the size breakdown of each bucket at the top of the handler's scope, by name.
+	Buckets map[string]SizeBreakdown
+}
+
+// debugPage is the body served by DebugHandler's key browser.
+type debugPage struct {
+	Path    []string     `json:"path"`
+	Buckets []string     `json:"buckets"`
+	Entries []debugEntry `json:"entries"`
+	// Next is the key to pass as after to fetch the next page, or empty if there are no more keys.
+	Next string `json:"next,omitempty"`
+}
+
+type debugEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// debugOp is the operation the reads made by DebugHandler's pages are tracked under.
+var debugOp = &operation{name: "DebugHandler"}
+
+// newDebugHandler returns the handler served by DebugHandler.
+func newDebugHandler(d *dbWrapper) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(debugPrefix, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != debugPrefix {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprintf(w, "quickbolt %s\n\n", d.Path())
+		fmt.Fprintf(w, "%sstats\t\twrite metrics, file size, and size breakdowns of the top-level buckets\n", debugPrefix)
+		fmt.Fprintf(w, "%smetrics\t\twrite, batch size, commit latency, and buffer timeout metrics in the Prometheus text format\n", debugPrefix)
+		fmt.Fprintf(w, "%ssize?path=a&path=b\tsize breakdown of a bucket\n", debugPrefix)
+		fmt.Fprintf(w, "%sslow\t\trecent operations taking at least %s\n", debugPrefix, slowOpThreshold)
+		fmt.Fprintf(w, "%skeys?path=a&path=b&after=k&limit=n\tbuckets and key-value pairs within a bucket\n", debugPrefix)
+	})
+
+	mux.HandleFunc(debugPrefix+"stats", func(w http.ResponseWriter, r *http.Request) {
+		p, err := d.resolveBucketPath([][]byte(nil))
+		if err != nil {
+			writeDebugError(w, err)
+			return
+		}
+
+		db, release := d.acquire()
+		untrack := d.trackRead(debugOp)
+		buckets, err := bucketSizes(db, p)
+		untrack()
+		release()
+		if err != nil {
+			writeDebugError(w, err)
+			return
+		}
+
+		s, size := d.Stats(), d.Size()
+		writeDebugJSON(w, debugStats{
+			Stats:            s,
+			AvgBatchSize:     s.AvgBatchSize(),
+			AvgCommitLatency: s.AvgCommitLatency(),
+			FileBytes:        size.Bytes(),
+			FileMB:           size.Megabytes(),
+			Buckets:          buckets,
+		})
+	})
+
+	mux.HandleFunc(debugPrefix+"metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheus(w, d.Stats())
+	})
+
+	mux.HandleFunc(debugPrefix+"size", func(w http.ResponseWriter, r *http.Request) {
+		s, err := d.SizeOf(debugPath(r))
+		if err != nil {
+			writeDebugError(w, err)
+			return
+		}
+		writeDebugJSON(w, s)
+	})
+
+	mux.HandleFunc(debugPrefix+"slow", func(w http.ResponseWriter, r *http.Request) {
+		writeDebugJSON(w, d.metrics.slowOps())
+	})
+
+	mux.HandleFunc(debugPrefix+"keys", func(w http.ResponseWriter, r *http.Request) {
+		limit := debugBrowseLimit
+		if l := r.URL.Query().Get("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n <= 0 {
+				http.Error(w, fmt.Sprintf("invalid limit %q", l), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		// The path is resolved like any other so that a scoped handler cannot browse outside its scope.
+		p, err := d.resolveBucketPath(debugPath(r))
+		if err != nil {
+			writeDebugError(w, err)
+			return
+		}
+
+		db, release := d.acquire()
+		untrack := d.trackRead(debugOp)
+		page, err := browse(db, p, []byte(r.URL.Query().Get("after")), limit)
+		untrack()
+		release()
+		if err != nil {
+			writeDebugError(w, err)
+			return
+		}
+		writeDebugJSON(w, page)
+	})
+
+	return mux
+}
+
+// debugPath returns the bucket path given by the request's path query parameters.
+func debugPath(r *http.Request) [][]byte {
+	var path [][]byte
+	for _, p := range r.URL.Query()["path"] {
+		path = append(path, []byte(p))
+	}
+	return path
+}
+
+func writeDebugJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writePrometheus writes the stats in the Prometheus text exposition format.
+func writePrometheus(w io.Writer, s Stats) {
+	counter := func(name, help string, v uint64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+	}
+	gauge := func(name, help string, v float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+
+	counter("quickbolt_writes_total", "Committed write operations.", s.Writes)
+	counter("quickbolt_commits_total", "Committed write transactions.", s.Commits)
+	counter("quickbolt_bytes_written_total", "Size of the keys and values committed.", s.BytesWritten)
+
+	gauge("quickbolt_batch_size_avg", "Mean number of write operations committed per transaction.", s.AvgBatchSize())
+	gauge("quickbolt_batch_size_max", "Largest number of write operations committed in a single transaction.", float64(s.MaxBatchSize))
+
+	// Bucket counts are cumulative in the exposition format.
+	const latency = "quickbolt_commit_latency_seconds"
+	fmt.Fprintf(w, "# HELP %s Time write operations spent waiting for their transaction to commit.\n# TYPE %s histogram\n", latency, latency)
+	var total uint64
+	for i, c := range s.CommitLatencyCounts {
+		total += c
+		le := "+Inf"
+		if i < len(CommitLatencyBuckets) {
+			le = strconv.FormatFloat(CommitLatencyBuckets[i].Seconds(), 'g', -1, 64)
+		}
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", latency, le, total)
+	}
+	if len(s.CommitLatencyCounts) == 0 {
+		fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} 0\n", latency)
+	}
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", latency, strconv.FormatFloat(s.CommitLatency.Seconds(), 'g', -1, 64), latency, total)
+
+	gauge("quickbolt_commit_latency_max_seconds", "Longest time a write operation spent waiting for its transaction to commit.", s.MaxCommitLatency.Seconds())
+
+	breakdown := func(name, help, label string, counts map[string]TimeoutCounts) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+		keys := make([]string, 0, len(counts))
+		for k := range counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			v := promLabelEscaper.Replace(k)
+			fmt.Fprintf(w, "%s{%s=\"%s\",direction=\"send\"} %d\n", name, label, v, counts[k].Send)
+			fmt.Fprintf(w, "%s{%s=\"%s\",direction=\"receive\"} %d\n", name, label, v, counts[k].Receive)
+		}
+	}
+
+	breakdown("quickbolt_buffer_timeouts_by_op_total", "Buffer timeouts by operation.", "op", s.BufferTimeoutsByOp)
+	breakdown("quickbolt_buffer_timeouts_by_path_total", "Buffer send timeouts by bucket path.", "path", s.BufferTimeoutsByPath)
+}
+
+// promLabelEscaper escapes label values for the Prometheus text exposition format.
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeDebugError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrAccess{}) {
+		status = http.StatusNotFound
+	}
+
+	http.Error(w, err.Error(), status)
+}
+
+// browse returns up to limit buckets and key-value pairs within the bucket at the given path, starting after the given key.
+func browse(db *bbolt.DB, path [][]byte, after []byte, limit int) (debugPage, error) {
+	if db == nil {
+		return debugPage{}, fmt.Errorf("browsing of %s received nil db", path)
+	}
+
+	page := debugPage{Path: make([]string, len(path)), Buckets: []string{}, Entries: []debugEntry{}}
+	for i, p := range path {
+		page.Path[i] = string(p)
+	}
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		c := bkt.Cursor()
+
+		k, v := c.First()
+		if len(after) > 0 {
+			k, v = c.Seek(after)
+			if k != nil && bytes.Equal(k, after) {
+				k, v = c.Next()
+			}
+		}
+
+		var last []byte
+		for n := 0; k != nil; k, v = c.Next() {
+			if n == limit {
+				page.Next = string(last)
+				break
+			}
+			n++
+			last = k
+
+			if v == nil {
+				page.Buckets = append(page.Buckets, string(k))
+			} else {
+				page.Entries = append(page.Entries, debugEntry{Key: string(k), Value: render(v, RenderAuto, 0)})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return debugPage{}, fmt.Errorf("browsing of %s experienced error while reading bucket: %w", path, err)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,161 @@
+package quickbolt
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_DebugHandler(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k1", `{"a": 1}`, []string{"a"}))
+	assert.Nil(t, db.Insert("k2", "v2", []string{"a"}))
+	assert.Nil(t, db.Insert("k3", "v3", []string{"a", "nested"}))
+
+	srv := httptest.NewServer(db.DebugHandler())
+	defer srv.Close()
+
+	get := func(path string, v any) int {
+		resp, err := http.Get(srv.URL + path)
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+
+		if v != nil && resp.StatusCode == http.StatusOK {
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, get("/debug/quickbolt/", nil))
+
+	var stats debugStats
+	assert.Equal(t, http.StatusOK, get("/debug/quickbolt/stats", &stats))
+	assert.Equal(t, uint64(3), stats.Writes)
+	assert.NotZero(t, stats.FileBytes)
+	assert.Equal(t, 3, stats.Buckets["a"].Keys)
+	assert.NotZero(t, stats.Buckets["a"].Bytes)
+
+	resp, err := http.Get(srv.URL + "/debug/quickbolt/metrics")
+	assert.Nil(t, err)
+	metrics, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Nil(t, err)
+	assert.Contains(t, string(metrics), "# TYPE quickbolt_writes_total counter\nquickbolt_writes_total 3\n")
+	assert.Contains(t, string(metrics), "quickbolt_commit_latency_seconds_count 3\n")
+
+	var size SizeBreakdown
+	assert.Equal(t, http.StatusOK, get("/debug/quickbolt/size?path=a", &size))
+	assert.Equal(t, 3, size.Keys)
+	assert.Equal(t, http.StatusNotFound, get("/debug/quickbolt/size?path=missing", nil))
+
+	var page debugPage
+	assert.Equal(t, http.StatusOK, get("/debug/quickbolt/keys?path=a&limit=2", &page))
+	assert.Equal(t, []debugEntry{{Key: "k1", Value: `{"a":1}`}, {Key: "k2", Value: `"v2"`}}, page.Entries)
+	assert.Equal(t, "k2", page.Next)
+
+	page = debugPage{}
+	assert.Equal(t, http.StatusOK, get("/debug/quickbolt/keys?path=a&after=k2", &page))
+	assert.Equal(t, []string{"nested"}, page.Buckets)
+	assert.Empty(t, page.Entries)
+	assert.Empty(t, page.Next)
+
+	assert.Equal(t, http.StatusBadRequest, get("/debug/quickbolt/keys?limit=x", nil))
+}
+
+func Test_dbWrapper_DebugHandler_Scoped(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	// A value large enough for the file to exceed a megabyte.
+	assert.Nil(t, db.Insert("big", strings.Repeat("x", 2<<20), []string{"tenant", "a"}))
+	assert.Nil(t, db.Insert("secret", "v", []string{"other"}))
+
+	srv := httptest.NewServer(db.Scope([]string{"tenant"}).DebugHandler())
+	defer srv.Close()
+
+	get := func(path string, v any) int {
+		resp, err := http.Get(srv.URL + path)
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+
+		if v != nil && resp.StatusCode == http.StatusOK {
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	var stats debugStats
+	assert.Equal(t, http.StatusOK, get("/debug/quickbolt/stats", &stats))
+	assert.NotZero(t, stats.FileMB)
+	assert.Equal(t, stats.FileBytes>>20, int64(stats.FileMB))
+	assert.Equal(t, []string{"a"}, sortedKeys(stats.Buckets))
+	assert.Equal(t, 1, stats.Buckets["a"].Keys)
+	assert.Greater(t, stats.Buckets["a"].Bytes, 2<<20)
+
+	var page debugPage
+	assert.Equal(t, http.StatusOK, get("/debug/quickbolt/keys", &page))
+	assert.Equal(t, []string{"a"}, page.Buckets)
+	assert.Equal(t, http.StatusNotFound, get("/debug/quickbolt/keys?path=other", nil))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func Test_metrics_slowOps(t *testing.T) {
+	m := newMetrics()
+
+	for i := 0; i < slowOpLogSize+1; i++ {
+		m.observeOp(&operation{name: "Fast", start: time.Now()}, nil)
+		m.observeOp(&operation{name: "Slow", seq: uint64(i), start: time.Now().Add(-slowOpThreshold)}, nil)
+	}
+
+	ops := m.slowOps()
+	assert.Len(t, ops, slowOpLogSize)
+	assert.Equal(t, "Slow", ops[0].Name)
+	assert.Equal(t, (&operation{seq: slowOpLogSize}).id(), ops[0].ID, "most recent first")
+}
+
+func Test_writePrometheus(t *testing.T) {
+	var b strings.Builder
+	writePrometheus(&b, Stats{BufferTimeoutsByOp: map[string]TimeoutCounts{"KeysAt": {Send: 2}}, BufferTimeoutsByPath: map[string]TimeoutCounts{`["a"]`: {Send: 2}}})
+
+	assert.Contains(t, b.String(), "quickbolt_buffer_timeouts_by_op_total{op=\"KeysAt\",direction=\"send\"} 2\n")
+	assert.Contains(t, b.String(), "quickbolt_buffer_timeouts_by_op_total{op=\"KeysAt\",direction=\"receive\"} 0\n")
+	assert.Contains(t, b.String(), `quickbolt_buffer_timeouts_by_path_total{path="[\"a\"]",direction="send"} 2`)
+	assert.Contains(t, b.String(), "quickbolt_commit_latency_seconds_bucket{le=\"+Inf\"} 0\nquickbolt_commit_latency_seconds_sum 0\nquickbolt_commit_latency_seconds_count 0\n")
+
+	counts := make([]uint64, len(CommitLatencyBuckets)+1)
+	counts[0], counts[2], counts[len(counts)-1] = 1, 2, 1
+	b.Reset()
+	writePrometheus(&b, Stats{Writes: 6, Commits: 2, MaxBatchSize: 4, CommitLatency: 7500 * time.Millisecond, MaxCommitLatency: 6 * time.Second, CommitLatencyCounts: counts})
+
+	assert.Contains(t, b.String(), "# TYPE quickbolt_batch_size_avg gauge\nquickbolt_batch_size_avg 3\n")
+	assert.Contains(t, b.String(), "quickbolt_batch_size_max 4\n")
+	assert.Contains(t, b.String(), "# TYPE quickbolt_commit_latency_seconds histogram\n")
+	assert.Contains(t, b.String(), "quickbolt_commit_latency_seconds_bucket{le=\"0.001\"} 1\n")
+	assert.Contains(t, b.String(), "quickbolt_commit_latency_seconds_bucket{le=\"0.005\"} 1\n")
+	assert.Contains(t, b.String(), "quickbolt_commit_latency_seconds_bucket{le=\"0.01\"} 3\n")
+	assert.Contains(t, b.String(), "quickbolt_commit_latency_seconds_bucket{le=\"5\"} 3\n")
+	assert.Contains(t, b.String(), "quickbolt_commit_latency_seconds_bucket{le=\"+Inf\"} 4\n")
+	assert.Contains(t, b.String(), "quickbolt_commit_latency_seconds_sum 7.5\nquickbolt_commit_latency_seconds_count 4\n")
+	assert.Contains(t, b.String(), "quickbolt_commit_latency_max_seconds 6\n")
+}
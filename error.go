@@ -1,10 +1,43 @@
 package quickbolt
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"syscall"
+	"time"
+
+	"go.etcd.io/bbolt"
 )
 
+// IsRetryable reports whether err is transient, such that retrying the operation that returned it may succeed.
+//
+// Timeouts, whether waiting on a buffer, a deadline, or the lock on the db file, are retryable.
+// Errors describing the request itself, such as an unsupported type or a missing key, are not,
+// and neither are errors that quickbolt cannot classify.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var timeout ErrTimeout
+	switch {
+	case errors.As(err, &timeout):
+		return true
+	case errors.Is(err, context.DeadlineExceeded):
+		return true
+	case errors.Is(err, bbolt.ErrTimeout), errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EWOULDBLOCK):
+		// The db file is locked by another process.
+		return true
+	}
+
+	return false
+}
+
+// ErrStop may be returned by the funcs given to ForEachKey and ForEachEntry to end iteration early without error.
+var ErrStop = errors.New("iteration stopped")
+
 const (
 	errLocateMsg               = "could not locate"
 	errAccessMsg               = "could not access"
@@ -12,6 +45,15 @@ const (
 	errTimeoutMsg              = "timed out while"
 	errBucketPathResolutionMsg = "while resolving bucket path"
 	errRecordResolutionMsg     = "could not resolve"
+	errSchemaViolationMsg      = "violates schema"
+	errValidationMsg           = "failed validation"
+	errDuplicateValueMsg       = "already exists"
+	errInvalidReferenceMsg     = "references missing key in"
+	errLockLostMsg             = "lost lock on"
+	errReencodeMsg             = "could not re-encode keys:"
+	errNestedTxMsg             = "would deadlock:"
+	errInvariantViolationMsg   = "violates invariant:"
+	errOperationMsg            = "op"
 )
 
 // "could not locate X"
@@ -68,9 +110,19 @@ func newErrUnsupportedType(what string) error {
 type ErrTimeout struct {
 	Who  string
 	What string
+	// Path and Key identify the item a streaming method was sending when it timed out, if any.
+	Path string
+	Key  string
+	// Elapsed is how long the send waited before timing out.
+	Elapsed time.Duration
+	// Capacity is the capacity of the buffer the send waited on.
+	Capacity int
 }
 
 func (e ErrTimeout) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s %s %s (key %q in %s, waited %s on buffer with capacity %d)", e.Who, errTimeoutMsg, e.What, e.Key, e.Path, e.Elapsed, e.Capacity)
+	}
 	return fmt.Sprintf("%s %s %s", e.Who, errTimeoutMsg, e.What)
 }
 
@@ -79,6 +131,11 @@ func newErrTimeout(who, what string) error {
 	return ErrTimeout{Who: who, What: what}
 }
 
+// who "timed out while waiting to send to buffer" "(key" key "in" path ", waited" elapsed "on buffer with capacity" capacity ")"
+func newErrSendTimeout(who string, path [][]byte, key []byte, elapsed time.Duration, capacity int) error {
+	return ErrTimeout{Who: who, What: "waiting to send to buffer", Path: fmt.Sprintf("%q", path), Key: string(key), Elapsed: elapsed, Capacity: capacity}
+}
+
 // "X while resolving bucket path"
 type ErrBucketPathResolution struct {
 	What string
@@ -107,3 +164,158 @@ func (e ErrRecordResolution) Error() string {
 func newErrRecordResolution(what string, value interface{}) error {
 	return ErrRecordResolution{What: what}
 }
+
+// "X violates schema"
+type ErrSchemaViolation struct {
+	What string
+}
+
+func (e ErrSchemaViolation) Error() string {
+	return fmt.Sprintf("%s %s", e.What, errSchemaViolationMsg)
+}
+
+// what "violates schema"
+func newErrSchemaViolation(what string) error {
+	return ErrSchemaViolation{What: what}
+}
+
+// "X failed validation: Y"
+type ErrValidation struct {
+	What string
+	Err  error
+}
+
+func (e ErrValidation) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.What, errValidationMsg, e.Err)
+}
+
+func (e ErrValidation) Unwrap() error {
+	return e.Err
+}
+
+// what "failed validation:" err
+func newErrValidation(what string, err error) error {
+	return ErrValidation{What: what, Err: err}
+}
+
+// "X already exists"
+type ErrDuplicateValue struct {
+	What string
+}
+
+func (e ErrDuplicateValue) Error() string {
+	return fmt.Sprintf("%s %s", e.What, errDuplicateValueMsg)
+}
+
+// what "already exists"
+func newErrDuplicateValue(what string) error {
+	return ErrDuplicateValue{What: what}
+}
+
+// "X references missing key in Y"
+type ErrInvalidReference struct {
+	What   string
+	Target [][]byte
+}
+
+func (e ErrInvalidReference) Error() string {
+	return fmt.Sprintf("%s %s %s", e.What, errInvalidReferenceMsg, e.Target)
+}
+
+// what "references missing key in" target
+func newErrInvalidReference(what string, target [][]byte) error {
+	return ErrInvalidReference{What: what, Target: target}
+}
+
+// "lost lock on X"
+type ErrLockLost struct {
+	What string
+}
+
+func (e ErrLockLost) Error() string {
+	return fmt.Sprintf("%s %s", errLockLostMsg, e.What)
+}
+
+// "lost lock on" what
+func newErrLockLost(what string) error {
+	return ErrLockLost{What: what}
+}
+
+// "could not re-encode keys: X"
+type ErrReencode struct {
+	Reason string
+}
+
+func (e ErrReencode) Error() string {
+	return fmt.Sprintf("%s %s", errReencodeMsg, e.Reason)
+}
+
+// "could not re-encode keys:" reason
+func newErrReencode(reason string) error {
+	return ErrReencode{Reason: reason}
+}
+
+// "X violates invariant: Y"
+type ErrInvariantViolation struct {
+	What      string
+	Invariant string
+}
+
+func (e ErrInvariantViolation) Error() string {
+	return fmt.Sprintf("%s %s %s", e.What, errInvariantViolationMsg, e.Invariant)
+}
+
+// what "violates invariant:" invariant
+func newErrInvariantViolation(what, invariant string) error {
+	return ErrInvariantViolation{What: what, Invariant: invariant}
+}
+
+// "would deadlock: X"
+type ErrNestedTx struct {
+	What string
+}
+
+func (e ErrNestedTx) Error() string {
+	return fmt.Sprintf("%s %s", errNestedTxMsg, e.What)
+}
+
+// "would deadlock:" what
+func newErrNestedTx(what string) error {
+	return ErrNestedTx{What: what}
+}
+
+// "op X: Y: Z"
+type ErrOperation struct {
+	ID  string
+	Err error
+
+	name string
+	pc   uintptr // pc is the program counter of the operation's caller, or 0 if caller info is disabled.
+}
+
+func (e ErrOperation) Error() string {
+	if caller := e.Caller(); caller != "" {
+		return fmt.Sprintf("%s %s: %s: %s", errOperationMsg, e.ID, caller, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %s", errOperationMsg, e.ID, e.Err)
+}
+
+// Caller describes where the operation was called from.
+// The returned string is empty if caller info is disabled.
+func (e ErrOperation) Caller() string {
+	if e.pc == 0 {
+		return ""
+	}
+	return describeCaller(e.name, e.pc)
+}
+
+func (e ErrOperation) Unwrap() error {
+	return e.Err
+}
+
+// "op" id ":" caller ":" err
+//
+// The caller is described from name and pc when the error's message is needed.
+func newErrOperation(id, name string, pc uintptr, err error) error {
+	return ErrOperation{ID: id, Err: err, name: name, pc: pc}
+}
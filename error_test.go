@@ -0,0 +1,37 @@
+package quickbolt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "buffer timeout", err: newErrSendTimeout("quickbolt value scanning", nil, []byte("a"), 0, 0), want: true},
+		{name: "wrapped timeout", err: newErrOperation("1", "ValuesAt", 0, fmt.Errorf("iteration experienced error: %w", newErrTimeout("x", "y"))), want: true},
+		{name: "deadline", err: fmt.Errorf("limiter: %w", context.DeadlineExceeded), want: true},
+		{name: "file lock timeout", err: fmt.Errorf("open: %w", bbolt.ErrTimeout), want: true},
+		{name: "file locked", err: fmt.Errorf("flock: %w", syscall.EWOULDBLOCK), want: true},
+		{name: "unsupported type", err: newErrUnsupportedType("float32"), want: false},
+		{name: "not found", err: fmt.Errorf("value retrieval experienced %w", newErrLocate("key")), want: false},
+		{name: "record resolution", err: newErrRecordResolution("key", 1.5), want: false},
+		{name: "duplicate value", err: newErrDuplicateValue("value"), want: false},
+		{name: "unknown", err: errors.New("something went wrong"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
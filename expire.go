@@ -0,0 +1,431 @@
+package quickbolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// expiryTimeKey returns the key of the given key's entry in the time-ordered expiry index.
+func expiryTimeKey(t []byte, key []byte) []byte {
+	return append(append(make([]byte, 0, len(t)+len(key)), t...), key...)
+}
+
+// expiryRecord returns the value of the key's entry in the key-ordered expiry index: the write time,
+// followed by a checksum of the value written.
+func expiryRecord(t time.Time, val []byte) []byte {
+	h := fnv.New64a()
+	h.Write(val)
+
+	record := make([]byte, 8, 16)
+	binary.BigEndian.PutUint64(record, uint64(t.UnixNano()))
+	return h.Sum(record)
+}
+
+// rewrittenUnindexed reports whether the value no longer matches the checksum in the key's expiry record,
+// meaning the pair was rewritten without the index being maintained, such as via RunUpdate.
+func rewrittenUnindexed(record, val []byte) bool {
+	return len(record) == 16 && !bytes.Equal(expiryRecord(time.Time{}, val)[8:], record[8:])
+}
+
+// putExpiryIndex records the given time as the write time of the key and value, replacing any the key had.
+func putExpiryIndex(tx *bbolt.Tx, path [][]byte, key, val []byte, t time.Time) error {
+	byTime, err := getCreateIndexChild(tx, path, expiryByTime)
+	if err != nil {
+		return fmt.Errorf("error while navigating expiry index: %w", err)
+	}
+	byKey, err := getCreateIndexChild(tx, path, expiryByKey)
+	if err != nil {
+		return fmt.Errorf("error while navigating expiry index: %w", err)
+	}
+
+	if old := byKey.Get(key); old != nil {
+		if err := byTime.Delete(expiryTimeKey(old[:8], key)); err != nil {
+			return fmt.Errorf("error while removing %s from expiry index: %w", key, err)
+		}
+	}
+
+	record := expiryRecord(t, val)
+
+	if err := byTime.Put(expiryTimeKey(record[:8], key), []byte{}); err != nil {
+		return fmt.Errorf("error while indexing %s by time: %w", key, err)
+	}
+	if err := byKey.Put(key, record); err != nil {
+		return fmt.Errorf("error while indexing %s by time: %w", key, err)
+	}
+
+	return nil
+}
+
+// deleteExpiryIndex removes the key from the expiry index.
+func deleteExpiryIndex(tx *bbolt.Tx, path [][]byte, key []byte) error {
+	byTime, byKey := getIndexChild(tx, path, expiryByTime), getIndexChild(tx, path, expiryByKey)
+	if byTime == nil || byKey == nil {
+		return nil
+	}
+
+	old := byKey.Get(key)
+	if old == nil {
+		return nil
+	}
+
+	if err := byTime.Delete(expiryTimeKey(old[:8], key)); err != nil {
+		return fmt.Errorf("error while removing %s from expiry index: %w", key, err)
+	}
+	if err := byKey.Delete(key); err != nil {
+		return fmt.Errorf("error while removing %s from expiry index: %w", key, err)
+	}
+
+	return nil
+}
+
+// indexUnindexedKeys records the given path as registered for expiry and adds the keys at the path missing from the
+// expiry index, using the given time as their write time.
+func indexUnindexedKeys(db *bbolt.DB, path [][]byte, t time.Time) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	return db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateBucket(tx, path)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		registered, err := getCreateMetaBucket(tx, expiringBucket)
+		if err != nil {
+			return err
+		}
+		if err := registered.Put(encodeAttachment(path, nil), []byte{}); err != nil {
+			return fmt.Errorf("error while registering %s for expiry: %w", path, err)
+		}
+
+		byKey, err := getCreateIndexChild(tx, path, expiryByKey)
+		if err != nil {
+			return fmt.Errorf("error while navigating expiry index: %w", err)
+		}
+
+		var missing [][2][]byte
+		c := bkt.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v != nil && byKey.Get(k) == nil {
+				missing = append(missing, [2][]byte{k, v})
+			}
+		}
+
+		for _, e := range missing {
+			if err := putExpiryIndex(tx, path, e[0], e[1], t); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// expiryRegisteredPaths returns the paths recorded by indexUnindexedKeys, so that their indexes may be
+// maintained from the moment the db is opened.
+func expiryRegisteredPaths(db *bbolt.DB) ([][][]byte, error) {
+	if db == nil {
+		return nil, fmt.Errorf("expiry registration loading received nil db")
+	}
+
+	var paths [][][]byte
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		meta := tx.Bucket([]byte(metaBucket))
+		if meta == nil {
+			return nil
+		}
+		registered := meta.Bucket([]byte(expiringBucket))
+		if registered == nil {
+			return nil
+		}
+
+		return registered.ForEach(func(k, _ []byte) error {
+			path, _, err := decodeAttachment(k)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, path)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("expiry registration loading experienced error while reading registrations: %w", err)
+	}
+	return paths, nil
+}
+
+// keysWrittenBefore sends the keys at the given path last written before the cutoff to the buffer, oldest first.
+func keysWrittenBefore(db *bbolt.DB, path [][]byte, cutoff time.Time, buffer chan []byte, dbWrap dbWrapper) error {
+	if db == nil {
+		return fmt.Errorf("expired key iteration at %s received nil db", path)
+	} else if buffer == nil {
+		return fmt.Errorf("expired key iteration at %s received nil channel", path)
+	}
+
+	defer close(buffer)
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		byTime, byKey := getIndexChild(tx, path, expiryByTime), getIndexChild(tx, path, expiryByKey)
+		if byTime == nil || byKey == nil {
+			return nil
+		}
+
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		end := uint64(cutoff.UnixNano())
+		c := byTime.Cursor()
+
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k[:8]) < end; k, _ = c.Next() {
+			if bkt == nil || rewrittenUnindexed(byKey.Get(k[8:]), bkt.Get(k[8:])) {
+				continue
+			}
+			if err := sendTo(buffer, copyBytes(k[8:]), dbWrap, "quickbolt expired key scanning", path, k[8:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("expired key iteration at %s experienced error while scanning index: %w", path, err)
+	}
+	return nil
+}
+
+// expireBatch deletes up to expireBatchSize of the pairs at the given path last written before the cutoff,
+// returning the number deleted and whether any such pairs remain.
+func expireBatch(db *bbolt.DB, path [][]byte, cutoff time.Time, env writeEnv) (deleted int, more bool, err error) {
+	if db == nil {
+		return 0, false, fmt.Errorf("expiry at %s received nil db", path)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		byTime, byKey := getIndexChild(tx, path, expiryByTime), getIndexChild(tx, path, expiryByKey)
+		if byTime == nil || byKey == nil {
+			return nil
+		}
+
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		outbox := getExpiredOutbox(tx, path)
+
+		// Keys are collected before deleting since deleting during cursor iteration skips entries.
+		var keys [][]byte
+		end := uint64(cutoff.UnixNano())
+		c := byTime.Cursor()
+		for k, _ := c.First(); k != nil && len(keys) < expireBatchSize && binary.BigEndian.Uint64(k[:8]) < end; k, _ = c.Next() {
+			keys = append(keys, copyBytes(k[8:]))
+		}
+		more = len(keys) == expireBatchSize
+
+		for _, k := range keys {
+			var old []byte
+			if bkt != nil {
+				old = bkt.Get(k)
+			}
+
+			if old == nil {
+				// The pair was removed without the index knowing, such as via RunUpdate.
+				if err := deleteExpiryIndex(tx, path, k); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if rewrittenUnindexed(byKey.Get(k), old) {
+				// The pair was rewritten without the index knowing, so its write time is unknown. It is
+				// reindexed as written now rather than deleted, as it may be fresh.
+				if err := putExpiryIndex(tx, path, k, old, time.Now()); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if outbox != nil {
+				if err := queueExpired(outbox, k, old); err != nil {
+					return err
+				}
+			}
+
+			if err := env.rules.beforeDelete(tx, path, k, old); err != nil {
+				return err
+			}
+			if err := bkt.Delete(k); err != nil {
+				return fmt.Errorf("error while deleting %s: %w", k, err)
+			}
+
+			// The index is maintained directly in case the rules were not registered in this process.
+			if err := deleteExpiryIndex(tx, path, k); err != nil {
+				return err
+			}
+
+			env.metrics.observeWrite(tx, 0)
+			deleted++
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, false, fmt.Errorf("expiry at %s experienced error while deleting keys: %w", path, err)
+	}
+	return deleted, more, nil
+}
+
+// getCreateExpiredOutbox returns the bucket queueing pairs expired at the given path for delivery, creating it if necessary.
+//
+// Once created, pairs expired at the path are queued even if no OnExpire func is registered in the current process.
+func getCreateExpiredOutbox(tx *bbolt.Tx, path [][]byte) (*bbolt.Bucket, error) {
+	all, err := getCreateMetaBucket(tx, expiredBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	bkt, err := all.CreateBucketIfNotExists([]byte(pathKey(path)))
+	if err != nil {
+		return nil, fmt.Errorf("error while accessing expired pairs for %s: %w", path, err)
+	}
+
+	return bkt, nil
+}
+
+// getExpiredOutbox returns the bucket queueing pairs expired at the given path for delivery, or nil if it does not exist.
+func getExpiredOutbox(tx *bbolt.Tx, path [][]byte) *bbolt.Bucket {
+	meta := tx.Bucket([]byte(metaBucket))
+	if meta == nil {
+		return nil
+	}
+
+	all := meta.Bucket([]byte(expiredBucket))
+	if all == nil {
+		return nil
+	}
+
+	return all.Bucket([]byte(pathKey(path)))
+}
+
+// queueExpired adds the expired pair to the outbox.
+func queueExpired(outbox *bbolt.Bucket, key, value []byte) error {
+	seq, err := outbox.NextSequence()
+	if err != nil {
+		return fmt.Errorf("error while queueing %s for delivery: %w", key, err)
+	}
+
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, seq)
+
+	record := make([]byte, 4, 4+len(key)+len(value))
+	binary.BigEndian.PutUint32(record, uint32(len(key)))
+	record = append(append(record, key...), value...)
+
+	if err := outbox.Put(id, record); err != nil {
+		return fmt.Errorf("error while queueing %s for delivery: %w", key, err)
+	}
+
+	return nil
+}
+
+// decodeExpired reverses queueExpired, returning false if the record is malformed.
+func decodeExpired(record []byte) (key, value []byte, ok bool) {
+	if len(record) < 4 {
+		return nil, nil, false
+	}
+
+	n := binary.BigEndian.Uint32(record)
+	if uint64(n) > uint64(len(record)-4) {
+		return nil, nil, false
+	}
+
+	return copyBytes(record[4 : 4+n]), copyBytes(record[4+n:]), true
+}
+
+// deliverExpired passes the pairs queued in the outbox for the given path to the func, removing each once delivered.
+//
+// Pairs are removed only after the func returns, so a crash during delivery causes pairs to be delivered again.
+// Malformed records are removed without being delivered and returned, so that they do not stall delivery.
+func deliverExpired(db *bbolt.DB, path [][]byte, onExpire func(k, v []byte), dbWrap dbWrapper) (dropped [][]byte, err error) {
+	if db == nil {
+		return nil, fmt.Errorf("expired pair delivery for %s received nil db", path)
+	}
+
+	for {
+		var ids, malformed [][]byte
+		var entries [][2][]byte
+
+		untrack := dbWrap.trackRead(dbWrap.op)
+		err := db.View(func(tx *bbolt.Tx) error {
+			outbox := getExpiredOutbox(tx, path)
+			if outbox == nil {
+				return nil
+			}
+
+			c := outbox.Cursor()
+			for id, record := c.First(); id != nil && len(ids) < expireBatchSize; id, record = c.Next() {
+				ids = append(ids, copyBytes(id))
+
+				k, v, ok := decodeExpired(record)
+				if !ok {
+					malformed = append(malformed, copyBytes(record))
+					continue
+				}
+				entries = append(entries, [2][]byte{k, v})
+			}
+			return nil
+		})
+		untrack()
+		if err != nil {
+			return dropped, fmt.Errorf("expired pair delivery for %s experienced error while reading queue: %w", path, err)
+		} else if len(ids) == 0 {
+			return dropped, nil
+		}
+
+		for _, e := range entries {
+			onExpire(e[0], e[1])
+		}
+
+		err = db.Update(func(tx *bbolt.Tx) error {
+			outbox := getExpiredOutbox(tx, path)
+			if outbox == nil {
+				return nil
+			}
+
+			for _, id := range ids {
+				if err := outbox.Delete(id); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return dropped, fmt.Errorf("expired pair delivery for %s experienced error while removing delivered pairs: %w", path, err)
+		}
+		dropped = append(dropped, malformed...)
+
+		if len(ids) < expireBatchSize {
+			return dropped, nil
+		}
+	}
+}
+
+// logDroppedExpired logs the malformed records deliverExpired removed from the outbox for the given path.
+func (d *dbWrapper) logDroppedExpired(op string, path [][]byte, dropped [][]byte) {
+	for _, record := range dropped {
+		logMutex.Lock()
+		d.logger.Warn().Str("operation", op).Str("path", fmt.Sprintf("%s", path)).Hex("record", record).Msg("expired pair delivery dropped malformed record")
+		logMutex.Unlock()
+	}
+}
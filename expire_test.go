@@ -0,0 +1,179 @@
+package quickbolt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func Test_dbWrapper_ExpireIndex(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"sessions"}
+
+	assert.Nil(t, db.Insert("preexisting", "v", path))
+	assert.Nil(t, db.ExpireIndex(path))
+
+	assert.Nil(t, db.Insert("old", "v", path))
+	assert.Nil(t, db.Insert("rewritten", "v", path))
+	time.Sleep(time.Millisecond * 5)
+	cutoff := time.Now()
+	time.Sleep(time.Millisecond * 5)
+	assert.Nil(t, db.Insert("rewritten", "v2", path))
+	assert.Nil(t, db.Insert("new", "v", path))
+	assert.Nil(t, db.Insert("deleted", "v", path))
+	assert.Nil(t, db.Delete("deleted", path))
+
+	keys := make(chan []byte)
+	go func() { assert.Nil(t, db.KeysWrittenBefore(path, cutoff, keys)) }()
+	var got []string
+	for k := range keys {
+		got = append(got, string(k))
+	}
+	assert.Equal(t, []string{"preexisting", "old"}, got)
+
+	n, err := db.ExpireBefore(path, cutoff)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	for _, k := range []string{"preexisting", "old"} {
+		v, err := db.GetValue(k, path, false)
+		assert.Nil(t, err)
+		assert.Nil(t, v, "%s must have expired", k)
+	}
+	for _, k := range []string{"rewritten", "new"} {
+		v, err := db.GetValue(k, path, true)
+		assert.Nil(t, err)
+		assert.NotNil(t, v)
+	}
+
+	n, err = db.ExpireBefore(path, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func Test_dbWrapper_ExpireIndex_Reopened(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := CreateWith("foo.db", dir)
+	assert.Nil(t, err)
+
+	path := []string{"sessions"}
+	assert.Nil(t, db.ExpireIndex(path))
+	assert.Nil(t, db.Insert("overwritten", "v", path))
+	assert.Nil(t, db.Insert("reinserted", "v", path))
+	assert.Nil(t, db.Insert("unindexed", "v", path))
+	time.Sleep(time.Millisecond * 5)
+	cutoff := time.Now()
+	time.Sleep(time.Millisecond * 5)
+
+	// The registration is restored on open, before ExpireIndex is called again.
+	assert.Nil(t, db.Close())
+	db, err = OpenWith("foo.db", dir)
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("overwritten", "v2", path))
+	assert.Nil(t, db.Delete("reinserted", path))
+	assert.Nil(t, db.Insert("reinserted", "v", path))
+	assert.Nil(t, db.RunUpdate(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(rootBucket)).Bucket([]byte("sessions")).Put([]byte("unindexed"), []byte("v2"))
+	}))
+
+	n, err := db.ExpireBefore(path, cutoff)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	for _, k := range []string{"overwritten", "reinserted", "unindexed"} {
+		v, err := db.GetValue(k, path, true)
+		assert.Nil(t, err)
+		assert.NotNil(t, v, "%s must not have expired", k)
+	}
+
+	n, err = db.ExpireBefore(path, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+}
+
+func Test_dbWrapper_OnExpire(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := CreateWith("foo.db", dir)
+	assert.Nil(t, err)
+
+	path := []string{"sessions"}
+
+	var got []string
+	assert.Nil(t, db.ExpireIndex(path))
+	assert.Nil(t, db.OnExpire(path, func(k, v []byte) { got = append(got, string(k)+"="+string(v)) }))
+
+	assert.Nil(t, db.Insert("a", "1", path))
+	n, err := db.ExpireBefore(path, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"a=1"}, got)
+
+	// Pairs expired while no func is registered are delivered once one is registered again.
+	assert.Nil(t, db.Close())
+	db, err = OpenWith("foo.db", dir)
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.ExpireIndex(path))
+	assert.Nil(t, db.Insert("b", "2", path))
+	n, err = db.ExpireBefore(path, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	got = nil
+	assert.Nil(t, db.OnExpire(path, func(k, v []byte) { got = append(got, string(k)+"="+string(v)) }))
+	assert.Equal(t, []string{"b=2"}, got)
+
+	got = nil
+	assert.Nil(t, db.OnExpire(path, func(k, v []byte) { got = append(got, string(k)+"="+string(v)) }))
+	assert.Nil(t, got, "delivered pairs must not be delivered again")
+}
+
+func Test_dbWrapper_OnExpire_Malformed(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"sessions"}
+	p := [][]byte{[]byte("sessions")}
+
+	var got []string
+	assert.Nil(t, db.ExpireIndex(path))
+	assert.Nil(t, db.OnExpire(path, func(k, v []byte) { got = append(got, string(k)+"="+string(v)) }))
+
+	// A record too short to hold a key length and one whose key length overruns the record.
+	assert.Nil(t, db.RunUpdate(func(tx *bbolt.Tx) error {
+		outbox, err := getCreateExpiredOutbox(tx, p)
+		if err != nil {
+			return err
+		}
+		assert.Nil(t, outbox.Put([]byte{0}, []byte{1, 2}))
+		assert.Nil(t, outbox.Put([]byte{1}, []byte{0, 0, 0, 9, 'k'}))
+		return nil
+	}))
+
+	assert.Nil(t, db.Insert("a", "1", path))
+	n, err := db.ExpireBefore(path, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"a=1"}, got)
+
+	assert.Nil(t, db.RunView(func(tx *bbolt.Tx) error {
+		k, _ := getExpiredOutbox(tx, p).Cursor().First()
+		assert.Nil(t, k, "malformed records must be removed from the outbox")
+		return nil
+	}))
+}
@@ -0,0 +1,164 @@
+package quickbolt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.etcd.io/bbolt"
+)
+
+// ChangeKind describes how a key differs between two databases.
+type ChangeKind int
+
+const (
+	// Added keys are only present in the changed database.
+	Added ChangeKind = iota + 1
+	// Removed keys are only present in the original database.
+	Removed
+	// Modified keys are present in both, paired with different values.
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Change is a difference between a database and the one it was forked from, as reported by DiffFrom.
+type Change struct {
+	Kind ChangeKind
+	// Path is the path of the bucket containing the key.
+	Path [][]byte
+	Key  []byte
+	// IsBucket is true if the key names a nested bucket rather than a value.
+	IsBucket bool
+	// Old is the value in the original database, or nil if the key was added or names a bucket.
+	Old []byte
+	// New is the value in the changed database, or nil if the key was removed or names a bucket.
+	New []byte
+}
+
+// forkTo copies the db to a new file at the given path within a single read transaction.
+func forkTo(db *bbolt.DB, path string, o options) error {
+	if db == nil {
+		return fmt.Errorf("forking received nil db")
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("forking experienced error: %s already exists", path)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("forking experienced error while checking %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), o.dirMode); err != nil {
+		return fmt.Errorf("forking experienced error while creating directory for %s: %w", path, err)
+	}
+
+	if err := db.View(func(tx *bbolt.Tx) error { return tx.CopyFile(path, o.fileMode) }); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("forking experienced error while copying db to %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// diffTrees sends the differences between the bucket trees at the given path, old being from the original
+// database and changed from the changed one, to the buffer in key order, depth-first.
+// Either bucket may be nil, in which case it is treated as empty.
+func diffTrees(old, changed *bbolt.Bucket, path [][]byte, buffer chan Change, dbWrap dbWrapper) error {
+	var oc, nc *bbolt.Cursor
+	var ok, ov, nk, nv []byte
+	if old != nil {
+		oc = old.Cursor()
+		ok, ov = oc.First()
+	}
+	if changed != nil {
+		nc = changed.Cursor()
+		nk, nv = nc.First()
+	}
+
+	send := func(c Change) error {
+		return sendTo(buffer, c, dbWrap, "quickbolt diffing", path, c.Key)
+	}
+
+	removed := func(k, v []byte) error {
+		if v != nil {
+			return send(Change{Kind: Removed, Path: copyPath(path), Key: copyBytes(k), Old: copyBytes(v)})
+		}
+		if err := send(Change{Kind: Removed, Path: copyPath(path), Key: copyBytes(k), IsBucket: true}); err != nil {
+			return err
+		}
+		return diffTrees(old.Bucket(k), nil, append(copyPath(path), copyBytes(k)), buffer, dbWrap)
+	}
+
+	added := func(k, v []byte) error {
+		if v != nil {
+			return send(Change{Kind: Added, Path: copyPath(path), Key: copyBytes(k), New: copyBytes(v)})
+		}
+		if err := send(Change{Kind: Added, Path: copyPath(path), Key: copyBytes(k), IsBucket: true}); err != nil {
+			return err
+		}
+		return diffTrees(nil, changed.Bucket(k), append(copyPath(path), copyBytes(k)), buffer, dbWrap)
+	}
+
+	for ok != nil || nk != nil {
+		switch c := compareKeys(ok, nk); {
+		case c < 0:
+			if err := removed(ok, ov); err != nil {
+				return err
+			}
+			ok, ov = oc.Next()
+		case c > 0:
+			if err := added(nk, nv); err != nil {
+				return err
+			}
+			nk, nv = nc.Next()
+		default:
+			var err error
+			switch {
+			case ov == nil && nv == nil:
+				err = diffTrees(old.Bucket(ok), changed.Bucket(nk), append(copyPath(path), copyBytes(ok)), buffer, dbWrap)
+			case ov != nil && nv != nil:
+				if !bytes.Equal(ov, nv) {
+					err = send(Change{Kind: Modified, Path: copyPath(path), Key: copyBytes(ok), Old: copyBytes(ov), New: copyBytes(nv)})
+				}
+			default:
+				// The key names a bucket on one side and a value on the other.
+				if err = removed(ok, ov); err == nil {
+					err = added(nk, nv)
+				}
+			}
+			if err != nil {
+				return err
+			}
+			ok, ov = oc.Next()
+			nk, nv = nc.Next()
+		}
+	}
+
+	return nil
+}
+
+// compareKeys compares cursor keys, ordering nil, meaning the cursor is exhausted, after every key.
+func compareKeys(a, b []byte) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	default:
+		return bytes.Compare(a, b)
+	}
+}
@@ -0,0 +1,67 @@
+package quickbolt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_Fork(t *testing.T) {
+	dir := t.TempDir()
+	db, err := CreateWith("foo.db", dir)
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.SetUnique([]string{"users"}))
+	assert.Nil(t, db.Insert("k1", "v1", []string{"users"}))
+	assert.Nil(t, db.Insert("k2", "v2", []string{"users"}))
+	assert.Nil(t, db.Insert("k", "v", []string{"old", "nested"}))
+	assert.Nil(t, db.Insert("same", "v", []string{"kept"}))
+
+	fork, err := db.Fork(filepath.Join(dir, "fork.db"))
+	assert.Nil(t, err)
+
+	defer fork.RemoveFile()
+
+	_, err = db.Fork(filepath.Join(dir, "fork.db"))
+	assert.NotNil(t, err, "forking must not overwrite an existing file")
+
+	var dup ErrDuplicateValue
+	assert.ErrorAs(t, fork.Insert("k3", "v1", []string{"users"}), &dup, "the fork must inherit rules and indexes")
+
+	assert.Nil(t, fork.Insert("k1", "changed", []string{"users"}))
+	assert.Nil(t, fork.Delete("k2", []string{"users"}))
+	assert.Nil(t, fork.DeleteBucket("old", []string{}))
+	assert.Nil(t, fork.Insert("k", "v", []string{"new"}))
+
+	v, err := db.GetValue("k1", []string{"users"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", string(v), "the parent must not see changes to the fork")
+
+	buffer := make(chan Change, 10)
+	assert.Nil(t, fork.DiffFrom(db, buffer))
+
+	var got []Change
+	for c := range buffer {
+		got = append(got, c)
+	}
+
+	b := func(s ...string) [][]byte {
+		p := [][]byte{}
+		for _, seg := range s {
+			p = append(p, []byte(seg))
+		}
+		return p
+	}
+	assert.Equal(t, []Change{
+		{Kind: Added, Path: b(), Key: []byte("new"), IsBucket: true},
+		{Kind: Added, Path: b("new"), Key: []byte("k"), New: []byte("v")},
+		{Kind: Removed, Path: b(), Key: []byte("old"), IsBucket: true},
+		{Kind: Removed, Path: b("old"), Key: []byte("nested"), IsBucket: true},
+		{Kind: Removed, Path: b("old", "nested"), Key: []byte("k"), Old: []byte("v")},
+		{Kind: Modified, Path: b("users"), Key: []byte("k1"), Old: []byte("v1"), New: []byte("changed")},
+		{Kind: Removed, Path: b("users"), Key: []byte("k2"), Old: []byte("v2")},
+	}, got)
+}
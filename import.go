@@ -0,0 +1,114 @@
+package quickbolt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"go.etcd.io/bbolt"
+)
+
+// Source supplies the entries written by ImportFrom.
+type Source interface {
+	// Next returns the next entry and the path of the bucket it belongs in.
+	//
+	// Next returns io.EOF once the source is exhausted. The returned slices are copied
+	// before the next call, so sources may reuse them.
+	Next() (path [][]byte, key, value []byte, err error)
+}
+
+// ImportOption configures an import.
+type ImportOption func(*importOptions)
+
+type importOptions struct {
+	progress func(imported int)
+}
+
+// WithImportProgress sets a func called after each batch of an import is committed with the number of entries imported so far.
+func WithImportProgress(f func(imported int)) ImportOption {
+	return func(o *importOptions) {
+		o.progress = f
+	}
+}
+
+// sliceSource is a Source reading from a slice of entries.
+type sliceSource struct {
+	entries []PathedEntry
+}
+
+// SliceSource returns a Source supplying the given entries in order.
+func SliceSource(entries []PathedEntry) Source {
+	return &sliceSource{entries: entries}
+}
+
+func (s *sliceSource) Next() ([][]byte, []byte, []byte, error) {
+	if len(s.entries) == 0 {
+		return nil, nil, nil, io.EOF
+	}
+
+	e := s.entries[0]
+	s.entries = s.entries[1:]
+
+	return e.Path, e.Key, e.Value, nil
+}
+
+// readImportBatch reads up to n entries from the source, copying each.
+//
+// An io.EOF is returned alongside the final entries once the source is exhausted.
+func readImportBatch(src Source, n int) ([]PathedEntry, error) {
+	batch := make([]PathedEntry, 0, n)
+
+	for len(batch) < n {
+		path, key, value, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return batch, io.EOF
+		} else if err != nil {
+			return batch, err
+		}
+
+		if len(path) == 0 {
+			return batch, fmt.Errorf("entry %s has empty path", key)
+		} else if key == nil || value == nil {
+			return batch, fmt.Errorf("entry in %s has nil key or value", path)
+		}
+
+		batch = append(batch, PathedEntry{Path: copyPath(path), Key: copyBytes(key), Value: copyBytes(value)})
+	}
+
+	return batch, nil
+}
+
+// importBatch writes the entries to the db within a single transaction.
+func importBatch(db *bbolt.DB, batch []PathedEntry, envFor func([][]byte) writeEnv) error {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		for _, e := range batch {
+			bkt, err := getCreateBucket(tx, e.Path)
+			if err != nil {
+				return fmt.Errorf("error while navigating path: %w", err)
+			}
+
+			env := envFor(e.Path)
+			k, err := env.rules.storeKey(tx, e.Path, e.Key)
+			if err != nil {
+				return err
+			}
+
+			if err := env.rules.beforePut(tx, e.Path, k, bkt.Get(k), e.Value); err != nil {
+				return err
+			}
+
+			if err := bkt.Put(k, e.Value); err != nil {
+				return fmt.Errorf("error while writing %s in %s: %w", e.Key, e.Path, err)
+			}
+
+			env.metrics.observeWrite(tx, len(k)+len(e.Value))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("error while importing %d entries to db: %w", len(batch), err)
+	}
+
+	return nil
+}
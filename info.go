@@ -0,0 +1,112 @@
+package quickbolt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+)
+
+// boltModule is the module path of bbolt, whose version Info reports.
+const boltModule = "go.etcd.io/bbolt"
+
+// Info describes how a database was opened, for printing in diagnostics.
+type Info struct {
+	// Path is the absolute path of the database file.
+	Path string
+	// Root is the name of the top-level bucket under which bucket paths are resolved.
+	Root string
+	// PageSize is the page size of the database file, in bytes.
+	PageSize int
+	// ReadOnly is true if the database file was opened read-only.
+	ReadOnly bool
+	// BoltVersion is the version of bbolt the binary was built with, or "unknown" if build info is unavailable.
+	BoltVersion string
+
+	// FileMode is the permissions used when the database file was created.
+	FileMode os.FileMode
+	// InitialMmapSize through MaxBatchDelay are the bbolt settings in effect, as described on bbolt.Options and bbolt.DB.
+	InitialMmapSize int
+	NoSync          bool
+	NoGrowSync      bool
+	NoFreelistSync  bool
+	FreelistType    string
+	StrictMode      bool
+	MaxBatchSize    int
+	MaxBatchDelay   time.Duration
+
+	// BufferTimeout is the timeout set via SetBufferTimeout, or the default.
+	BufferTimeout time.Duration
+	// AutoCompact is true if the database was opened via WithAutoCompact.
+	AutoCompact bool
+	// LeaseSweep is the interval set via WithLeaseSweep, or 0 if disabled.
+	LeaseSweep time.Duration
+	// LongReadWarning is the age set via WithLongReadWarning, or 0 if disabled.
+	LongReadWarning time.Duration
+	// NestedTxDetection is true if the database was opened via WithNestedTxDetection.
+	NestedTxDetection bool
+}
+
+// String returns a one-line summary of the info, e.g. for a diagnostic header.
+func (i Info) String() string {
+	mode := "read-write"
+	if i.ReadOnly {
+		mode = "read-only"
+	}
+
+	return fmt.Sprintf("quickbolt db at %s (%s, root %q, page size %d, freelist %s, bbolt %s)", i.Path, mode, i.Root, i.PageSize, i.FreelistType, i.BoltVersion)
+}
+
+// info returns the Info of the wrapper's database.
+func (d *dbWrapper) info() Info {
+	db, release := d.acquire()
+	defer release()
+
+	path := db.Path()
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	return Info{
+		Path:              path,
+		Root:              rootBucket,
+		PageSize:          db.Info().PageSize,
+		ReadOnly:          db.IsReadOnly(),
+		BoltVersion:       boltVersion(),
+		FileMode:          d.opts.fileMode,
+		InitialMmapSize:   d.opts.initialMmapSize,
+		NoSync:            db.NoSync,
+		NoGrowSync:        db.NoGrowSync,
+		NoFreelistSync:    db.NoFreelistSync,
+		FreelistType:      string(db.FreelistType),
+		StrictMode:        db.StrictMode,
+		MaxBatchSize:      db.MaxBatchSize,
+		MaxBatchDelay:     db.MaxBatchDelay,
+		BufferTimeout:     d.bufferTimeout,
+		AutoCompact:       d.opts.autoCompact != nil,
+		LeaseSweep:        d.opts.leaseSweep,
+		LongReadWarning:   d.opts.longRead,
+		NestedTxDetection: d.opts.nestedTxDetection,
+	}
+}
+
+// boltVersion returns the version of bbolt in the binary's build info, or "unknown" if unavailable.
+func boltVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	for _, dep := range bi.Deps {
+		if dep.Path != boltModule {
+			continue
+		}
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+
+	return "unknown"
+}
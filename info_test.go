@@ -0,0 +1,28 @@
+package quickbolt
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_Info(t *testing.T) {
+	dir := t.TempDir()
+	db, err := CreateWith("foo.db", dir, WithPageSize(8192), WithNoGrowSync(), WithLongReadWarning(time.Minute))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	info := db.Info()
+	assert.Equal(t, filepath.Join(dir, "foo.db"), info.Path)
+	assert.Equal(t, rootBucket, info.Root)
+	assert.Equal(t, 8192, info.PageSize)
+	assert.False(t, info.ReadOnly)
+	assert.True(t, info.NoGrowSync)
+	assert.Equal(t, time.Minute, info.LongReadWarning)
+	assert.Equal(t, defaultFileMode, info.FileMode)
+	assert.NotEmpty(t, info.BoltVersion)
+	assert.Contains(t, info.String(), "page size 8192")
+}
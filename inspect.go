@@ -0,0 +1,148 @@
+package quickbolt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.etcd.io/bbolt"
+)
+
+// Rendering determines how Inspect prints keys and values.
+type Rendering int
+
+const (
+	// RenderAuto prints JSON objects and arrays compactly, printable text as a quoted string, and anything else as hex.
+	RenderAuto Rendering = iota
+	// RenderUTF8 prints bytes as a quoted string.
+	RenderUTF8
+	// RenderHex prints bytes as hex.
+	RenderHex
+	// RenderJSON prints bytes as indented JSON, falling back to hex if they are not valid JSON.
+	RenderJSON
+)
+
+// InspectOptions configures the output of Inspect.
+type InspectOptions struct {
+	// Keys determines how keys are rendered.
+	Keys Rendering
+	// Values determines how values are rendered.
+	Values Rendering
+	// MaxValueLen truncates rendered values longer than the given number of characters.
+	// Values are not truncated if MaxValueLen is 0.
+	MaxValueLen int
+}
+
+// inspect writes a human-readable dump of the bucket at the given path and everything nested under it to w.
+func inspect(db *bbolt.DB, path [][]byte, w io.Writer, opts InspectOptions) error {
+	if db == nil {
+		return fmt.Errorf("inspection of %s received nil db", path)
+	} else if w == nil {
+		return fmt.Errorf("inspection of %s received nil writer", path)
+	}
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		names := make([]string, len(path))
+		for i, p := range path {
+			names[i] = render(p, opts.Keys, 0)
+		}
+		if _, err := fmt.Fprintf(w, "%s/\n", strings.Join(names, "/")); err != nil {
+			return err
+		}
+
+		return inspectBucket(bkt, w, opts, 1)
+	})
+
+	if err != nil {
+		return fmt.Errorf("inspection of %s experienced error while writing dump: %w", path, err)
+	}
+	return nil
+}
+
+func inspectBucket(bkt *bbolt.Bucket, w io.Writer, opts InspectOptions, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	c := bkt.Cursor()
+
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		key := render(k, opts.Keys, 0)
+
+		if v != nil {
+			if _, err := fmt.Fprintf(w, "%s%s: %s\n", indent, key, render(v, opts.Values, opts.MaxValueLen)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "%s%s/\n", indent, key); err != nil {
+			return err
+		}
+		if err := inspectBucket(bkt.Bucket(k), w, opts, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// render formats b according to r, truncating the result to maxLen characters if maxLen is above 0.
+func render(b []byte, r Rendering, maxLen int) string {
+	var s string
+
+	switch r {
+	case RenderUTF8:
+		s = strconv.Quote(string(b))
+	case RenderHex:
+		s = hex.EncodeToString(b)
+	case RenderJSON:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, b, "", "  "); err != nil {
+			s = hex.EncodeToString(b)
+		} else {
+			s = buf.String()
+		}
+	default:
+		s = renderAuto(b)
+	}
+
+	if maxLen > 0 && utf8.RuneCountInString(s) > maxLen {
+		s = string([]rune(s)[:maxLen]) + fmt.Sprintf("... (%d bytes)", len(b))
+	}
+
+	return s
+}
+
+func renderAuto(b []byte) string {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, trimmed); err == nil {
+			return buf.String()
+		}
+	}
+
+	if utf8.Valid(b) && isPrintable(b) {
+		return strconv.Quote(string(b))
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func isPrintable(b []byte) bool {
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package quickbolt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_render(t *testing.T) {
+	tests := []struct {
+		name   string
+		b      []byte
+		r      Rendering
+		maxLen int
+		want   string
+	}{
+		{name: "auto text", b: []byte("hello"), r: RenderAuto, want: `"hello"`},
+		{name: "auto json", b: []byte(`{ "a": 1 }`), r: RenderAuto, want: `{"a":1}`},
+		{name: "auto binary", b: []byte{0, 1, 255}, r: RenderAuto, want: "0001ff"},
+		{name: "utf8", b: []byte{0}, r: RenderUTF8, want: `"\x00"`},
+		{name: "hex", b: []byte("a"), r: RenderHex, want: "61"},
+		{name: "json", b: []byte(`[1]`), r: RenderJSON, want: "[\n  1\n]"},
+		{name: "invalid json", b: []byte("a"), r: RenderJSON, want: "61"},
+		{name: "truncated", b: []byte("abcdef"), r: RenderHex, maxLen: 4, want: "6162... (6 bytes)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, render(tt.b, tt.r, tt.maxLen))
+		})
+	}
+}
+
+func Test_dbWrapper_Inspect(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k1", "v1", []string{"a"}))
+	assert.Nil(t, db.Insert("k2", `{"x": true}`, []string{"a", "b"}))
+
+	var sb strings.Builder
+	assert.Nil(t, db.Inspect([]string{"a"}, &sb, InspectOptions{}))
+
+	want := `"a"/
+  "b"/
+    "k2": {"x":true}
+  "k1": "v1"
+`
+	assert.Equal(t, want, sb.String())
+}
@@ -0,0 +1,196 @@
+package quickbolt
+
+import (
+	"bytes"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// Invariant is a structural check of the db's contents, such as DecodesAs, IndexOf, or NoEmptyBuckets,
+// run via Verify or registered via AddInvariant.
+type Invariant struct {
+	desc  string
+	paths []any
+	// check returns an ErrInvariantViolation describing the first violation found at the resolved paths.
+	check func(d *dbWrapper, tx *bbolt.Tx, paths [][][]byte) error
+}
+
+// String describes the invariant.
+func (i Invariant) String() string {
+	return i.desc
+}
+
+// boundInvariant is an invariant with its bucket paths resolved.
+type boundInvariant struct {
+	inv   Invariant
+	paths [][][]byte
+}
+
+// bind resolves the invariant's bucket paths relative to the wrapper's scope.
+func (d *dbWrapper) bind(inv Invariant) (boundInvariant, error) {
+	if inv.check == nil {
+		return boundInvariant{}, fmt.Errorf("invariant is zero")
+	}
+
+	b := boundInvariant{inv: inv}
+	for _, p := range inv.paths {
+		r, err := d.resolveBucketPath(p)
+		if err != nil {
+			return boundInvariant{}, fmt.Errorf("error while resolving path of invariant %s: %w", inv, err)
+		}
+		b.paths = append(b.paths, r)
+	}
+
+	return b, nil
+}
+
+// DecodesAs requires every value at the given path to decode into a T via the codec used for the path
+// by the typed helpers. Nested buckets are not checked.
+//
+// BucketPath must be of type []string, [][]byte, []any, or Path.
+func DecodesAs[T any](bucketPath any) Invariant {
+	var zero T
+
+	return Invariant{
+		desc:  fmt.Sprintf("values at %v decode as %T", bucketPath, zero),
+		paths: []any{bucketPath},
+		check: func(d *dbWrapper, tx *bbolt.Tx, paths [][][]byte) error {
+			p := paths[0]
+			bkt, err := getBucket(tx, p, false)
+			if err != nil {
+				return fmt.Errorf("error while navigating path: %w", err)
+			} else if bkt == nil {
+				return nil
+			}
+
+			codec := d.codec
+			if r := d.rules.forPath(p); r != nil && r.codec != nil {
+				codec = r.codec
+			} else if codec == nil {
+				codec = JSONCodec{}
+			}
+
+			c := bkt.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				if v == nil {
+					continue
+				}
+
+				var t T
+				if err := codec.Unmarshal(v, &t); err != nil {
+					return newErrInvariantViolation(fmt.Sprintf("value of %s at %s (%v)", k, p, err), fmt.Sprintf("values at %s decode as %T", p, zero))
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// IndexOf requires the bucket at indexPath to index the keys at dataPath: the value of every pair
+// at indexPath must be a key at dataPath, and every key at dataPath must be the value of a pair at indexPath.
+// Nested buckets are not checked.
+//
+// IndexPath and dataPath must be of type []string, [][]byte, []any, or Path.
+func IndexOf(indexPath, dataPath any) Invariant {
+	return Invariant{
+		desc:  fmt.Sprintf("%v indexes %v", indexPath, dataPath),
+		paths: []any{indexPath, dataPath},
+		check: func(d *dbWrapper, tx *bbolt.Tx, paths [][][]byte) error {
+			desc := fmt.Sprintf("%s indexes %s", paths[0], paths[1])
+
+			idx, err := getBucket(tx, paths[0], false)
+			if err != nil {
+				return fmt.Errorf("error while navigating index path: %w", err)
+			}
+			data, err := getBucket(tx, paths[1], false)
+			if err != nil {
+				return fmt.Errorf("error while navigating data path: %w", err)
+			}
+
+			indexed := make(map[string]bool)
+			if idx != nil {
+				c := idx.Cursor()
+				for k, v := c.First(); k != nil; k, v = c.Next() {
+					if v == nil {
+						continue
+					}
+					if data == nil || data.Get(v) == nil {
+						return newErrInvariantViolation(fmt.Sprintf("index entry %s naming missing key %s", k, v), desc)
+					}
+					indexed[string(v)] = true
+				}
+			}
+
+			if data != nil {
+				c := data.Cursor()
+				for k, v := c.First(); k != nil; k, v = c.Next() {
+					if v != nil && !indexed[string(k)] {
+						return newErrInvariantViolation(fmt.Sprintf("unindexed key %s", k), desc)
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// NoEmptyBuckets requires every bucket nested under the given path, at any depth, to hold at least one key.
+//
+// BucketPath must be of type []string, [][]byte, []any, or Path.
+func NoEmptyBuckets(bucketPath any) Invariant {
+	return Invariant{
+		desc:  fmt.Sprintf("no empty buckets under %v", bucketPath),
+		paths: []any{bucketPath},
+		check: func(d *dbWrapper, tx *bbolt.Tx, paths [][][]byte) error {
+			bkt, err := getBucket(tx, paths[0], false)
+			if err != nil {
+				return fmt.Errorf("error while navigating path: %w", err)
+			} else if bkt == nil {
+				return nil
+			}
+
+			return findEmptyBucket(bkt, paths[0], fmt.Sprintf("no empty buckets under %s", paths[0]))
+		},
+	}
+}
+
+// findEmptyBucket returns an ErrInvariantViolation for the first empty bucket nested under bkt, depth-first.
+func findEmptyBucket(bkt *bbolt.Bucket, path [][]byte, desc string) error {
+	c := bkt.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v != nil {
+			continue
+		}
+
+		child := bkt.Bucket(k)
+		childPath := append(copyPath(path), copyBytes(k))
+		if first, _ := child.Cursor().First(); first == nil {
+			return newErrInvariantViolation(fmt.Sprintf("empty bucket %s", bytes.Join(childPath, []byte("/"))), desc)
+		}
+
+		if err := findEmptyBucket(child, childPath, desc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verify runs the given invariants within a single read transaction, returning the first violation found.
+func verify(db *bbolt.DB, d *dbWrapper, invariants []boundInvariant) error {
+	if db == nil {
+		return fmt.Errorf("verification received nil db")
+	}
+
+	return db.View(func(tx *bbolt.Tx) error {
+		for _, inv := range invariants {
+			if err := inv.inv.check(d, tx, inv.paths); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
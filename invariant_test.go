@@ -0,0 +1,55 @@
+package quickbolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type invariantUser struct {
+	Name string `json:"name"`
+}
+
+func Test_dbWrapper_Verify(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	users := []string{"users"}
+	byName := []string{"users_by_name"}
+
+	assert.Nil(t, Put(db, "1", invariantUser{Name: "alice"}, users))
+	assert.Nil(t, Put(db, "2", invariantUser{Name: "bob"}, users))
+	assert.Nil(t, db.Insert("alice", "1", byName))
+	assert.Nil(t, db.Insert("bob", "2", byName))
+
+	assert.Nil(t, db.AddInvariant(DecodesAs[invariantUser](users)))
+	assert.Nil(t, db.Verify(IndexOf(byName, users), NoEmptyBuckets([]string{})))
+
+	var violation ErrInvariantViolation
+
+	// A value that doesn't decode violates the registered invariant.
+	assert.Nil(t, db.Insert("3", "not json", users))
+	assert.ErrorAs(t, db.Verify(), &violation)
+	assert.Contains(t, violation.What, "value of 3 at")
+	assert.Nil(t, db.Delete("3", users))
+	assert.Nil(t, db.Verify())
+
+	// An index entry naming a missing key, and a key missing from the index, each violate IndexOf.
+	assert.Nil(t, db.Insert("carol", "3", byName))
+	assert.ErrorAs(t, db.Verify(IndexOf(byName, users)), &violation)
+	assert.Contains(t, violation.What, "missing key 3")
+	assert.Nil(t, db.Delete("carol", byName))
+
+	assert.Nil(t, Put(db, "3", invariantUser{Name: "carol"}, users))
+	assert.ErrorAs(t, db.Verify(IndexOf(byName, users)), &violation)
+	assert.Contains(t, violation.What, "unindexed key 3")
+
+	assert.Nil(t, db.InsertBucket("empty", []string{"nested", "inner"}))
+	assert.ErrorAs(t, db.Verify(NoEmptyBuckets([]string{"nested"})), &violation)
+	assert.Contains(t, violation.What, "nested/inner/empty")
+
+	assert.NotNil(t, db.AddInvariant(Invariant{}))
+	assert.NotNil(t, db.Verify(DecodesAs[int](1.5i)))
+}
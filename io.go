@@ -60,3 +60,25 @@ func removeFile(db *bbolt.DB) error {
 
 	return os.Remove(path)
 }
+
+// dirArgs converts an optional directory into the variadic form accepted by dbPath.
+func dirArgs(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+
+	return []string{dir}
+}
+
+// removeTempDir closes the db and deletes the temporary directory containing it.
+func removeTempDir(db *bbolt.DB, dir string) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	if err := closeDB(db); err != nil {
+		return fmt.Errorf("error while closing db: %w", err)
+	}
+
+	return os.RemoveAll(dir)
+}
@@ -0,0 +1,83 @@
+package quickbolt
+
+import (
+	"bytes"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// JoinKind determines which keys Join sends.
+type JoinKind int
+
+const (
+	// JoinInner sends the keys present in both buckets.
+	JoinInner JoinKind = iota
+	// JoinLeft sends every key in the first bucket, with a nil B for keys missing from the second.
+	JoinLeft
+)
+
+// JoinedEntry pairs the values stored under the same key in the two buckets given to Join.
+type JoinedEntry struct {
+	Key []byte
+	A   []byte
+	// B is nil if the key is missing from the second bucket, which is only the case for a JoinLeft.
+	B []byte
+}
+
+// join sends the entries of the buckets at pathA and pathB sharing keys to the buffer, in key order,
+// advancing a cursor over each bucket in step with the other.
+func join(db *bbolt.DB, pathA, pathB [][]byte, kind JoinKind, buffer chan JoinedEntry, dbWrap dbWrapper) error {
+	if db == nil {
+		return fmt.Errorf("join of %s and %s received nil db", pathA, pathB)
+	} else if buffer == nil {
+		return fmt.Errorf("join of %s and %s received nil channel", pathA, pathB)
+	} else if kind != JoinInner && kind != JoinLeft {
+		return fmt.Errorf("join of %s and %s received unknown kind %d", pathA, pathB, kind)
+	}
+
+	defer close(buffer)
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		a, err := getBucket(tx, pathA, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating first path: %w", err)
+		}
+
+		b, err := getBucket(tx, pathB, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating second path: %w", err)
+		}
+
+		ca, cb := a.Cursor(), b.Cursor()
+		kb, vb := cb.First()
+
+		for ka, va := ca.First(); ka != nil; ka, va = ca.Next() {
+			if va == nil {
+				continue
+			}
+
+			// The second cursor only ever moves forward, so each bucket is read once.
+			for kb != nil && bytes.Compare(kb, ka) < 0 {
+				kb, vb = cb.Next()
+			}
+
+			e := JoinedEntry{Key: copyBytes(ka), A: copyBytes(va)}
+			if kb != nil && vb != nil && bytes.Equal(kb, ka) {
+				e.B = copyBytes(vb)
+			} else if kind == JoinInner {
+				continue
+			}
+
+			if err := sendTo(buffer, e, dbWrap, "quickbolt join", pathA, e.Key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("join of %s and %s experienced error while scanning keys: %w", pathA, pathB, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package quickbolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_Join(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	users, meta := []string{"users"}, []string{"meta"}
+	for _, k := range []string{"ann", "bob", "cat", "dan"} {
+		assert.Nil(t, db.Insert(k, "user-"+k, users))
+	}
+	for _, k := range []string{"aaa", "bob", "dan", "eve"} {
+		assert.Nil(t, db.Insert(k, "meta-"+k, meta))
+	}
+	assert.Nil(t, db.InsertBucket("cat", meta))
+
+	collect := func(kind JoinKind) []JoinedEntry {
+		buffer := make(chan JoinedEntry)
+		go func() { assert.Nil(t, db.Join(users, meta, buffer, kind)) }()
+
+		var got []JoinedEntry
+		for e := range buffer {
+			got = append(got, e)
+		}
+		return got
+	}
+
+	assert.Equal(t, []JoinedEntry{
+		{Key: []byte("bob"), A: []byte("user-bob"), B: []byte("meta-bob")},
+		{Key: []byte("dan"), A: []byte("user-dan"), B: []byte("meta-dan")},
+	}, collect(JoinInner))
+
+	assert.Equal(t, []JoinedEntry{
+		{Key: []byte("ann"), A: []byte("user-ann")},
+		{Key: []byte("bob"), A: []byte("user-bob"), B: []byte("meta-bob")},
+		{Key: []byte("cat"), A: []byte("user-cat")},
+		{Key: []byte("dan"), A: []byte("user-dan"), B: []byte("meta-dan")},
+	}, collect(JoinLeft), "keys of nested buckets must not match")
+}
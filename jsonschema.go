@@ -0,0 +1,402 @@
+package quickbolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// jsonSchema is a compiled JSON Schema supporting a subset of the specification's keywords.
+type jsonSchema struct {
+	types      []string
+	enum       []interface{}
+	constant   *interface{}
+	properties map[string]*jsonSchema
+	required   []string
+	// additional is nil if additional properties are unconstrained.
+	additional *jsonSchema
+	// noAdditional is true if additionalProperties is false.
+	noAdditional bool
+	items        *jsonSchema
+	minItems     *int
+	maxItems     *int
+	minLength    *int
+	maxLength    *int
+	pattern      *regexp.Regexp
+	minimum      *float64
+	maximum      *float64
+	exclusiveMin *float64
+	exclusiveMax *float64
+	// never is true for the schema literal false, which rejects every value.
+	never bool
+}
+
+// compileJSONSchema parses the given schema document.
+func compileJSONSchema(schema []byte) (*jsonSchema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(schema, &raw); err != nil {
+		return nil, fmt.Errorf("error while parsing schema: %w", err)
+	}
+
+	return compileJSONSchemaValue(raw)
+}
+
+func compileJSONSchemaValue(raw interface{}) (*jsonSchema, error) {
+	switch r := raw.(type) {
+	case bool:
+		return &jsonSchema{never: !r}, nil
+	case map[string]interface{}:
+		return compileJSONSchemaObject(r)
+	default:
+		return nil, fmt.Errorf("schema must be an object or boolean")
+	}
+}
+
+// jsonSchemaKeywords are the keywords compileJSONSchemaObject accepts. Annotations, which
+// validate nothing, are accepted alongside the keywords that are implemented.
+var jsonSchemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "properties": true, "required": true,
+	"additionalProperties": true, "items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minimum": true, "maximum": true,
+	"exclusiveMinimum": true, "exclusiveMaximum": true,
+
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// jsonSchemaTypes are the type names of the JSON Schema specification.
+var jsonSchemaTypes = map[string]bool{
+	"null": true, "boolean": true, "string": true, "number": true, "integer": true, "array": true, "object": true,
+}
+
+func compileJSONSchemaObject(raw map[string]interface{}) (*jsonSchema, error) {
+	// Keywords that are not implemented are rejected, rather than ignored, so that a schema
+	// never compiles into a validator that is looser than it reads.
+	var unsupported []string
+	for keyword := range raw {
+		if !jsonSchemaKeywords[keyword] {
+			unsupported = append(unsupported, keyword)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return nil, fmt.Errorf("unsupported keywords %v", unsupported)
+	}
+
+	s := &jsonSchema{}
+
+	switch t := raw["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("type must contain only strings")
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("type must be a string or array")
+	}
+	for _, t := range s.types {
+		if !jsonSchemaTypes[t] {
+			return nil, fmt.Errorf("unknown type %s", t)
+		}
+	}
+
+	if e, ok := raw["enum"]; ok {
+		values, ok := e.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("enum must be an array")
+		}
+		s.enum = values
+	}
+
+	if c, ok := raw["const"]; ok {
+		s.constant = &c
+	}
+
+	if p, ok := raw["properties"]; ok {
+		props, ok := p.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("properties must be an object")
+		}
+
+		s.properties = make(map[string]*jsonSchema, len(props))
+		for name, sub := range props {
+			compiled, err := compileJSONSchemaValue(sub)
+			if err != nil {
+				return nil, fmt.Errorf("error while compiling property %s: %w", name, err)
+			}
+			s.properties[name] = compiled
+		}
+	}
+
+	if r, ok := raw["required"]; ok {
+		names, ok := r.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("required must be an array")
+		}
+
+		for _, n := range names {
+			name, ok := n.(string)
+			if !ok {
+				return nil, fmt.Errorf("required must contain only strings")
+			}
+			s.required = append(s.required, name)
+		}
+	}
+
+	if a, ok := raw["additionalProperties"]; ok {
+		if b, ok := a.(bool); ok {
+			s.noAdditional = !b
+		} else {
+			compiled, err := compileJSONSchemaValue(a)
+			if err != nil {
+				return nil, fmt.Errorf("error while compiling additionalProperties: %w", err)
+			}
+			s.additional = compiled
+		}
+	}
+
+	if i, ok := raw["items"]; ok {
+		compiled, err := compileJSONSchemaValue(i)
+		if err != nil {
+			return nil, fmt.Errorf("error while compiling items: %w", err)
+		}
+		s.items = compiled
+	}
+
+	var err error
+	if s.minItems, err = schemaInt(raw, "minItems"); err != nil {
+		return nil, err
+	}
+	if s.maxItems, err = schemaInt(raw, "maxItems"); err != nil {
+		return nil, err
+	}
+	if s.minLength, err = schemaInt(raw, "minLength"); err != nil {
+		return nil, err
+	}
+	if s.maxLength, err = schemaInt(raw, "maxLength"); err != nil {
+		return nil, err
+	}
+	if s.minimum, err = schemaNumber(raw, "minimum"); err != nil {
+		return nil, err
+	}
+	if s.maximum, err = schemaNumber(raw, "maximum"); err != nil {
+		return nil, err
+	}
+	if s.exclusiveMin, err = schemaNumber(raw, "exclusiveMinimum"); err != nil {
+		return nil, err
+	}
+	if s.exclusiveMax, err = schemaNumber(raw, "exclusiveMaximum"); err != nil {
+		return nil, err
+	}
+
+	if p, ok := raw["pattern"]; ok {
+		expr, ok := p.(string)
+		if !ok {
+			return nil, fmt.Errorf("pattern must be a string")
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("error while compiling pattern: %w", err)
+		}
+		s.pattern = re
+	}
+
+	return s, nil
+}
+
+func schemaNumber(raw map[string]interface{}, keyword string) (*float64, error) {
+	v, ok := raw[keyword]
+	if !ok {
+		return nil, nil
+	}
+
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a number", keyword)
+	}
+
+	return &f, nil
+}
+
+func schemaInt(raw map[string]interface{}, keyword string) (*int, error) {
+	f, err := schemaNumber(raw, keyword)
+	if err != nil || f == nil {
+		return nil, err
+	}
+
+	if *f < 0 || *f != math.Trunc(*f) {
+		return nil, fmt.Errorf("%s must be a non-negative integer", keyword)
+	}
+
+	i := int(*f)
+	return &i, nil
+}
+
+// validate checks the decoded JSON value against the schema.
+//
+// at describes the location of the value within the document and is used in error messages.
+func (s *jsonSchema) validate(v interface{}, at string) error {
+	if s.never {
+		return fmt.Errorf("%s is not allowed", at)
+	}
+
+	if len(s.types) > 0 && !s.matchesType(v) {
+		return fmt.Errorf("%s must be of type %v", at, s.types)
+	}
+
+	if s.enum != nil && !containsJSON(s.enum, v) {
+		return fmt.Errorf("%s must be one of %v", at, s.enum)
+	}
+
+	if s.constant != nil && !reflect.DeepEqual(*s.constant, v) {
+		return fmt.Errorf("%s must equal %v", at, *s.constant)
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return s.validateObject(val, at)
+	case []interface{}:
+		return s.validateArray(val, at)
+	case string:
+		return s.validateString(val, at)
+	case float64:
+		return s.validateNumber(val, at)
+	}
+
+	return nil
+}
+
+func (s *jsonSchema) validateObject(obj map[string]interface{}, at string) error {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s is missing required property %s", at, name)
+		}
+	}
+
+	for name, v := range obj {
+		sub, ok := s.properties[name]
+		switch {
+		case ok:
+			if err := sub.validate(v, at+"."+name); err != nil {
+				return err
+			}
+		case s.noAdditional:
+			return fmt.Errorf("%s has unexpected property %s", at, name)
+		case s.additional != nil:
+			if err := s.additional.validate(v, at+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *jsonSchema) validateArray(arr []interface{}, at string) error {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		return fmt.Errorf("%s must contain at least %d items", at, *s.minItems)
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		return fmt.Errorf("%s must contain at most %d items", at, *s.maxItems)
+	}
+
+	if s.items != nil {
+		for i, v := range arr {
+			if err := s.items.validate(v, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *jsonSchema) validateString(str string, at string) error {
+	n := utf8.RuneCountInString(str)
+
+	if s.minLength != nil && n < *s.minLength {
+		return fmt.Errorf("%s must be at least %d characters", at, *s.minLength)
+	}
+	if s.maxLength != nil && n > *s.maxLength {
+		return fmt.Errorf("%s must be at most %d characters", at, *s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		return fmt.Errorf("%s must match pattern %s", at, s.pattern)
+	}
+
+	return nil
+}
+
+func (s *jsonSchema) validateNumber(f float64, at string) error {
+	if s.minimum != nil && f < *s.minimum {
+		return fmt.Errorf("%s must be >= %v", at, *s.minimum)
+	}
+	if s.maximum != nil && f > *s.maximum {
+		return fmt.Errorf("%s must be <= %v", at, *s.maximum)
+	}
+	if s.exclusiveMin != nil && f <= *s.exclusiveMin {
+		return fmt.Errorf("%s must be > %v", at, *s.exclusiveMin)
+	}
+	if s.exclusiveMax != nil && f >= *s.exclusiveMax {
+		return fmt.Errorf("%s must be < %v", at, *s.exclusiveMax)
+	}
+
+	return nil
+}
+
+func (s *jsonSchema) matchesType(v interface{}) bool {
+	for _, t := range s.types {
+		switch t {
+		case "null":
+			if v == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := v.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]interface{}); ok {
+				return true
+			}
+		case "object":
+			if _, ok := v.(map[string]interface{}); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func containsJSON(values []interface{}, v interface{}) bool {
+	for _, e := range values {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,315 @@
+package quickbolt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// LeaseID identifies a lease granted by Grant.
+type LeaseID uint64
+
+// Keys within a lease's bucket.
+const (
+	leaseTTL    = "ttl"
+	leaseExpiry = "expiry"
+	leaseKeys   = "keys"
+)
+
+// WithLeaseSweep enables revocation of expired leases in the background, checking at the given interval.
+//
+// Without it, expired leases are only revoked by calls to RevokeExpiredLeases.
+func WithLeaseSweep(interval time.Duration) Option {
+	return func(o *options) {
+		o.leaseSweep = interval
+	}
+}
+
+// sweepLeases periodically revokes expired leases until background work is stopped.
+func (d *dbWrapper) sweepLeases(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.state.stop:
+			return
+		case <-ticker.C:
+			if _, err := d.RevokeExpiredLeases(); err != nil {
+				logMutex.Lock()
+				d.logger.Err(err).Str("operation", "RevokeExpiredLeases").Msg("lease sweep")
+				logMutex.Unlock()
+			}
+		}
+	}
+}
+
+// grantLease records a new lease expiring after ttl.
+func grantLease(db *bbolt.DB, ttl time.Duration) (LeaseID, error) {
+	if db == nil {
+		return 0, fmt.Errorf("lease grant received nil db")
+	}
+
+	var id LeaseID
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		leases, err := getCreateMetaBucket(tx, leasesBucket)
+		if err != nil {
+			return err
+		}
+
+		seq, err := leases.NextSequence()
+		if err != nil {
+			return err
+		}
+		id = LeaseID(seq)
+
+		lease, err := leases.CreateBucket(leaseKey(id))
+		if err != nil {
+			return err
+		}
+
+		if _, err := lease.CreateBucket([]byte(leaseKeys)); err != nil {
+			return err
+		}
+
+		if err := lease.Put([]byte(leaseTTL), uint64Bytes(uint64(ttl))); err != nil {
+			return err
+		}
+
+		return lease.Put([]byte(leaseExpiry), uint64Bytes(uint64(time.Now().Add(ttl).UnixNano())))
+	})
+	if err != nil {
+		return 0, fmt.Errorf("lease grant experienced error while recording lease: %w", err)
+	}
+
+	return id, nil
+}
+
+// attachKey attaches the key at the given path to the lease, returning ErrLocate if the lease does not exist or has expired.
+func attachKey(db *bbolt.DB, id LeaseID, key []byte, path [][]byte) error {
+	if db == nil {
+		return fmt.Errorf("lease attachment of %s received nil db", key)
+	}
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		lease, err := getLiveLease(tx, id)
+		if err != nil {
+			return err
+		}
+
+		return lease.Bucket([]byte(leaseKeys)).Put(encodeAttachment(path, key), []byte{})
+	})
+	if err != nil {
+		return fmt.Errorf("lease attachment of %s experienced error while attaching key: %w", key, err)
+	}
+
+	return nil
+}
+
+// keepAlive extends the expiry of the lease by its ttl, returning ErrLocate if the lease does not exist or has expired.
+func keepAlive(db *bbolt.DB, id LeaseID) error {
+	if db == nil {
+		return fmt.Errorf("lease renewal of %d received nil db", id)
+	}
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		lease, err := getLiveLease(tx, id)
+		if err != nil {
+			return err
+		}
+
+		ttl := time.Duration(binary.BigEndian.Uint64(lease.Get([]byte(leaseTTL))))
+
+		return lease.Put([]byte(leaseExpiry), uint64Bytes(uint64(time.Now().Add(ttl).UnixNano())))
+	})
+	if err != nil {
+		return fmt.Errorf("lease renewal of %d experienced error while extending lease: %w", id, err)
+	}
+
+	return nil
+}
+
+// revokeLease deletes the lease and every key attached to it, returning ErrLocate if the lease does not exist.
+//
+// If expiredOnly is true, the lease is left alone and false returned unless it has expired.
+func revokeLease(db *bbolt.DB, id LeaseID, expiredOnly bool, envOf func([][]byte) writeEnv) (bool, error) {
+	if db == nil {
+		return false, fmt.Errorf("lease revocation of %d received nil db", id)
+	}
+
+	revoked := false
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		leases, lease := getLease(tx, id)
+		if lease == nil {
+			return newErrLocate(fmt.Sprintf("lease %d", id))
+		} else if expiredOnly && time.Now().Before(leaseExpiryOf(lease)) {
+			return nil
+		}
+
+		c := lease.Bucket([]byte(leaseKeys)).Cursor()
+		for a, _ := c.First(); a != nil; a, _ = c.Next() {
+			path, key, err := decodeAttachment(a)
+			if err != nil {
+				return err
+			}
+
+			if err := deleteAttached(tx, path, key, envOf(path)); err != nil {
+				return err
+			}
+		}
+
+		revoked = true
+
+		return leases.DeleteBucket(leaseKey(id))
+	})
+	if err != nil {
+		return false, fmt.Errorf("lease revocation of %d experienced error while deleting keys: %w", id, err)
+	}
+
+	return revoked, nil
+}
+
+// deleteAttached removes the key at the given path, if it still exists.
+func deleteAttached(tx *bbolt.Tx, path [][]byte, key []byte, env writeEnv) error {
+	bkt, err := getBucket(tx, path, false)
+	if err != nil || bkt == nil {
+		return err
+	}
+
+	old := bkt.Get(key)
+	if old == nil {
+		return nil
+	}
+
+	if err := env.rules.beforeDelete(tx, path, key, old); err != nil {
+		return err
+	}
+
+	if err := bkt.Delete(key); err != nil {
+		return fmt.Errorf("error while deleting %s from %s: %w", key, path, err)
+	}
+
+	env.metrics.observeWrite(tx, 0)
+
+	return nil
+}
+
+// expiredLeases returns the leases whose expiry has passed.
+func expiredLeases(db *bbolt.DB) ([]LeaseID, error) {
+	if db == nil {
+		return nil, fmt.Errorf("expired lease scanning received nil db")
+	}
+
+	var ids []LeaseID
+	now := time.Now()
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		meta := tx.Bucket([]byte(metaBucket))
+		if meta == nil {
+			return nil
+		}
+
+		leases := meta.Bucket([]byte(leasesBucket))
+		if leases == nil {
+			return nil
+		}
+
+		return leases.ForEach(func(k, v []byte) error {
+			if v != nil || len(k) != 8 {
+				return nil
+			}
+
+			if !now.Before(leaseExpiryOf(leases.Bucket(k))) {
+				ids = append(ids, LeaseID(binary.BigEndian.Uint64(k)))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("expired lease scanning experienced error while reading leases: %w", err)
+	}
+
+	return ids, nil
+}
+
+// getLease returns the bucket holding all leases and the bucket of the given lease, which is nil if it does not exist.
+func getLease(tx *bbolt.Tx, id LeaseID) (*bbolt.Bucket, *bbolt.Bucket) {
+	meta := tx.Bucket([]byte(metaBucket))
+	if meta == nil {
+		return nil, nil
+	}
+
+	leases := meta.Bucket([]byte(leasesBucket))
+	if leases == nil {
+		return nil, nil
+	}
+
+	return leases, leases.Bucket(leaseKey(id))
+}
+
+// getLiveLease returns the bucket of the given lease, or ErrLocate if it does not exist or has expired.
+func getLiveLease(tx *bbolt.Tx, id LeaseID) (*bbolt.Bucket, error) {
+	_, lease := getLease(tx, id)
+	if lease == nil || !time.Now().Before(leaseExpiryOf(lease)) {
+		return nil, newErrLocate(fmt.Sprintf("lease %d", id))
+	}
+
+	return lease, nil
+}
+
+func leaseExpiryOf(lease *bbolt.Bucket) time.Time {
+	v := lease.Get([]byte(leaseExpiry))
+	if len(v) != 8 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, int64(binary.BigEndian.Uint64(v)))
+}
+
+func leaseKey(id LeaseID) []byte {
+	return uint64Bytes(uint64(id))
+}
+
+func uint64Bytes(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b
+}
+
+// encodeAttachment encodes the key and the path it belongs to as a single key.
+//
+// Each path segment is prefixed by its length, followed by the key.
+func encodeAttachment(path [][]byte, key []byte) []byte {
+	b := binary.AppendUvarint(nil, uint64(len(path)))
+	for _, p := range path {
+		b = binary.AppendUvarint(b, uint64(len(p)))
+		b = append(b, p...)
+	}
+
+	return append(b, key...)
+}
+
+// decodeAttachment reverses encodeAttachment.
+func decodeAttachment(b []byte) ([][]byte, []byte, error) {
+	n, read := binary.Uvarint(b)
+	if read <= 0 {
+		return nil, nil, fmt.Errorf("malformed lease attachment %x", b)
+	}
+	b = b[read:]
+
+	path := make([][]byte, 0, n)
+	for i := uint64(0); i < n; i++ {
+		l, read := binary.Uvarint(b)
+		if read <= 0 || uint64(len(b)-read) < l {
+			return nil, nil, fmt.Errorf("malformed lease attachment %x", b)
+		}
+		path = append(path, copyBytes(b[read:read+int(l)]))
+		b = b[read+int(l):]
+	}
+
+	return path, copyBytes(b), nil
+}
@@ -0,0 +1,91 @@
+package quickbolt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_Lease(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	sessions := []string{"sessions"}
+	presence := []string{"presence", "users"}
+
+	assert.Nil(t, db.Insert("s1", "v", sessions))
+	assert.Nil(t, db.Insert("u1", "v", presence))
+	assert.Nil(t, db.Insert("u2", "v", presence))
+
+	short, err := db.Grant(time.Millisecond * 100)
+	assert.Nil(t, err)
+	long, err := db.Grant(time.Hour)
+	assert.Nil(t, err)
+	assert.NotEqual(t, short, long)
+	assert.Nil(t, db.AttachKey(short, "s1", sessions))
+	assert.Nil(t, db.AttachKey(short, "u1", presence))
+	assert.Nil(t, db.AttachKey(long, "u2", presence))
+
+	n, err := db.RevokeExpiredLeases()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	time.Sleep(time.Millisecond * 100)
+
+	assert.True(t, errors.Is(db.KeepAlive(short), ErrLocate{}), "expired leases cannot be renewed")
+	assert.True(t, errors.Is(db.AttachKey(short, "s2", sessions), ErrLocate{}))
+	assert.Nil(t, db.KeepAlive(long))
+
+	n, err = db.RevokeExpiredLeases()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	for _, k := range []struct {
+		key  string
+		path []string
+		want bool
+	}{{"s1", sessions, false}, {"u1", presence, false}, {"u2", presence, true}} {
+		_, found, err := db.GetValueOK(k.key, k.path)
+		assert.Nil(t, err)
+		assert.Equal(t, k.want, found, k.key)
+	}
+
+	assert.Nil(t, db.Revoke(long))
+	_, found, err := db.GetValueOK("u2", presence)
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.True(t, errors.Is(db.Revoke(long), ErrLocate{}))
+}
+
+func Test_dbWrapper_LeaseSweep(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithLeaseSweep(time.Millisecond*10))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k", "v", []string{"a"}))
+	id, err := db.Grant(time.Millisecond * 50)
+	assert.Nil(t, err)
+	assert.Nil(t, db.AttachKey(id, "k", []string{"a"}))
+
+	assert.Eventually(t, func() bool {
+		_, found, err := db.GetValueOK("k", []string{"a"})
+		return err == nil && !found
+	}, time.Second, time.Millisecond*10)
+}
+
+func Test_encodeAttachment(t *testing.T) {
+	path := [][]byte{[]byte("a"), {}, []byte("long segment")}
+
+	gotPath, gotKey, err := decodeAttachment(encodeAttachment(path, []byte("key")))
+	assert.Nil(t, err)
+	assert.Equal(t, path, gotPath)
+	assert.Equal(t, []byte("key"), gotKey)
+
+	_, _, err = decodeAttachment([]byte{2, 9, 'a'})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,67 @@
+// Package leveldbsource provides a quickbolt.Source reading from a LevelDB database directory.
+package leveldbsource
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// KeyMapper returns the bucket path and key a LevelDB key is imported under.
+type KeyMapper func(key []byte) (path [][]byte, newKey []byte, err error)
+
+// IntoBucket returns a KeyMapper importing every key unchanged into the bucket at the given path.
+func IntoBucket(path [][]byte) KeyMapper {
+	return func(key []byte) ([][]byte, []byte, error) {
+		return path, key, nil
+	}
+}
+
+// Source reads every key-value pair of a LevelDB database in key order.
+type Source struct {
+	db     *leveldb.DB
+	it     iterator.Iterator
+	mapKey KeyMapper
+}
+
+// Open opens the LevelDB database in the given directory as read-only.
+//
+// Each key is imported where mapKey places it. The Source must be closed once the import is done.
+func Open(dir string, mapKey KeyMapper) (*Source, error) {
+	if mapKey == nil {
+		return nil, fmt.Errorf("leveldb source received nil key mapper")
+	}
+
+	db, err := leveldb.OpenFile(dir, &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+	if err != nil {
+		return nil, fmt.Errorf("error while opening leveldb at %s: %w", dir, err)
+	}
+
+	return &Source{db: db, it: db.NewIterator(nil, nil), mapKey: mapKey}, nil
+}
+
+// Next returns the next entry, or io.EOF once every entry has been read.
+func (s *Source) Next() ([][]byte, []byte, []byte, error) {
+	if !s.it.Next() {
+		if err := s.it.Error(); err != nil {
+			return nil, nil, nil, fmt.Errorf("error while iterating leveldb: %w", err)
+		}
+		return nil, nil, nil, io.EOF
+	}
+
+	path, key, err := s.mapKey(s.it.Key())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error while mapping key %s: %w", s.it.Key(), err)
+	}
+
+	return path, key, s.it.Value(), nil
+}
+
+// Close releases the LevelDB database.
+func (s *Source) Close() error {
+	s.it.Release()
+	return s.db.Close()
+}
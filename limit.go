@@ -0,0 +1,48 @@
+package quickbolt
+
+import (
+	"context"
+	"fmt"
+)
+
+// WriteLimiter throttles writes to the database.
+//
+// *rate.Limiter from golang.org/x/time/rate satisfies WriteLimiter.
+type WriteLimiter interface {
+	// Wait blocks until a write may proceed.
+	Wait(ctx context.Context) error
+}
+
+// WithWriteLimiter throttles Insert, InsertValue, InsertValueWith, InsertValues, InsertBucket, Upsert, UpsertMany, Apply, Delete,
+// DeleteKeys, DeleteBucket, DeleteValues, PutObject, Reset, BeginWrite, RunUpdate, RunBatch, and ValidatePaths when creating buckets,
+// each of which waits on the limiter once per call.
+//
+// ImportFrom, CopyWhere, DeleteWhere, UpdateWhere, MapBucket, GroupBy, ReencodeKeys, and ExpireBefore wait on the limiter
+// once per transaction they commit, so a bulk job is throttled batch by batch.
+//
+// Waiting happens before each transaction begins, so throttled writers hold no transaction or lock while they wait.
+// This keeps bulk jobs from starving other traffic on the same file.
+func WithWriteLimiter(l WriteLimiter) Option {
+	return func(o *options) {
+		o.writeLimiter = l
+	}
+}
+
+// waitForWrite blocks until the write limiter, if any, permits a write.
+//
+// ErrNestedTx is returned instead if the write would deadlock, as detected per WithNestedTxDetection.
+func (d *dbWrapper) waitForWrite() error {
+	if err := d.checkNested("write"); err != nil {
+		return err
+	}
+
+	if d.opts.writeLimiter == nil {
+		return nil
+	}
+
+	if err := d.opts.writeLimiter.Wait(context.Background()); err != nil {
+		return fmt.Errorf("error while waiting for write limiter: %w", err)
+	}
+
+	return nil
+}
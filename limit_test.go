@@ -0,0 +1,106 @@
+package quickbolt
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+// countingLimiter permits a fixed number of writes, then refuses the rest.
+type countingLimiter struct {
+	remaining atomic.Int64
+	waits     atomic.Int64
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits.Add(1)
+	if l.remaining.Add(-1) < 0 {
+		return fmt.Errorf("limit exceeded")
+	}
+	return nil
+}
+
+func TestWithWriteLimiter(t *testing.T) {
+	limiter := &countingLimiter{}
+	limiter.remaining.Store(3)
+
+	db, err := CreateWith("foo.db", t.TempDir(), WithWriteLimiter(limiter))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a"}
+
+	assert.Nil(t, db.Insert("k1", "v", path))
+	assert.Nil(t, db.Upsert("k1", "v", path, func(a, b []byte) ([]byte, error) { return b, nil }))
+	assert.Nil(t, db.Delete("k1", path))
+	assert.NotNil(t, db.Insert("k2", "v", path))
+
+	_, err = db.GetValue("k1", path, false)
+	assert.Nil(t, err, "reads must not be limited")
+	assert.Equal(t, int64(4), limiter.waits.Load())
+
+	_, found, err := db.GetValueOK("k2", path)
+	assert.Nil(t, err)
+	assert.False(t, found, "refused writes must not be applied")
+}
+
+func TestWithWriteLimiter_Bulk(t *testing.T) {
+	limiter := &countingLimiter{}
+	limiter.remaining.Store(1 << 20)
+
+	db, err := CreateWith("foo.db", t.TempDir(), WithWriteLimiter(limiter))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a"}
+
+	entries := make([]PathedEntry, importBatchSize+1)
+	for i := range entries {
+		entries[i] = PathedEntry{Path: [][]byte{[]byte("a")}, Key: []byte(fmt.Sprint(i)), Value: []byte("v")}
+	}
+
+	// Bulk writes wait once per transaction.
+	n, err := db.ImportFrom(SliceSource(entries))
+	assert.Nil(t, err)
+	assert.Equal(t, len(entries), n)
+	assert.Equal(t, int64(2), limiter.waits.Load())
+
+	limiter.remaining.Store(0)
+
+	all := func(k, v []byte) bool { return true }
+	same := func(v []byte) ([]byte, error) { return v, nil }
+
+	_, err = db.ImportFrom(SliceSource(entries[:1]))
+	assert.NotNil(t, err, "ImportFrom")
+	_, err = db.CopyWhere(path, []string{"b"}, all)
+	assert.NotNil(t, err, "CopyWhere")
+	_, err = db.DeleteWhere(path, all)
+	assert.NotNil(t, err, "DeleteWhere")
+	_, err = db.UpdateWhere(path, all, same)
+	assert.NotNil(t, err, "UpdateWhere")
+	_, err = db.MapBucket(path, []string{"b"}, func(k, v []byte) ([]byte, []byte, bool, error) { return nil, nil, false, nil })
+	assert.NotNil(t, err, "MapBucket")
+	_, err = db.GroupBy(path, []string{"b"}, func(k, v []byte) []byte { return v })
+	assert.NotNil(t, err, "GroupBy")
+	_, err = db.ReencodeKeys(path, same)
+	assert.NotNil(t, err, "ReencodeKeys")
+	_, err = db.ExpireBefore(path, time.Now())
+	assert.NotNil(t, err, "ExpireBefore")
+	assert.NotNil(t, db.RunUpdate(func(tx *bbolt.Tx) error { return nil }), "RunUpdate")
+	assert.NotNil(t, db.RunBatch(func(tx *bbolt.Tx) error { return nil }), "RunBatch")
+
+	count, err := db.CountAt(path)
+	assert.Nil(t, err)
+	assert.Equal(t, len(entries), count, "refused writes must not be applied")
+
+	exists, err := db.HasBucket([]string{"b"})
+	assert.Nil(t, err)
+	assert.False(t, exists, "refused writes must not be applied")
+}
@@ -0,0 +1,154 @@
+package quickbolt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Unlock releases an advisory lock.
+//
+// An ErrLockLost is returned if the lock expired and was taken by another holder before Unlock was called.
+type Unlock func() error
+
+// lockTokenSize is the number of random bytes identifying a lock's holder.
+const lockTokenSize = 16
+
+// lockHeldFor returns how long remains until the lock on the given key at the given path expires,
+// or 0 if it is not held.
+//
+// Unlike tryLock, it only reads the db, so waiters may poll it without committing a transaction per attempt.
+func lockHeldFor(db *bbolt.DB, key []byte, path [][]byte) (time.Duration, error) {
+	if db == nil {
+		return 0, fmt.Errorf("lock inspection of %s received nil db", key)
+	}
+
+	var wait time.Duration
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		meta := tx.Bucket([]byte(metaBucket))
+		if meta == nil {
+			return nil
+		}
+		locks := meta.Bucket([]byte(locksBucket))
+		if locks == nil {
+			return nil
+		}
+		bkt := locks.Bucket([]byte(pathKey(path)))
+		if bkt == nil {
+			return nil
+		}
+
+		if held := bkt.Get(key); held != nil {
+			if now, expiry := time.Now(), lockExpiry(held); now.Before(expiry) {
+				wait = expiry.Sub(now)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("lock inspection of %s experienced error while reading lock: %w", key, err)
+	}
+
+	return wait, nil
+}
+
+// tryLock acquires an advisory lock on the given key at the given path for the holder identified by token.
+//
+// If the key is locked by another holder, tryLock returns how long remains until that lock expires.
+func tryLock(db *bbolt.DB, key []byte, path [][]byte, token []byte, ttl time.Duration) (time.Duration, error) {
+	if db == nil {
+		return 0, fmt.Errorf("locking of %s received nil db", key)
+	}
+
+	var wait time.Duration
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateLockBucket(tx, path)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if held := bkt.Get(key); held != nil {
+			if expiry := lockExpiry(held); now.Before(expiry) {
+				wait = expiry.Sub(now)
+				return nil
+			}
+		}
+
+		record := make([]byte, lockTokenSize+8)
+		copy(record, token)
+		binary.BigEndian.PutUint64(record[lockTokenSize:], uint64(now.Add(ttl).UnixNano()))
+
+		return bkt.Put(key, record)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("locking of %s experienced error while acquiring lock: %w", key, err)
+	}
+
+	return wait, nil
+}
+
+// newLockToken returns a random token identifying a lock's holder.
+func newLockToken() ([]byte, error) {
+	token := make([]byte, lockTokenSize)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// unlock releases the lock on the given key at the given path if it is still held with the given token.
+func unlock(db *bbolt.DB, key []byte, path [][]byte, token []byte) error {
+	if db == nil {
+		return fmt.Errorf("unlocking of %s received nil db", key)
+	}
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateLockBucket(tx, path)
+		if err != nil {
+			return err
+		}
+
+		held := bkt.Get(key)
+		if held == nil || !bytes.Equal(held[:lockTokenSize], token) {
+			return newErrLockLost(fmt.Sprintf("%s in %s", key, path))
+		}
+
+		return bkt.Delete(key)
+	})
+	if err != nil {
+		return fmt.Errorf("unlocking of %s experienced error while releasing lock: %w", key, err)
+	}
+
+	return nil
+}
+
+func lockExpiry(record []byte) time.Time {
+	if len(record) < lockTokenSize+8 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, int64(binary.BigEndian.Uint64(record[lockTokenSize:])))
+}
+
+// getCreateLockBucket returns the bucket holding the locks for the given path, creating it if necessary.
+func getCreateLockBucket(tx *bbolt.Tx, path [][]byte) (*bbolt.Bucket, error) {
+	locks, err := getCreateMetaBucket(tx, locksBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	bkt, err := locks.CreateBucketIfNotExists([]byte(pathKey(path)))
+	if err != nil {
+		return nil, fmt.Errorf("error while accessing locks for %s: %w", path, err)
+	}
+
+	return bkt, nil
+}
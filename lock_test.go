@@ -0,0 +1,93 @@
+package quickbolt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func Test_dbWrapper_Lock(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"counters"}
+	assert.Nil(t, db.Insert("hits", 0, path))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := db.Lock(context.Background(), "hits", path, time.Minute)
+			assert.Nil(t, err)
+
+			v, err := db.GetValue("hits", path, true)
+			assert.Nil(t, err)
+			assert.Nil(t, db.Insert("hits", string(append(v, 'x')), path))
+
+			assert.Nil(t, unlock())
+		}()
+	}
+	wg.Wait()
+
+	v, err := db.GetValue("hits", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "0xxxxxxxxxx", string(v))
+}
+
+func Test_dbWrapper_LockExpiry(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	stale, err := db.Lock(context.Background(), "k", []string{"b"}, time.Millisecond*20)
+	assert.Nil(t, err)
+
+	fresh, err := db.Lock(context.Background(), "k", []string{"b"}, time.Minute)
+	assert.Nil(t, err)
+
+	err = stale()
+	assert.True(t, errors.As(err, &ErrLockLost{}))
+	assert.Nil(t, fresh())
+
+	_, err = db.Lock(context.Background(), "k", []string{"b"}, 0)
+	assert.NotNil(t, err)
+}
+
+func Test_dbWrapper_LockContext(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	held, err := db.Lock(context.Background(), "k", []string{"b"}, time.Hour)
+	assert.Nil(t, err)
+
+	// Read transactions share the ID of the last committed write transaction.
+	lastCommit := func() (id int) {
+		assert.Nil(t, db.RunView(func(tx *bbolt.Tx) error {
+			id = tx.ID()
+			return nil
+		}))
+		return id
+	}
+	before := lastCommit()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	_, err = db.Lock(ctx, "k", []string{"b"}, time.Minute)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, before, lastCommit(), "waiting for a held lock must not commit")
+
+	assert.Nil(t, held())
+}
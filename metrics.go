@@ -0,0 +1,302 @@
+package quickbolt
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Stats holds the metrics collected for a database.
+type Stats struct {
+	// Writes is the number of committed write operations made via Upsert, Insert, InsertValue,
+	// InsertBucket, Delete, DeleteBucket, DeleteValues, and RunUpdate.
+	Writes uint64
+	// Commits is the number of committed write transactions.
+	Commits uint64
+	// MaxBatchSize is the largest number of write operations committed in a single transaction.
+	MaxBatchSize uint64
+	// BytesWritten is the total size of the keys and values committed.
+	BytesWritten uint64
+	// CommitLatency is the total time write operations spent waiting for their transaction to commit.
+	CommitLatency time.Duration
+	// MaxCommitLatency is the longest time a write operation spent waiting for its transaction to commit.
+	MaxCommitLatency time.Duration
+	// CommitLatencyCounts counts the write operations whose latency is included in CommitLatency, by latency.
+	// The count at index i is of those taking no longer than CommitLatencyBuckets[i] and, for i > 0, longer than
+	// CommitLatencyBuckets[i-1]. The final count is of those taking longer than every bucket.
+	CommitLatencyCounts []uint64
+	// BufferTimeouts is the number of streaming methods that failed after timing out while sending to their buffer.
+	BufferTimeouts uint64
+	// BufferReceiveTimeouts is the number of DoEach and DoEachInto calls given the db that failed after
+	// timing out while receiving from their input channel.
+	BufferReceiveTimeouts uint64
+	// BufferTimeoutsByOp breaks down buffer timeouts by the name of the method that timed out, e.g. "KeysAt".
+	BufferTimeoutsByOp map[string]TimeoutCounts
+	// BufferTimeoutsByPath breaks down buffer send timeouts by the bucket path being streamed,
+	// formatted as in ErrTimeout.Path. Receive timeouts have no path and aren't included.
+	BufferTimeoutsByPath map[string]TimeoutCounts
+}
+
+// TimeoutCounts counts the buffer timeouts of an operation or bucket path.
+type TimeoutCounts struct {
+	// Send is the number of timeouts while sending to a buffer, meaning its consumer fell behind.
+	Send uint64
+	// Receive is the number of timeouts while receiving from a buffer, meaning its producer fell behind.
+	Receive uint64
+}
+
+// CommitLatencyBuckets are the upper bounds of the latencies counted by Stats.CommitLatencyCounts.
+var CommitLatencyBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second,
+}
+
+// AvgBatchSize returns the mean number of write operations committed per transaction.
+func (s Stats) AvgBatchSize() float64 {
+	if s.Commits == 0 {
+		return 0
+	}
+	return float64(s.Writes) / float64(s.Commits)
+}
+
+// AvgCommitLatency returns the mean time a write operation spent waiting for its transaction to commit.
+func (s Stats) AvgCommitLatency() time.Duration {
+	if s.Writes == 0 {
+		return 0
+	}
+	return s.CommitLatency / time.Duration(s.Writes)
+}
+
+// metrics collects the Stats for a database.
+type metrics struct {
+	mu    sync.Mutex
+	stats Stats
+	// current tracks the write transaction in progress.
+	current *txMetrics
+	// slow holds the most recent operations that took at least slowOpThreshold, oldest first.
+	slow []slowOp
+}
+
+// slowOp records a DB method call that took at least slowOpThreshold.
+type slowOp struct {
+	Name     string        `json:"name"`
+	ID       string        `json:"id"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Err      string        `json:"error,omitempty"`
+}
+
+// txMetrics tracks the writes made within a single transaction.
+type txMetrics struct {
+	tx     *bbolt.Tx
+	writes uint64
+	bytes  uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{}
+}
+
+// observeWrite records a write operation of the given size within the given transaction.
+// The write is counted once the transaction commits.
+//
+// A nil *metrics records nothing.
+func (m *metrics) observeWrite(tx *bbolt.Tx, bytes int) {
+	if m == nil || tx == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current == nil || m.current.tx != tx {
+		t := &txMetrics{tx: tx}
+		m.current = t
+		tx.OnCommit(func() { m.commit(t) })
+	}
+
+	m.current.writes++
+	m.current.bytes += uint64(bytes)
+}
+
+// commit records the writes of a committed transaction.
+func (m *metrics) commit(t *txMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.Commits++
+	m.stats.Writes += t.writes
+	m.stats.BytesWritten += t.bytes
+
+	if t.writes > m.stats.MaxBatchSize {
+		m.stats.MaxBatchSize = t.writes
+	}
+
+	if m.current == t {
+		m.current = nil
+	}
+}
+
+// observeLatency records the time a write operation started at the given time spent waiting for its
+// transaction to commit, if the operation succeeded.
+//
+// A nil *metrics records nothing.
+func (m *metrics) observeLatency(start time.Time, err error) {
+	if m == nil || err != nil {
+		return
+	}
+
+	d := time.Since(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.CommitLatency += d
+
+	if d > m.stats.MaxCommitLatency {
+		m.stats.MaxCommitLatency = d
+	}
+
+	if m.stats.CommitLatencyCounts == nil {
+		m.stats.CommitLatencyCounts = make([]uint64, len(CommitLatencyBuckets)+1)
+	}
+	i := sort.Search(len(CommitLatencyBuckets), func(i int) bool { return d <= CommitLatencyBuckets[i] })
+	m.stats.CommitLatencyCounts[i]++
+}
+
+// observeSendTimeout records the named operation timing out while sending to its buffer
+// the contents of the given path.
+//
+// A nil *metrics records nothing.
+func (m *metrics) observeSendTimeout(op string, path [][]byte) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.BufferTimeouts++
+
+	if m.stats.BufferTimeoutsByOp == nil {
+		m.stats.BufferTimeoutsByOp = make(map[string]TimeoutCounts)
+	}
+	c := m.stats.BufferTimeoutsByOp[op]
+	c.Send++
+	m.stats.BufferTimeoutsByOp[op] = c
+
+	if m.stats.BufferTimeoutsByPath == nil {
+		m.stats.BufferTimeoutsByPath = make(map[string]TimeoutCounts)
+	}
+	c = m.stats.BufferTimeoutsByPath[pathKey(path)]
+	c.Send++
+	m.stats.BufferTimeoutsByPath[pathKey(path)] = c
+}
+
+// observeReceiveTimeout records the named operation timing out while receiving from its input channel.
+//
+// A nil *metrics records nothing.
+func (m *metrics) observeReceiveTimeout(op string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.BufferReceiveTimeouts++
+
+	if m.stats.BufferTimeoutsByOp == nil {
+		m.stats.BufferTimeoutsByOp = make(map[string]TimeoutCounts)
+	}
+	c := m.stats.BufferTimeoutsByOp[op]
+	c.Receive++
+	m.stats.BufferTimeoutsByOp[op] = c
+}
+
+// metricsOf returns the metrics of the db, or nil for DB implementations from outside this package.
+func metricsOf(db DB) *metrics {
+	if d, ok := db.(*dbWrapper); ok {
+		return d.metrics
+	}
+
+	return nil
+}
+
+// observeOp records the operation if it took at least slowOpThreshold.
+//
+// A nil *metrics records nothing.
+func (m *metrics) observeOp(o *operation, err error) {
+	if m == nil {
+		return
+	}
+
+	d := time.Since(o.start)
+	if d < slowOpThreshold {
+		return
+	}
+
+	op := slowOp{Name: o.name, ID: o.id(), Start: o.start, Duration: d}
+	if err != nil {
+		op.Err = err.Error()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.slow) == slowOpLogSize {
+		copy(m.slow, m.slow[1:])
+		m.slow = m.slow[:len(m.slow)-1]
+	}
+	m.slow = append(m.slow, op)
+}
+
+// slowOps returns a copy of the recorded slow operations, most recent first.
+func (m *metrics) slowOps() []slowOp {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ops := make([]slowOp, len(m.slow))
+	for i, op := range m.slow {
+		ops[len(ops)-1-i] = op
+	}
+
+	return ops
+}
+
+// snapshot returns a copy of the collected Stats.
+func (m *metrics) snapshot() Stats {
+	if m == nil {
+		return Stats{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.stats
+	s.BufferTimeoutsByOp = copyTimeoutCounts(m.stats.BufferTimeoutsByOp)
+	s.BufferTimeoutsByPath = copyTimeoutCounts(m.stats.BufferTimeoutsByPath)
+	s.CommitLatencyCounts = append([]uint64(nil), m.stats.CommitLatencyCounts...)
+
+	return s
+}
+
+// copyTimeoutCounts returns a copy of the map, or nil if it is empty.
+func copyTimeoutCounts(m map[string]TimeoutCounts) map[string]TimeoutCounts {
+	if len(m) == 0 {
+		return nil
+	}
+
+	c := make(map[string]TimeoutCounts, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+
+	return c
+}
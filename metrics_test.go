@@ -0,0 +1,66 @@
+package quickbolt
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+	"golang.org/x/sync/errgroup"
+)
+
+func Test_dbWrapper_Stats(t *testing.T) {
+	db, err := Create("foo.db")
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("key", "value", []string{"stats"}))
+
+	stats := db.Stats()
+	assert.Equal(t, uint64(1), stats.Writes)
+	assert.Equal(t, uint64(1), stats.Commits)
+	assert.Equal(t, uint64(len("key")+len("value")), stats.BytesWritten)
+	assert.Greater(t, stats.CommitLatency, time.Duration(0))
+
+	var eg errgroup.Group
+	for i := 0; i < 10; i++ {
+		i := i
+		eg.Go(func() error { return db.Insert(strconv.Itoa(i), "value", []string{"stats"}) })
+	}
+	assert.Nil(t, eg.Wait())
+
+	assert.Nil(t, db.RunUpdate(func(tx *bbolt.Tx) error { return nil }))
+
+	stats = db.Stats()
+	assert.Equal(t, uint64(12), stats.Writes)
+	assert.LessOrEqual(t, stats.Commits, uint64(12))
+	assert.GreaterOrEqual(t, stats.MaxBatchSize, uint64(1))
+	assert.GreaterOrEqual(t, stats.AvgBatchSize(), 1.0)
+}
+
+func Test_dbWrapper_StatsBufferTimeouts(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	db.SetBufferTimeout(time.Millisecond * 10)
+	assert.Nil(t, db.Insert("k1", "v", []string{"a"}))
+	assert.Nil(t, db.Insert("k2", "v", []string{"a"}))
+
+	assert.NotNil(t, db.KeysAt([]string{"a"}, true, make(chan []byte)))
+	assert.NotNil(t, db.EntriesAt([]string{"a"}, true, make(chan [2][]byte)))
+	assert.NotNil(t, DoEach(make(chan int), db, func(int, chan int, DB) error { return nil }, make(chan int), 1, nil, nil, time.Millisecond*10))
+
+	stats := db.Stats()
+	assert.Equal(t, uint64(2), stats.BufferTimeouts)
+	assert.Equal(t, uint64(1), stats.BufferReceiveTimeouts)
+	assert.Equal(t, map[string]TimeoutCounts{"KeysAt": {Send: 1}, "EntriesAt": {Send: 1}, "DoEach": {Receive: 1}}, stats.BufferTimeoutsByOp)
+	assert.Equal(t, map[string]TimeoutCounts{`["a"]`: {Send: 2}}, stats.BufferTimeoutsByPath)
+
+	// Snapshots don't share their maps with the metrics.
+	stats.BufferTimeoutsByOp["KeysAt"] = TimeoutCounts{}
+	assert.Equal(t, TimeoutCounts{Send: 1}, db.Stats().BufferTimeoutsByOp["KeysAt"])
+}
@@ -0,0 +1,155 @@
+package quickbolt
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// WithNestedTxDetection makes writes fail with ErrNestedTx, rather than deadlock, when made on a goroutine
+// already holding a transaction opened by the db, such as from within a RunView or RunUpdate func,
+// between BeginRead and Rollback, or from any other func the db runs within a transaction, such as those
+// given to ForEachEntry, DeleteWhere, or RegisterValidator. Compact, Close, and RemoveFile, which wait for
+// every transaction to end, are checked as well.
+//
+// Writes made from a DoEach or DoEachInto func are checked too while the channel it receives from is being
+// fed by one of the db's iterations, such as ValuesAt, as the iteration holds its read transaction until
+// the func has taken every value.
+//
+// Detection identifies goroutines by parsing their stack traces, so it is intended for debugging
+// and tests rather than production. Transactions opened via the raw bbolt.DB are not tracked.
+func WithNestedTxDetection() Option {
+	return func(o *options) {
+		o.nestedTxDetection = true
+	}
+}
+
+// goroutineID returns the ID of the calling goroutine, as shown in its stack trace.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+
+	// The trace begins with "goroutine N [".
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// holdTx records that the calling goroutine holds a transaction opened by the given operation,
+// returning a func that must be called once the transaction ends.
+//
+// Nothing is recorded unless WithNestedTxDetection was given, or if the goroutine already holds a transaction.
+func (d *dbWrapper) holdTx(o *operation) func() {
+	holders := d.txHolders()
+	if holders == nil {
+		return func() {}
+	}
+
+	id := goroutineID()
+	if _, held := holders.LoadOrStore(id, o); held {
+		return func() {}
+	}
+
+	return func() { holders.Delete(id) }
+}
+
+// feedHold records that a goroutine is running a DoEach func receiving from the buffer.
+type feedHold struct {
+	buffer any
+}
+
+// feedTx records that the given operation is sending to the buffer from within a transaction,
+// returning a func that must be called once the transaction ends.
+//
+// Nothing is recorded unless WithNestedTxDetection was given.
+func (d *dbWrapper) feedTx(o *operation, buffer any) func() {
+	feeds := d.txFeeds()
+	if feeds == nil || buffer == nil {
+		return func() {}
+	}
+
+	feeds.Store(buffer, o)
+	return func() { feeds.Delete(buffer) }
+}
+
+// holdFeed records that the calling goroutine is running a DoEach func receiving from the buffer,
+// returning a func that must be called once the func returns.
+//
+// Writes made by the goroutine are treated as nested only while an operation is feeding the buffer.
+func (d *dbWrapper) holdFeed(buffer any) func() {
+	holders := d.txHolders()
+	if holders == nil {
+		return func() {}
+	}
+
+	id := goroutineID()
+	if _, held := holders.LoadOrStore(id, feedHold{buffer: buffer}); held {
+		return func() {}
+	}
+
+	return func() { holders.Delete(id) }
+}
+
+// holdingMerge returns add wrapped so that the goroutine running it, which may not be the caller's
+// when the write is batched, is recorded as holding a transaction opened by the given operation.
+func (d *dbWrapper) holdingMerge(o *operation, add MergeFunc) MergeFunc {
+	if add == nil {
+		return nil
+	}
+
+	return func(existing, v []byte) ([]byte, error) {
+		defer d.holdTx(o)()
+		return add(existing, v)
+	}
+}
+
+// checkNested returns ErrNestedTx, describing the given task, if the calling goroutine holds a transaction
+// opened by the db.
+func (d *dbWrapper) checkNested(task string) error {
+	holders := d.txHolders()
+	if holders == nil {
+		return nil
+	}
+
+	v, held := holders.Load(goroutineID())
+	if !held {
+		return nil
+	}
+
+	if h, ok := v.(feedHold); ok {
+		f, feeding := d.txFeeds().Load(h.buffer)
+		if !feeding {
+			return nil
+		}
+
+		o := f.(*operation)
+		return newErrNestedTx(task + " was attempted from a DoEach func receiving from a transaction opened by " + describeCaller(o.name, o.caller))
+	}
+
+	o := v.(*operation)
+	return newErrNestedTx(task + " was attempted on a goroutine holding a transaction opened by " + describeCaller(o.name, o.caller))
+}
+
+// txHolders returns the goroutines holding transactions, or nil if WithNestedTxDetection was not given.
+func (d *dbWrapper) txHolders() *sync.Map {
+	h := d.holder()
+	if h.state == nil {
+		return nil
+	}
+	return h.state.txHolders
+}
+
+// txFeeds returns the operations feeding buffers from within transactions, by buffer,
+// or nil if WithNestedTxDetection was not given.
+func (d *dbWrapper) txFeeds() *sync.Map {
+	h := d.holder()
+	if h.state == nil {
+		return nil
+	}
+	return h.state.txFeeds
+}
@@ -0,0 +1,143 @@
+package quickbolt
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func TestWithNestedTxDetection(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithNestedTxDetection())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	var nested ErrNestedTx
+
+	err = db.RunView(func(tx *bbolt.Tx) error {
+		err := db.Insert("k", "v", []string{"a"})
+		assert.ErrorAs(t, err, &nested)
+		assert.Contains(t, nested.What, "RunView called at line")
+		assert.Contains(t, nested.What, "nested_test.go")
+
+		// Reads within reads are safe.
+		_, err = db.GetValue("k", []string{"a"}, false)
+		assert.Nil(t, err)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	err = db.RunUpdate(func(tx *bbolt.Tx) error {
+		assert.ErrorAs(t, db.RunUpdate(func(tx *bbolt.Tx) error { return nil }), &nested)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	tx, err := db.BeginRead()
+	assert.Nil(t, err)
+	assert.ErrorAs(t, db.Compact(), &nested)
+	assert.Nil(t, tx.Rollback())
+
+	// The goroutine no longer holds a transaction.
+	assert.Nil(t, db.Insert("k", "v", []string{"a"}))
+	assert.Nil(t, db.Compact())
+
+	done := make(chan error)
+	err = db.RunView(func(tx *bbolt.Tx) error {
+		// Other goroutines are unaffected.
+		go func() { done <- db.RunView(func(tx *bbolt.Tx) error { return nil }) }()
+		return <-done
+	})
+	assert.Nil(t, err)
+}
+
+func TestWithNestedTxDetection_Callbacks(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithNestedTxDetection())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a"}
+	assert.Nil(t, db.Insert("k", "v", path))
+
+	var nested ErrNestedTx
+	write := func(what string) {
+		err := db.Insert("other", "v", []string{"b"})
+		assert.ErrorAs(t, err, &nested, what)
+		assert.Contains(t, nested.What, "nested_test.go", what)
+	}
+
+	assert.Nil(t, db.ForEachEntry(path, func(k, v []byte) error { write("ForEachEntry"); return nil }))
+	assert.Nil(t, db.ForEachKey(path, func(k []byte) error { write("ForEachKey"); return nil }))
+
+	_, err = db.CopyWhere(path, []string{"c"}, func(k, v []byte) bool { write("CopyWhere"); return false })
+	assert.Nil(t, err)
+	_, err = db.DeleteWhere(path, func(k, v []byte) bool { write("DeleteWhere"); return false })
+	assert.Nil(t, err)
+	_, err = db.UpdateWhere(path, func(k, v []byte) bool { write("UpdateWhere"); return false }, func(v []byte) ([]byte, error) { return v, nil })
+	assert.Nil(t, err)
+	_, err = db.GroupBy(path, []string{"c"}, func(k, v []byte) []byte { write("GroupBy"); return nil })
+	assert.Nil(t, err)
+	_, err = db.MapBucket(path, []string{"c"}, func(k, v []byte) ([]byte, []byte, bool, error) { write("MapBucket"); return nil, nil, true, nil })
+	assert.Nil(t, err)
+
+	assert.Nil(t, db.Upsert("k", "v", path, func(a, b []byte) ([]byte, error) { write("Upsert"); return b, nil }))
+	assert.Nil(t, db.RunBatch(func(tx *bbolt.Tx) error { write("RunBatch"); return nil }))
+
+	assert.Nil(t, db.RegisterValidator(path, func(k, v []byte) error { write("RegisterValidator"); return nil }))
+	assert.Nil(t, db.Insert("k2", "v", path))
+
+	// The goroutine no longer holds a transaction.
+	assert.Nil(t, db.Insert("k3", "v", []string{"b"}))
+}
+
+func TestWithNestedTxDetection_DoEach(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithNestedTxDetection())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a"}
+	for _, k := range []string{"1", "2", "3"} {
+		assert.Nil(t, db.Insert(k, k, path))
+	}
+
+	buffer := make(chan []byte)
+	go db.ValuesAt(path, true, buffer)
+
+	var mu sync.Mutex
+	errs := make(map[string]error)
+	do := func(v []byte, out chan []byte, db DB) error {
+		err := db.Insert("other", "v", []string{"b"})
+		mu.Lock()
+		errs[string(v)] = err
+		mu.Unlock()
+		return nil
+	}
+
+	// With one worker, ValuesAt is still waiting to send the third value while the first is handled.
+	assert.Nil(t, DoEach(buffer, db, do, make(chan []byte), 1, nil, nil))
+
+	var nested ErrNestedTx
+	assert.ErrorAs(t, errs["1"], &nested)
+	assert.Contains(t, nested.What, "ValuesAt")
+
+	// Once the iteration has ended, the funcs may write.
+	in := make(chan []byte, 1)
+	in <- []byte("4")
+	close(in)
+	assert.Nil(t, DoEach(in, db, do, make(chan []byte), 1, nil, nil))
+	assert.Nil(t, errs["4"])
+}
+
+func Test_goroutineID(t *testing.T) {
+	id := goroutineID()
+	assert.NotZero(t, id)
+	assert.Equal(t, id, goroutineID())
+
+	other := make(chan uint64)
+	go func() { other <- goroutineID() }()
+	assert.NotEqual(t, id, <-other)
+}
@@ -0,0 +1,43 @@
+package quickbolt
+
+import "golang.org/x/text/unicode/norm"
+
+// NormalizationForm is a Unicode normalization form applied to string keys and path segments.
+type NormalizationForm int
+
+const (
+	// NFC composes characters by canonical equivalence, so that, e.g., "é" written as "e" followed by
+	// a combining acute accent matches the precomposed "é".
+	NFC NormalizationForm = iota + 1
+	// NFKC composes characters by compatibility equivalence, which additionally matches, e.g.,
+	// the ligature "ﬁ" with "fi" and full-width letters with their ASCII counterparts.
+	NFKC
+)
+
+// normalizer returns the func normalizing strings to the form, or nil if the form is not set.
+func (f NormalizationForm) normalizer() func(string) string {
+	switch f {
+	case NFC:
+		return norm.NFC.String
+	case NFKC:
+		return norm.NFKC.String
+	}
+
+	return nil
+}
+
+// WithUnicodeNormalization normalizes keys and bucket path segments given as strings to the given form,
+// so that visually identical strings name the same pair or bucket.
+//
+// Keys and segments given as []byte, or as any other type, are left as is. Keys already in the db
+// are not normalized, so the option should be used from the db's creation on.
+func WithUnicodeNormalization(form NormalizationForm) Option {
+	return func(o *options) {
+		o.normalization = form
+	}
+}
+
+// resolveKey resolves the given key, normalizing it if it is a string and WithUnicodeNormalization is used.
+func (d *dbWrapper) resolveKey(key any) ([]byte, error) {
+	return resolveNormalizedKey(key, d.opts.normalization.normalizer())
+}
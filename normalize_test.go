@@ -0,0 +1,48 @@
+package quickbolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithUnicodeNormalization(t *testing.T) {
+	const (
+		composed   = "caf\u00e9"  // "café" with a precomposed é
+		decomposed = "cafe\u0301" // "café" with a combining acute accent
+	)
+
+	db, err := CreateWith("foo.db", t.TempDir(), WithUnicodeNormalization(NFC))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert(decomposed, "1", []string{decomposed}))
+
+	v, err := db.GetValue(composed, []string{composed}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+
+	v, err = db.GetValue(composed, P(composed), true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+
+	v, err = db.GetValue(composed, []any{composed}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+
+	// Keys and segments given as bytes are left as is.
+	v, err = db.GetValue([]byte(decomposed), [][]byte{[]byte(composed)}, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	v, err = db.GetValue(composed, [][]byte{[]byte(composed)}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+}
+
+func TestNormalizationForm(t *testing.T) {
+	assert.Equal(t, "fi", NFKC.normalizer()("ﬁ"))
+	assert.Equal(t, "ﬁ", NFC.normalizer()("ﬁ"))
+	assert.Nil(t, NormalizationForm(0).normalizer())
+}
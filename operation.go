@@ -0,0 +1,142 @@
+package quickbolt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	opCounter atomic.Uint64
+	// opPrefix distinguishes the operation IDs of this process from those of others writing to the same log sinks.
+	opPrefix = newOpPrefix()
+)
+
+func newOpPrefix() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "op"
+	}
+	return hex.EncodeToString(b)
+}
+
+// operation tracks a single call to a DB method.
+type operation struct {
+	// seq is formatted into the operation's ID only when the ID is needed.
+	seq  uint64
+	name string
+	// caller is the program counter of the DB method's caller, or 0 if caller info is disabled.
+	caller  uintptr
+	start   time.Time
+	metrics *metrics
+	// labeled is true if the operation sets pprof labels on its goroutine.
+	labeled bool
+	// path is the bucket path the operation was called with, if any.
+	path    [][]byte
+	onError func(op string, path [][]byte, err error)
+}
+
+// beginOp starts tracking a call to the DB method of the given name.
+//
+// beginOp must be called directly by the DB method so that the method's caller can be recorded.
+func (d *dbWrapper) beginOp(name string) *operation {
+	o := &operation{
+		seq:     opCounter.Add(1),
+		name:    name,
+		start:   time.Now(),
+		metrics: d.metrics,
+		onError: d.onError,
+	}
+
+	if !d.opts.noCallerInfo {
+		o.caller = callerPC(3)
+	}
+
+	if d.opts.profilerLabels {
+		o.labeled = true
+		o.setLabels(nil)
+	}
+
+	return o
+}
+
+// id returns the operation's ID.
+func (o *operation) id() string {
+	return opPrefix + "-" + strconv.FormatUint(o.seq, 36)
+}
+
+// end finishes tracking the operation, wrapping the error pointed to by err, if any, with the operation's ID
+// and caller info.
+//
+// The caller info is not resolved until the error's message is needed.
+func (o *operation) end(err *error) {
+	o.clearLabels()
+
+	if err == nil || *err == nil {
+		o.metrics.observeOp(o, nil)
+		return
+	}
+
+	o.metrics.observeOp(o, *err)
+
+	*err = newErrOperation(o.id(), o.name, o.caller, *err)
+
+	if o.onError != nil {
+		o.onError(o.name, o.path, *err)
+	}
+}
+
+// notifyErr passes an error that is logged rather than returned, such as one from background work,
+// to the listener set via OnError, if any.
+func (d *dbWrapper) notifyErr(op string, path [][]byte, err error) {
+	if d.onError != nil {
+		d.onError(op, path, err)
+	}
+}
+
+// forOp returns a copy of the wrapper whose log events carry the ID of the given operation.
+func (d *dbWrapper) forOp(o *operation) dbWrapper {
+	w := *d
+	w.op = o
+	return w
+}
+
+// logErr returns an error event for the wrapper's logger, carrying the ID and name of the wrapper's operation
+// if it has one.
+//
+// Callers must hold logMutex while the event is written.
+func (d *dbWrapper) logErr(err error) *zerolog.Event {
+	e := d.logger.Err(err)
+	if d.op != nil {
+		e = e.Str("op", d.op.id()).Str("operation", d.op.name)
+	}
+	return e
+}
+
+// newLogger returns a logger writing to w whose events carry the db's file path and root bucket,
+// along with the fields given via WithLogFields.
+func (d *dbWrapper) newLogger(w io.Writer) zerolog.Logger {
+	c := zerolog.New(w).With().Str("root", rootBucket)
+	if d.db != nil {
+		c = c.Str("db", d.db.Path())
+	}
+
+	return c.Fields(d.opts.logFields).Logger()
+}
+
+// OperationID returns the ID of the operation that produced the given error.
+//
+// The returned bool is false if the error was not returned by a DB method.
+func OperationID(err error) (string, bool) {
+	var op ErrOperation
+	if !errors.As(err, &op) {
+		return "", false
+	}
+	return op.ID, true
+}
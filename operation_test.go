@@ -0,0 +1,119 @@
+package quickbolt
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationID(t *testing.T) {
+	db, err := Create("foo.db")
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	_, err = db.GetValue("missing", []string{"ops"}, true)
+	first, ok := OperationID(err)
+	assert.True(t, ok)
+	assert.Contains(t, err.Error(), first)
+
+	_, err = db.GetValue("missing", []string{"ops"}, true)
+	second, ok := OperationID(err)
+	assert.True(t, ok)
+	assert.NotEqual(t, first, second)
+
+	_, ok = OperationID(nil)
+	assert.False(t, ok)
+
+	var log bytes.Buffer
+	db.AddLog(&log)
+	db.SetBufferTimeout(time.Millisecond)
+
+	assert.Nil(t, db.Insert("key", "value", []string{"ops"}))
+
+	err = db.ValuesAt([]string{"ops"}, true, make(chan []byte))
+	id, ok := OperationID(err)
+	assert.True(t, ok)
+	assert.Contains(t, log.String(), id)
+}
+
+func TestWithoutCallerInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantCaller bool
+	}{
+		{name: "Default", wantCaller: true},
+		{name: "Disabled", opts: []Option{WithoutCallerInfo()}, wantCaller: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := CreateWith("foo.db", t.TempDir(), tt.opts...)
+			assert.Nil(t, err)
+
+			defer db.RemoveFile()
+
+			_, err = db.GetValue("missing", []string{"ops"}, true)
+			assert.NotNil(t, err)
+			assert.Equal(t, tt.wantCaller, strings.Contains(err.Error(), "operation_test.go"))
+		})
+	}
+}
+
+func Test_dbWrapper_OnError(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	type event struct {
+		op   string
+		path [][]byte
+		err  error
+	}
+	var events []event
+	db.OnError(func(op string, path [][]byte, err error) {
+		events = append(events, event{op: op, path: path, err: err})
+	})
+
+	assert.Nil(t, db.Insert("a", "b", []string{"ops"}))
+	assert.Empty(t, events)
+
+	_, err = db.GetValue("missing", []string{"ops"}, true)
+	assert.NotNil(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "GetValue", events[0].op)
+		assert.Equal(t, [][]byte{[]byte("ops")}, events[0].path)
+		assert.Equal(t, err, events[0].err)
+	}
+
+	db.OnError(nil)
+	_, err = db.GetValue("missing", []string{"ops"}, true)
+	assert.NotNil(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestWithLogFields(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithLogFields(map[string]any{"service": "billing"}))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	var log bytes.Buffer
+	db.AddLog(&log)
+	db.SetBufferTimeout(time.Millisecond)
+
+	assert.Nil(t, db.Insert("key", "value", []string{"ops"}))
+	assert.NotNil(t, db.ValuesAt([]string{"ops"}, true, make(chan []byte)))
+
+	var event map[string]any
+	assert.Nil(t, json.Unmarshal(log.Bytes(), &event))
+	assert.Equal(t, "billing", event["service"])
+	assert.Equal(t, db.Path(), event["db"])
+	assert.Equal(t, string(db.RootBucket()), event["root"])
+	assert.Equal(t, "ValuesAt", event["operation"])
+}
@@ -0,0 +1,189 @@
+package quickbolt
+
+import (
+	"os"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Option configures how a database is opened.
+type Option func(*options)
+
+// options holds the settings applied when opening a database.
+type options struct {
+	fileMode    os.FileMode
+	dirMode     os.FileMode
+	schema      *Schema
+	autoCompact *autoCompactPolicy
+	// noCallerInfo disables the collection of caller info for errors.
+	noCallerInfo    bool
+	initialMmapSize int
+	noGrowSync      bool
+	pageSize        int
+	strictMode      bool
+	writeLimiter    WriteLimiter
+	profilerLabels  bool
+	leaseSweep      time.Duration // leaseSweep is the interval between background sweeps of expired leases, or 0 if disabled.
+	normalization   NormalizationForm
+	keyGenerator    KeyGenerator
+	longRead        time.Duration // longRead is the age beyond which read transactions are warned of, or 0 if disabled.
+	// nestedTxDetection makes writes on goroutines holding a transaction fail rather than deadlock.
+	nestedTxDetection bool
+	logFields         map[string]any
+	openTimeout       time.Duration
+	readOnly          bool
+	noSync            bool
+	freelistType      bbolt.FreelistType
+}
+
+// newOptions returns the default options with the given options applied.
+func newOptions(opts ...Option) options {
+	o := options{
+		fileMode: defaultFileMode,
+		dirMode:  defaultDirMode,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+
+	return o
+}
+
+// WithFileMode sets the permissions used when creating the database file.
+//
+// The default is 0600.
+func WithFileMode(mode os.FileMode) Option {
+	return func(o *options) {
+		o.fileMode = mode
+	}
+}
+
+// WithDirMode sets the permissions used for any directories quickbolt creates.
+//
+// The default is 0700.
+func WithDirMode(mode os.FileMode) Option {
+	return func(o *options) {
+		o.dirMode = mode
+	}
+}
+
+// WithoutCallerInfo disables the collection of caller info for errors returned by the database.
+//
+// By default, errors describe the file and line the failing method was called from,
+// which requires a stack lookup on every call.
+func WithoutCallerInfo() Option {
+	return func(o *options) {
+		o.noCallerInfo = true
+	}
+}
+
+// WithInitialMmapSize sets the initial size, in bytes, of the database's memory map.
+//
+// Databases expected to grow large can be pre-mapped to avoid remapping, which blocks writes, as they grow.
+// A size smaller than the database file has no effect.
+func WithInitialMmapSize(size int) Option {
+	return func(o *options) {
+		o.initialMmapSize = size
+	}
+}
+
+// WithNoGrowSync skips syncing the file's size to disk when the database grows.
+//
+// This is only safe on file systems that do not require it, such as ext3 and ext4, and is unsafe on others.
+func WithNoGrowSync() Option {
+	return func(o *options) {
+		o.noGrowSync = true
+	}
+}
+
+// WithPageSize sets the page size, in bytes, used when creating the database file.
+//
+// Larger pages suit workloads with large values. The page size of an existing file cannot be changed,
+// so this has no effect when opening one. The default is the OS page size.
+func WithPageSize(size int) Option {
+	return func(o *options) {
+		o.pageSize = size
+	}
+}
+
+// WithStrictMode enables bbolt's strict mode, which checks the database's consistency after every commit
+// and panics if it finds an error.
+//
+// The checks are expensive, so strict mode is intended for tests rather than production.
+func WithStrictMode() Option {
+	return func(o *options) {
+		o.strictMode = true
+	}
+}
+
+// WithLogFields adds the given fields to every event the database logs, alongside the db's file path,
+// root bucket, and, where known, the name of the operation that logged the event.
+func WithLogFields(fields map[string]any) Option {
+	return func(o *options) {
+		o.logFields = fields
+	}
+}
+
+// WithOpenTimeout sets how long opening the database waits for the file lock held by another process
+// before failing.
+//
+// The default is to wait indefinitely.
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.openTimeout = timeout
+	}
+}
+
+// WithReadOnly opens the database file read-only, under a shared lock, so that other processes
+// may read it concurrently. Writes fail, and the file must already exist.
+func WithReadOnly() Option {
+	return func(o *options) {
+		o.readOnly = true
+	}
+}
+
+// WithNoSync skips syncing the file to disk after every commit, so that a crash may lose or corrupt recent writes.
+//
+// This suits bulk loads that can be rerun from scratch, and is unsafe otherwise.
+func WithNoSync() Option {
+	return func(o *options) {
+		o.noSync = true
+	}
+}
+
+// WithFreelistType sets the backend of the freelist tracking the file's free pages,
+// e.g. bbolt.FreelistMapType, which speeds up writes to large, fragmented databases.
+//
+// The default is bbolt.FreelistArrayType.
+func WithFreelistType(t bbolt.FreelistType) Option {
+	return func(o *options) {
+		o.freelistType = t
+	}
+}
+
+// openBolt opens the bbolt database at the given path per the given options.
+func openBolt(path string, o options) (*bbolt.DB, error) {
+	bo := *bbolt.DefaultOptions
+	bo.InitialMmapSize = o.initialMmapSize
+	bo.NoGrowSync = o.noGrowSync
+	bo.PageSize = o.pageSize
+	bo.Timeout = o.openTimeout
+	bo.ReadOnly = o.readOnly
+	bo.NoSync = o.noSync
+	if o.freelistType != "" {
+		bo.FreelistType = o.freelistType
+	}
+
+	db, err := bbolt.Open(path, o.fileMode, &bo)
+	if err != nil {
+		return nil, err
+	}
+
+	db.StrictMode = o.strictMode
+
+	return db, nil
+}
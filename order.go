@@ -0,0 +1,164 @@
+package quickbolt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"go.etcd.io/bbolt"
+)
+
+// OrderFunc returns the bytes a key is ordered by when iterating via EntriesInOrder.
+//
+// Keys with equal order bytes are ordered by the keys themselves.
+type OrderFunc func(key []byte) []byte
+
+// NumericOrder orders keys that are decimal integers, such as those generated by InsertValue, by value.
+// Keys that are not decimal integers follow in byte order.
+func NumericOrder(key []byte) []byte {
+	b := make([]byte, 9)
+
+	if n, err := strconv.ParseInt(string(key), 10, 64); err == nil {
+		// Flipping the sign bit places negative numbers before positive ones.
+		b[0] = 1
+		binary.BigEndian.PutUint64(b[1:], uint64(n)^1<<63)
+		return b
+	} else if u, err := strconv.ParseUint(string(key), 10, 64); err == nil {
+		// Only numbers beyond the range of int64 reach here.
+		b[0] = 2
+		binary.BigEndian.PutUint64(b[1:], u)
+		return b
+	}
+
+	return append([]byte{3}, key...)
+}
+
+// orderIndexKey returns the key of the given key's entry in the order index.
+//
+// Zero bytes within the order bytes are escaped and the order bytes are terminated so that
+// order bytes sharing a prefix sort as they would alone, regardless of the key appended after them.
+func orderIndexKey(order, key []byte) []byte {
+	b := make([]byte, 0, len(order)+len(key)+2)
+	for _, c := range order {
+		if c == 0 {
+			b = append(b, 0, 0xff)
+			continue
+		}
+		b = append(b, c)
+	}
+
+	return append(append(b, 0, 1), key...)
+}
+
+// putOrderIndex adds the key to the order index for the given path.
+func putOrderIndex(tx *bbolt.Tx, path [][]byte, key []byte, order OrderFunc) error {
+	idx, err := getCreateIndexChild(tx, path, orderByKey)
+	if err != nil {
+		return fmt.Errorf("error while navigating order index: %w", err)
+	}
+
+	if err := idx.Put(orderIndexKey(order(key), key), key); err != nil {
+		return fmt.Errorf("error while ordering %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// deleteOrderIndex removes the key from the order index for the given path.
+func deleteOrderIndex(tx *bbolt.Tx, path [][]byte, key []byte, order OrderFunc) error {
+	idx := getIndexChild(tx, path, orderByKey)
+	if idx == nil {
+		return nil
+	}
+
+	if err := idx.Delete(orderIndexKey(order(key), key)); err != nil {
+		return fmt.Errorf("error while removing %s from order index: %w", key, err)
+	}
+
+	return nil
+}
+
+// rebuildOrderIndex discards the order index for the given path and rebuilds it from the bucket's current keys.
+func rebuildOrderIndex(db *bbolt.DB, path [][]byte, order OrderFunc) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	return db.Update(func(tx *bbolt.Tx) error {
+		idx, err := getCreateIndexChild(tx, path, orderByKey)
+		if err != nil {
+			return fmt.Errorf("error while navigating order index: %w", err)
+		}
+
+		var stale [][]byte
+		idx.ForEach(func(k, _ []byte) error {
+			stale = append(stale, k)
+			return nil
+		})
+
+		for _, k := range stale {
+			if err := idx.Delete(k); err != nil {
+				return fmt.Errorf("error while clearing order index: %w", err)
+			}
+		}
+
+		bkt, err := getCreateBucket(tx, path)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		c := bkt.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+
+			if err := idx.Put(orderIndexKey(order(k), k), k); err != nil {
+				return fmt.Errorf("error while ordering %s: %w", k, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// entriesInOrder sends the key-value pairs at the given path to the buffer in the order recorded by its order index.
+func entriesInOrder(db *bbolt.DB, path [][]byte, buffer chan [2][]byte, dbWrap dbWrapper) error {
+	if db == nil {
+		return fmt.Errorf("ordered key-value iteration at %s received nil db", path)
+	} else if buffer == nil {
+		return fmt.Errorf("ordered key-value iteration at %s received nil channel", path)
+	}
+
+	defer close(buffer)
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		idx := getIndexChild(tx, path, orderByKey)
+		if bkt == nil || idx == nil {
+			return nil
+		}
+
+		c := idx.Cursor()
+		for _, k := c.First(); k != nil; _, k = c.Next() {
+			v := bkt.Get(k)
+			if v == nil {
+				continue
+			}
+
+			if err := sendTo(buffer, [2][]byte{copyBytes(k), copyBytes(v)}, dbWrap, "quickbolt ordered key-value scanning", path, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("ordered key-value iteration at %s experienced error while scanning index: %w", path, err)
+	}
+	return nil
+}
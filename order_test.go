@@ -0,0 +1,69 @@
+package quickbolt
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumericOrder(t *testing.T) {
+	keys := []string{"10", "-3", "abc", "2", "18446744073709551615", "0", "-20", "9"}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(NumericOrder([]byte(keys[i])), NumericOrder([]byte(keys[j]))) < 0
+	})
+	assert.Equal(t, []string{"-20", "-3", "0", "2", "9", "10", "18446744073709551615", "abc"}, keys)
+}
+
+func Test_orderIndexKey(t *testing.T) {
+	// Order bytes sharing a prefix sort as they would alone, whatever the keys.
+	a := orderIndexKey([]byte("a"), []byte("zzz"))
+	ab := orderIndexKey([]byte("ab"), []byte("a"))
+	a0 := orderIndexKey([]byte("a\x00"), []byte("a"))
+	assert.Equal(t, -1, bytes.Compare(a, ab))
+	assert.Equal(t, -1, bytes.Compare(a, a0))
+	assert.Equal(t, -1, bytes.Compare(a0, ab))
+}
+
+func collectEntries(t *testing.T, buffer chan [2][]byte, run func() error) []string {
+	done := make(chan error)
+	go func() { done <- run() }()
+
+	var keys []string
+	for e := range buffer {
+		keys = append(keys, string(e[0]))
+	}
+	assert.Nil(t, <-done)
+	return keys
+}
+
+func Test_dbWrapper_EntriesInOrder(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"ordered"}
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, db.InsertValue("v", path))
+	}
+
+	assert.NotNil(t, db.EntriesInOrder(path, make(chan [2][]byte)))
+
+	assert.Nil(t, db.RegisterOrder(path, NumericOrder))
+	for i := 0; i < 7; i++ {
+		assert.Nil(t, db.InsertValue("v", path))
+	}
+	assert.Nil(t, db.Delete("3", path))
+
+	buffer := make(chan [2][]byte)
+	keys := collectEntries(t, buffer, func() error { return db.EntriesInOrder(path, buffer) })
+	assert.Equal(t, []string{"1", "2", "4", "5", "6", "7", "8", "9", "10", "11", "12"}, keys)
+
+	// Registering a new order rebuilds the index.
+	assert.Nil(t, db.RegisterOrder(path, func(k []byte) []byte { return k }))
+	buffer = make(chan [2][]byte)
+	keys = collectEntries(t, buffer, func() error { return db.EntriesInOrder(path, buffer) })
+	assert.Equal(t, []string{"1", "10", "11", "12", "2", "4", "5", "6", "7", "8", "9"}, keys)
+}
@@ -0,0 +1,157 @@
+package quickbolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"go.etcd.io/bbolt"
+)
+
+// partitionSamples is the number of cursor seeks made per requested partition when computing partitions.
+const partitionSamples = 32
+
+// KeyRange is a range of keys from Start, inclusive, to End, exclusive.
+//
+// A nil Start begins the range at the first key, and a nil End ends it after the last.
+type KeyRange struct {
+	Start []byte
+	End   []byte
+}
+
+// Contains reports whether the key falls within the range.
+func (r KeyRange) Contains(key []byte) bool {
+	return (r.Start == nil || bytes.Compare(key, r.Start) >= 0) && (r.End == nil || bytes.Compare(key, r.End) < 0)
+}
+
+// partitions splits the keys of the bucket at the given path into up to n contiguous ranges of roughly equal size.
+//
+// Rather than counting every key, the key space between the first and last keys is sampled with cursor seeks,
+// and the ranges are split at quantiles of the keys found.
+func partitions(db *bbolt.DB, path [][]byte, n int) ([]KeyRange, error) {
+	if db == nil {
+		return nil, fmt.Errorf("partitioning of %s received nil db", path)
+	} else if n < 1 {
+		return nil, fmt.Errorf("partitioning of %s received invalid partition count %d", path, n)
+	}
+
+	var splits [][]byte
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		c := bkt.Cursor()
+		first, _ := c.First()
+		last, _ := c.Last()
+		if first == nil || n == 1 {
+			return nil
+		}
+
+		// Keys are interpreted as numbers after their common prefix so that evenly spaced seek targets can be computed.
+		prefix := commonPrefix(first, last)
+		lo, hi := keyNumber(first[len(prefix):]), keyNumber(last[len(prefix):])
+
+		var samples [][]byte
+		total := uint64(n * partitionSamples)
+		for i := uint64(1); i < total; i++ {
+			target := make([]byte, len(prefix)+8)
+			copy(target, prefix)
+			// The offset is computed as (hi-lo)*i/total in 128 bits so that narrow key spaces don't round to zero.
+			mulHi, mulLo := bits.Mul64(hi-lo, i)
+			offset, _ := bits.Div64(mulHi, mulLo, total)
+			binary.BigEndian.PutUint64(target[len(prefix):], lo+offset)
+
+			k, _ := c.Seek(target)
+			if k == nil || bytes.Equal(k, first) {
+				continue
+			}
+			if len(samples) == 0 || !bytes.Equal(samples[len(samples)-1], k) {
+				samples = append(samples, copyBytes(k))
+			}
+		}
+
+		for j := 1; j < n && len(samples) > 0; j++ {
+			s := samples[j*len(samples)/n]
+			if len(splits) == 0 || !bytes.Equal(splits[len(splits)-1], s) {
+				splits = append(splits, s)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("partitioning of %s experienced error while sampling keys: %w", path, err)
+	}
+
+	ranges := make([]KeyRange, 0, len(splits)+1)
+	var start []byte
+	for _, s := range splits {
+		ranges = append(ranges, KeyRange{Start: start, End: s})
+		start = s
+	}
+
+	return append(ranges, KeyRange{Start: start}), nil
+}
+
+// commonPrefix returns the longest prefix shared by a and b.
+func commonPrefix(a, b []byte) []byte {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+// keyNumber returns the first 8 bytes of the key as a big-endian number, padding shorter keys with zeros.
+func keyNumber(key []byte) uint64 {
+	var b [8]byte
+	copy(b[:], key)
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// entriesInRange sends the key-value pairs within the range at the given path to the buffer.
+func entriesInRange(db *bbolt.DB, path [][]byte, r KeyRange, buffer chan [2][]byte, dbWrap dbWrapper) error {
+	if db == nil {
+		return fmt.Errorf("range iteration at %s received nil db", path)
+	} else if buffer == nil {
+		return fmt.Errorf("range iteration at %s received nil channel", path)
+	}
+
+	defer close(buffer)
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		c := bkt.Cursor()
+
+		k, v := c.First()
+		if r.Start != nil {
+			k, v = c.Seek(r.Start)
+		}
+
+		for ; k != nil && (r.End == nil || bytes.Compare(k, r.End) < 0); k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+
+			if err := sendTo(buffer, [2][]byte{copyBytes(k), copyBytes(v)}, dbWrap, "quickbolt range scanning", path, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("range iteration at %s experienced error while scanning keys: %w", path, err)
+	}
+	return nil
+}
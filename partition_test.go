@@ -0,0 +1,67 @@
+package quickbolt
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func Test_dbWrapper_Partitions(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := [][]byte{[]byte("items")}
+	const total = 10000
+
+	err = db.RunUpdate(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateBucket(tx, path)
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); i < total; i++ {
+			k := make([]byte, 8)
+			binary.BigEndian.PutUint64(k, i*7)
+			if err := bkt.Put(k, []byte("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	ranges, err := db.Partitions(path, 4)
+	assert.Nil(t, err)
+	assert.Len(t, ranges, 4)
+	assert.Nil(t, ranges[0].Start)
+	assert.Nil(t, ranges[3].End)
+
+	seen := 0
+	for i, r := range ranges {
+		if i > 0 {
+			assert.Equal(t, ranges[i-1].End, r.Start, "ranges must be contiguous")
+		}
+
+		buffer := make(chan [2][]byte)
+		go func() { assert.Nil(t, db.EntriesInRange(path, r, buffer)) }()
+
+		n := 0
+		for e := range buffer {
+			assert.True(t, r.Contains(e[0]))
+			n++
+		}
+		assert.InDelta(t, total/4, n, total/20, "partition %d is unbalanced", i)
+		seen += n
+	}
+	assert.Equal(t, total, seen)
+
+	single, err := db.Partitions(path, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []KeyRange{{}}, single)
+
+	_, err = db.Partitions(path, 0)
+	assert.NotNil(t, err)
+}
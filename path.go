@@ -0,0 +1,67 @@
+package quickbolt
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Path is a bucket path built segment by segment, e.g. P("users").Sub("active").Sub(tenantID).
+//
+// Segments must be non-empty and of a type supported for keys, and are resolved as keys are.
+// The first invalid segment is recorded and described by Err, and DB methods given the path fail
+// with ErrBucketPathResolution.
+//
+// The zero Path is the empty path.
+type Path struct {
+	segments [][]byte
+	// text records which segments were given as strings, as only those are subject to WithUnicodeNormalization.
+	text []bool
+	err  error
+}
+
+// P returns a Path of the given segments.
+func P(segments ...any) Path {
+	var p Path
+	for _, s := range segments {
+		p = p.Sub(s)
+	}
+	return p
+}
+
+// Sub returns a copy of the path with the given segment appended.
+func (p Path) Sub(segment any) Path {
+	if p.err != nil {
+		return p
+	}
+
+	s, err := resolveRecord(segment)
+	if err != nil {
+		return Path{segments: p.segments, text: p.text, err: fmt.Errorf("segment %d of path %s could not be resolved: %w", len(p.segments), p, err)}
+	}
+
+	if len(s) == 0 {
+		return Path{segments: p.segments, text: p.text, err: fmt.Errorf("segment %d of path %s is empty", len(p.segments), p)}
+	}
+
+	_, isText := segment.(string)
+
+	return Path{
+		segments: append(append(make([][]byte, 0, len(p.segments)+1), p.segments...), s),
+		text:     append(append(make([]bool, 0, len(p.text)+1), p.text...), isText),
+	}
+}
+
+// Err returns the error encountered while building the path, if any.
+func (p Path) Err() error {
+	return p.err
+}
+
+// Bytes returns a copy of the path's segments.
+func (p Path) Bytes() [][]byte {
+	return copyPath(p.segments)
+}
+
+// String returns the path's segments joined by "/".
+func (p Path) String() string {
+	return string(bytes.Join(p.segments, []byte("/")))
+}
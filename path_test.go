@@ -0,0 +1,56 @@
+package quickbolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath(t *testing.T) {
+	p := P("users").Sub("active").Sub([]byte("t1"))
+	assert.Nil(t, p.Err())
+	assert.Equal(t, "users/active/t1", p.String())
+	assert.Equal(t, [][]byte{[]byte("users"), []byte("active"), []byte("t1")}, p.Bytes())
+
+	// Sub leaves the receiver unchanged.
+	q := p.Sub("a")
+	r := p.Sub("b")
+	assert.Equal(t, "users/active/t1/a", q.String())
+	assert.Equal(t, "users/active/t1/b", r.String())
+
+	empty := P("users").Sub("").Sub("active")
+	assert.ErrorContains(t, empty.Err(), "segment 1 of path users is empty")
+
+	unsupported := P("users", complex(1, 1))
+	var typeErr ErrUnsupportedType
+	assert.ErrorAs(t, unsupported.Err(), &typeErr)
+
+	assert.Equal(t, "", Path{}.String())
+	assert.Empty(t, P().Bytes())
+}
+
+func Test_dbWrapper_Path(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	p := P("users").Sub("active")
+	assert.Nil(t, db.Insert("alice", "1", p))
+	assert.Nil(t, db.Insert("carol", "3", []any{"tenants", 42, uint64(7)}))
+	assert.Nil(t, db.Insert("dave", "4", P("tenants", 42).Sub(uint64(7))))
+
+	keys, err := db.GetKeys("3", []any{"tenants", 42, uint64(7)}, true)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 1)
+	v, err := db.GetValue("dave", []any{"tenants", "42", uint64(7)}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("4"), v)
+
+	v, err = db.GetValue("alice", []string{"users", "active"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+
+	err = db.Insert("bob", "2", P("users", ""))
+	assert.ErrorIs(t, err, ErrBucketPathResolution{What: "error"})
+}
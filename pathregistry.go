@@ -0,0 +1,125 @@
+package quickbolt
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"go.etcd.io/bbolt"
+)
+
+// registerPath records the path under the given name, returning an error if the name is registered to another path.
+func (r *ruleRegistry) registerPath(name string, path [][]byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.paths[name]; ok && pathKey(existing) != pathKey(path) {
+		return newErrDuplicateValue(fmt.Sprintf("path name %s, registered to %s", name, existing))
+	}
+
+	if r.paths == nil {
+		r.paths = make(map[string][][]byte)
+	}
+	r.paths[name] = path
+
+	return nil
+}
+
+// registeredPath returns the path registered under the given name.
+func (r *ruleRegistry) registeredPath(name string) ([][]byte, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.paths[name]
+	return p, ok
+}
+
+// allPaths returns the registered paths, sorted by name.
+func (r *ruleRegistry) allPaths() (names []string, paths [][][]byte) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for name := range r.paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		paths = append(paths, r.paths[name])
+	}
+
+	return names, paths
+}
+
+// validatePaths checks that every given path exists, creating it if create is true, and returns the paths
+// of the topmost buckets not along or nested under any of them.
+func validatePaths(db *bbolt.DB, names []string, paths [][][]byte, create bool) ([][][]byte, error) {
+	if db == nil {
+		return nil, fmt.Errorf("path validation received nil db")
+	}
+
+	var unregistered [][][]byte
+
+	visit := func(tx *bbolt.Tx) error {
+		for i, p := range paths {
+			if create {
+				if _, err := getCreateBucket(tx, p); err != nil {
+					return fmt.Errorf("error while creating %s path %s: %w", names[i], p, err)
+				}
+			} else if bkt, err := getBucket(tx, p, false); err != nil {
+				return fmt.Errorf("error while navigating %s path %s: %w", names[i], p, err)
+			} else if bkt == nil {
+				return newErrLocate(fmt.Sprintf("%s path %s", names[i], p))
+			}
+		}
+
+		root, err := getBucket(tx, nil, false)
+		if err != nil || root == nil {
+			return err
+		}
+
+		return walkBuckets(root, nil, func(b [][]byte) error {
+			for _, u := range unregistered {
+				if hasPathPrefix(b, u) {
+					return nil
+				}
+			}
+
+			for _, p := range paths {
+				if hasPathPrefix(p, b) || hasPathPrefix(b, p) {
+					return nil
+				}
+			}
+
+			unregistered = append(unregistered, b)
+			return nil
+		})
+	}
+
+	var err error
+	if create {
+		err = db.Update(visit)
+	} else {
+		err = db.View(visit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("path validation experienced error: %w", err)
+	}
+
+	return unregistered, nil
+}
+
+// hasPathPrefix reports whether path begins with the segments of prefix.
+func hasPathPrefix(path, prefix [][]byte) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+
+	for i := range prefix {
+		if !bytes.Equal(path[i], prefix[i]) {
+			return false
+		}
+	}
+
+	return true
+}
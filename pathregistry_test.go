@@ -0,0 +1,50 @@
+package quickbolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_ValidatePaths(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	var log syncBuffer
+	db.AddLog(&log)
+
+	assert.Nil(t, db.RegisterPath("users", []string{"app", "users"}))
+	assert.Nil(t, db.RegisterPath("orders", []string{"app", "orders"}))
+	assert.Nil(t, db.RegisterPath("users", []string{"app", "users"}))
+
+	var duplicate ErrDuplicateValue
+	assert.ErrorAs(t, db.RegisterPath("users", []string{"app", "people"}), &duplicate)
+
+	p, ok := db.RegisteredPath("users")
+	assert.True(t, ok)
+	assert.Equal(t, "app/users", p.String())
+	_, ok = db.RegisteredPath("missing")
+	assert.False(t, ok)
+
+	var missing ErrLocate
+	_, err = db.ValidatePaths(false)
+	assert.ErrorAs(t, err, &missing)
+
+	unregistered, err := db.ValidatePaths(true)
+	assert.Nil(t, err)
+	assert.Empty(t, unregistered)
+
+	// Buckets nested under registered paths are expected, whereas those elsewhere are reported.
+	assert.Nil(t, db.Insert("alice", "1", []string{"app", "users", "active"}))
+	assert.Nil(t, db.Insert("bob", "2", []string{"app", "usres"}))
+	assert.Nil(t, db.Insert("carol", "3", []string{"stray", "nested"}))
+
+	unregistered, err = db.ValidatePaths(false)
+	assert.Nil(t, err)
+	assert.Len(t, unregistered, 2)
+	assert.Equal(t, "app/usres", unregistered[0].String())
+	assert.Equal(t, "stray", unregistered[1].String())
+	assert.Contains(t, log.String(), "app/usres")
+}
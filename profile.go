@@ -0,0 +1,52 @@
+package quickbolt
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+)
+
+// Keys of the pprof labels set when WithProfilerLabels is used.
+const (
+	opLabel   = "quickbolt.op"
+	pathLabel = "quickbolt.path"
+)
+
+// WithProfilerLabels labels the goroutine running each DB method with pprof labels carrying
+// the method's name and, for methods taking a bucket path, the path with segments joined by "/".
+//
+// CPU profiles then attribute time spent within bbolt to the quickbolt operations and buckets responsible.
+// As with pprof.Do called with an empty context, labels already set on the calling goroutine are
+// replaced for the duration of the call and cleared when it returns.
+func WithProfilerLabels() Option {
+	return func(o *options) {
+		o.profilerLabels = true
+	}
+}
+
+// setLabels applies the operation's labels to the current goroutine, including the given path if it is not nil.
+func (o *operation) setLabels(path [][]byte) {
+	labels := []string{opLabel, o.name}
+	if path != nil {
+		labels = append(labels, pathLabel, string(bytes.Join(path, []byte("/"))))
+	}
+
+	pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), pprof.Labels(labels...)))
+}
+
+// labelPath records the given bucket path as the operation's, adding it to the operation's pprof labels
+// if profiler labels are enabled.
+func (o *operation) labelPath(path [][]byte) {
+	o.path = path
+
+	if o.labeled {
+		o.setLabels(path)
+	}
+}
+
+// clearLabels removes the operation's pprof labels from the current goroutine, if profiler labels are enabled.
+func (o *operation) clearLabels() {
+	if o.labeled {
+		pprof.SetGoroutineLabels(context.Background())
+	}
+}
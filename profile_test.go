@@ -0,0 +1,33 @@
+package quickbolt
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithProfilerLabels(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithProfilerLabels())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a", "b"}
+	assert.Nil(t, db.Insert("k", "v", path))
+
+	var profile bytes.Buffer
+	merge := func(a, b []byte) ([]byte, error) {
+		// Merge funcs given to UpsertMany run on the calling goroutine, within the operation.
+		return b, pprof.Lookup("goroutine").WriteTo(&profile, 1)
+	}
+	assert.Nil(t, db.UpsertMany([]Entry{{Key: []byte("k"), Value: []byte("v2")}}, path, merge))
+
+	assert.Contains(t, profile.String(), `"quickbolt.op":"UpsertMany"`)
+	assert.Contains(t, profile.String(), `"quickbolt.path":"a/b"`)
+
+	profile.Reset()
+	assert.Nil(t, pprof.Lookup("goroutine").WriteTo(&profile, 1))
+	assert.NotContains(t, profile.String(), "quickbolt.op", "labels must be cleared once the operation returns")
+}
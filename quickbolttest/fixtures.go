@@ -0,0 +1,181 @@
+// Package quickbolttest provides helpers for testing code built on quickbolt.
+package quickbolttest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Kindred87/quickbolt"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
+)
+
+// maxFixtureWriters limits the number of concurrent inserts while loading fixtures.
+const maxFixtureWriters = 64
+
+// LoadFixtures populates the db from every fixture file in fsys matching the given pattern.
+//
+// Fixture files are YAML, or JSON if their extension is .json. Each file is a mapping in which
+// nested mappings are buckets and any other value is written to its key as is:
+//
+//	users:
+//	  alice: '{"age": 30}'
+//	  bob: '{"age": 25}'
+//	  archived:
+//	    carol: '{"age": 41}'
+//
+// Integers are written in their decimal form. Lists are written as JSON. Empty mappings are created as empty buckets.
+// Keys and string values of the form "base64:" followed by base64-encoded bytes, as written by Golden, are decoded.
+func LoadFixtures(db quickbolt.DB, fsys fs.FS, pattern string) error {
+	if db == nil {
+		return fmt.Errorf("fixture loading received nil db")
+	}
+
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return fmt.Errorf("error while matching fixtures to %s: %w", pattern, err)
+	} else if len(names) == 0 {
+		return fmt.Errorf("no fixtures matched %s", pattern)
+	}
+
+	for _, name := range names {
+		if err := loadFixture(db, fsys, name); err != nil {
+			return fmt.Errorf("error while loading fixture %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func loadFixture(db quickbolt.DB, fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("error while reading file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	if path.Ext(name) == ".json" {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return fmt.Errorf("error while decoding file: %w", err)
+	}
+
+	// Inserts are issued concurrently so the db can coalesce them into shared transactions.
+	var g errgroup.Group
+	g.SetLimit(maxFixtureWriters)
+
+	if err := walkFixture(doc, [][]byte{}, func(key []byte, val interface{}, bucketPath [][]byte) {
+		g.Go(func() error { return db.Insert(key, val, bucketPath) })
+	}, func(bucketPath [][]byte) {
+		g.Go(func() error { return createBucket(db, bucketPath) })
+	}); err != nil {
+		return err
+	}
+
+	return g.Wait()
+}
+
+// walkFixture calls insert for every entry in the given fixture mapping located at the given path,
+// and create for every empty bucket.
+func walkFixture(m map[string]interface{}, bucketPath [][]byte, insert func(key []byte, val interface{}, bucketPath [][]byte), create func(bucketPath [][]byte)) error {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		k, err := decodeFixtureString(name)
+		if err != nil {
+			return fmt.Errorf("error while decoding key %s in %v: %w", name, bucketPath, err)
+		}
+
+		switch v := normalizeFixtureValue(m[name]).(type) {
+		case map[string]interface{}:
+			child := append(append([][]byte{}, bucketPath...), k)
+			if len(v) == 0 {
+				create(child)
+				continue
+			}
+			if err := walkFixture(v, child, insert, create); err != nil {
+				return err
+			}
+		case string:
+			if len(bucketPath) == 0 {
+				return fmt.Errorf("entry %s must be nested within a bucket", k)
+			}
+			b, err := decodeFixtureString(v)
+			if err != nil {
+				return fmt.Errorf("error while decoding value of %s in %v: %w", k, bucketPath, err)
+			}
+			insert(k, b, bucketPath)
+		case int:
+			if len(bucketPath) == 0 {
+				return fmt.Errorf("entry %s must be nested within a bucket", k)
+			}
+			insert(k, v, bucketPath)
+		case []interface{}:
+			if len(bucketPath) == 0 {
+				return fmt.Errorf("entry %s must be nested within a bucket", k)
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("error while encoding %s in %v: %w", k, bucketPath, err)
+			}
+			insert(k, b, bucketPath)
+		case nil:
+			return fmt.Errorf("entry %s in %v has no value", k, bucketPath)
+		default:
+			if len(bucketPath) == 0 {
+				return fmt.Errorf("entry %s must be nested within a bucket", k)
+			}
+			insert(k, fmt.Sprint(v), bucketPath)
+		}
+	}
+
+	return nil
+}
+
+// decodeFixtureString returns the bytes named by s, decoding it if it is of the form written by exportString.
+func decodeFixtureString(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, base64Prefix) {
+		return []byte(s), nil
+	}
+
+	return base64.StdEncoding.DecodeString(strings.TrimPrefix(s, base64Prefix))
+}
+
+// createBucket creates the bucket at the given path if it does not already exist.
+func createBucket(db quickbolt.DB, bucketPath [][]byte) error {
+	if ok, err := db.HasBucket(bucketPath); err != nil || ok {
+		return err
+	}
+
+	return db.InsertBucket(bucketPath[len(bucketPath)-1], bucketPath[:len(bucketPath)-1])
+}
+
+// normalizeFixtureValue converts mappings with non-string keys and whole JSON numbers into the forms walkFixture expects.
+func normalizeFixtureValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			m[fmt.Sprint(k)] = e
+		}
+		return m
+	case float64:
+		if val == float64(int(val)) {
+			return int(val)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,52 @@
+package quickbolttest
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/Kindred87/quickbolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/users.yaml": {Data: []byte(`
+users:
+  alice: '{"age": 30}'
+  archived:
+    carol: 41
+  tags: [a, b]
+`)},
+		"fixtures/orders.json":  {Data: []byte(`{"orders": {"1": "pending"}}`)},
+		"fixtures/invalid.yaml": {Data: []byte(`orphan: value`)},
+	}
+
+	db, err := quickbolt.CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, LoadFixtures(db, fsys, "fixtures/users.yaml"))
+	assert.Nil(t, LoadFixtures(db, fsys, "fixtures/*.json"))
+
+	tests := []struct {
+		key  string
+		path []string
+		want string
+	}{
+		{key: "alice", path: []string{"users"}, want: `{"age": 30}`},
+		{key: "carol", path: []string{"users", "archived"}, want: "41"},
+		{key: "tags", path: []string{"users"}, want: `["a","b"]`},
+		{key: "1", path: []string{"orders"}, want: "pending"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			v, err := db.GetValue(tt.key, tt.path, true)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(v))
+		})
+	}
+
+	assert.NotNil(t, LoadFixtures(db, fsys, "fixtures/invalid.yaml"))
+	assert.NotNil(t, LoadFixtures(db, fsys, "fixtures/*.xml"))
+}
@@ -0,0 +1,116 @@
+package quickbolttest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Kindred87/quickbolt"
+	"github.com/stretchr/testify/assert"
+)
+
+// update rewrites golden files rather than comparing against them, e.g. go test ./... -quickbolttest.update
+var update = flag.Bool("quickbolttest.update", false, "rewrite golden files with the current db contents")
+
+// base64Prefix marks base64-encoded keys and values in exported subtrees and fixtures.
+const base64Prefix = "base64:"
+
+// Golden compares the subtree of the db at the given path against the golden file, failing the test if they differ.
+// If the -quickbolttest.update flag is set, the golden file is written instead.
+//
+// The subtree is exported as indented JSON in the fixture format read by LoadFixtures, with nested buckets
+// as objects and values as strings, so golden files can double as fixtures. Keys are sorted, and keys and
+// values that are not valid UTF-8, or that begin with "base64:" themselves, are written as "base64:" followed by
+// their base64 encoding, which LoadFixtures decodes. Empty buckets are written as empty objects.
+func Golden(t testing.TB, db quickbolt.DB, bucketPath []string, file string) {
+	t.Helper()
+
+	got, err := ExportJSON(db, bucketPath)
+	if err != nil {
+		t.Fatalf("golden export of %v experienced error: %v", bucketPath, err)
+	}
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatalf("error while creating directory for golden file %s: %v", file, err)
+		}
+		if err := os.WriteFile(file, got, 0o644); err != nil {
+			t.Fatalf("error while writing golden file %s: %v", file, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("error while reading golden file %s, which may be created with -quickbolttest.update: %v", file, err)
+	}
+
+	assert.Equal(t, string(want), string(got), "db contents at %v differ from golden file %s", bucketPath, file)
+}
+
+// ExportJSON returns the subtree of the db at the given path as Golden writes it.
+func ExportJSON(db quickbolt.DB, bucketPath []string) ([]byte, error) {
+	if db == nil {
+		return nil, fmt.Errorf("export received nil db")
+	}
+
+	root := make(map[string]interface{})
+
+	buckets := make(chan [][]byte)
+	errc := make(chan error, 1)
+	go func() { errc <- db.BucketsAtRecursive(bucketPath, true, buckets) }()
+	for p := range buckets {
+		exportBucket(root, p[len(bucketPath):])
+	}
+	if err := <-errc; err != nil {
+		return nil, fmt.Errorf("error while scanning buckets: %w", err)
+	}
+
+	entries := make(chan quickbolt.PathedEntry)
+	go func() { errc <- db.EntriesAtRecursive(bucketPath, true, entries) }()
+	for e := range entries {
+		exportBucket(root, e.Path[len(bucketPath):])[exportString(e.Key)] = exportString(e.Value)
+	}
+	if err := <-errc; err != nil {
+		return nil, fmt.Errorf("error while scanning entries: %w", err)
+	}
+
+	b, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error while encoding JSON: %w", err)
+	}
+
+	return append(b, '\n'), nil
+}
+
+// exportBucket returns the object of the bucket at the given path within root, creating objects as needed.
+func exportBucket(root map[string]interface{}, path [][]byte) map[string]interface{} {
+	m := root
+	for _, p := range path {
+		k := exportString(p)
+		child, ok := m[k].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			m[k] = child
+		}
+		m = child
+	}
+
+	return m
+}
+
+// exportString returns b as a string if it is valid UTF-8, or base64-encoded behind base64Prefix otherwise
+// or if the string would itself begin with base64Prefix.
+func exportString(b []byte) string {
+	if utf8.Valid(b) && !bytes.HasPrefix(b, []byte(base64Prefix)) {
+		return string(b)
+	}
+
+	return base64Prefix + base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,106 @@
+package quickbolttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kindred87/quickbolt"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingT records failures rather than failing the test.
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingT) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func TestGolden(t *testing.T) {
+	// The checked-in golden file must not be rewritten by this test.
+	defer func(u bool) { *update = u }(*update)
+	*update = false
+
+	db, err := quickbolt.CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("alice", `{"age": 30}`, []string{"app", "users"}))
+	assert.Nil(t, db.Insert("carol", "41", []string{"app", "users", "archived"}))
+	assert.Nil(t, db.Insert([]byte{0xff}, []byte{0xfe, 0x00}, []string{"app", "blobs"}))
+	assert.Nil(t, db.InsertBucket("empty", []string{"app"}))
+	assert.Nil(t, db.Insert("other", "excluded", []string{"elsewhere"}))
+
+	Golden(t, db, []string{"app"}, "testdata/golden.json")
+
+	assert.Nil(t, db.Insert("bob", "25", []string{"app", "users"}))
+	r := &recordingT{TB: t}
+	Golden(r, db, []string{"app"}, "testdata/golden.json")
+	assert.Len(t, r.failures, 1)
+
+	file := filepath.Join(t.TempDir(), "nested", "golden.json")
+	*update = true
+	Golden(t, db, []string{"app"}, file)
+	*update = false
+
+	written, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Contains(t, string(written), `"bob": "25"`)
+	Golden(t, db, []string{"app"}, file)
+
+	// Golden files are valid fixtures.
+	loaded, err := quickbolt.CreateWith("bar.db", t.TempDir())
+	assert.Nil(t, err)
+	defer loaded.RemoveFile()
+
+	assert.Nil(t, LoadFixtures(loaded, os.DirFS("testdata"), "golden.json"))
+	v, err := loaded.GetValue("carol", []string{"users", "archived"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "41", string(v))
+
+	// Loading a golden file restores binary pairs and empty buckets, so it exports unchanged.
+	v, err = loaded.GetValue([]byte{0xff}, []string{"blobs"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xfe, 0x00}, v)
+
+	want, err := os.ReadFile("testdata/golden.json")
+	assert.Nil(t, err)
+	got, err := ExportJSON(loaded, []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, string(want), string(got))
+}
+
+func TestExportJSON_Base64Prefix(t *testing.T) {
+	db, err := quickbolt.CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("base64:key", "base64:value", []string{"app"}))
+
+	b, err := ExportJSON(db, []string{})
+	assert.Nil(t, err)
+
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "fixture.json"), b, 0o644))
+
+	loaded, err := quickbolt.CreateWith("bar.db", t.TempDir())
+	assert.Nil(t, err)
+	defer loaded.RemoveFile()
+
+	assert.Nil(t, LoadFixtures(loaded, os.DirFS(dir), "fixture.json"))
+	v, err := loaded.GetValue("base64:key", []string{"app"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "base64:value", string(v))
+}
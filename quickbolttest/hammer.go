@@ -0,0 +1,295 @@
+package quickbolttest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kindred87/quickbolt"
+	"golang.org/x/sync/errgroup"
+)
+
+// HammerConfig configures a Hammer run. Zero fields take the defaults described.
+type HammerConfig struct {
+	// BucketPath is the bucket the run writes to and iterates over, which should be empty beforehand.
+	//
+	// The default is ["hammer"].
+	BucketPath []string
+	// Workers is the number of goroutines issuing operations concurrently.
+	//
+	// The default is 8.
+	Workers int
+	// Ops is the number of operations issued by each worker.
+	//
+	// The default is 1000.
+	Ops int
+	// Keys is the number of keys each worker writes to.
+	//
+	// The default is 64.
+	Keys int
+	// Reads, Writes, Deletes, and Iterations weight how often each kind of operation is chosen.
+	// A read gets a single key, and an iteration scans every entry in the bucket.
+	//
+	// If all are zero, the weights are 4, 4, 1, and 1 respectively.
+	Reads, Writes, Deletes, Iterations int
+	// Seed seeds the choice of operations and keys, so that a run can be repeated.
+	//
+	// The default is the time the run starts.
+	Seed int64
+	// Check, if not nil, is called once the run ends to verify invariants of the application's own.
+	Check func(db quickbolt.DB) error
+}
+
+// HammerReport counts the operations issued by a Hammer run.
+type HammerReport struct {
+	Reads, Writes, Deletes, Iterations int
+	// Retryable is the number of operations that failed with an error for which quickbolt.IsRetryable is true,
+	// such as buffer timeouts. Such failures don't end the run but may indicate a regression.
+	Retryable int
+	Elapsed   time.Duration
+	// Seed is the seed the run used.
+	Seed int64
+}
+
+// Hammer issues a mix of concurrent reads, writes, deletes, and iterations against the db, then checks
+// that its contents match those expected.
+//
+// Each worker writes to keys of its own, so every read can check that it sees the worker's last write
+// to the key, and every iteration can check that each entry it sees is well-formed.
+// Once the workers are done, the bucket must hold exactly the values last written by each,
+// after which cfg.Check is called, if set.
+//
+// An error is returned if an operation fails with a non-retryable error or an invariant is violated.
+func Hammer(db quickbolt.DB, cfg HammerConfig) (HammerReport, error) {
+	if db == nil {
+		return HammerReport{}, fmt.Errorf("hammering received nil db")
+	}
+
+	cfg = hammerDefaults(cfg)
+	report := HammerReport{Seed: cfg.Seed}
+	start := time.Now()
+
+	var mu sync.Mutex
+	models := make([]map[string]string, cfg.Workers)
+	uncertain := make(map[string]bool)
+
+	var g errgroup.Group
+	for w := 0; w < cfg.Workers; w++ {
+		w := w
+		g.Go(func() error {
+			h := hammerWorker{db: db, cfg: cfg, id: w, rng: rand.New(rand.NewSource(cfg.Seed + int64(w))), model: make(map[string]string), uncertain: make(map[string]bool)}
+			err := h.run()
+
+			mu.Lock()
+			defer mu.Unlock()
+			models[w] = h.model
+			for k := range h.uncertain {
+				uncertain[k] = true
+			}
+			report.Reads += h.report.Reads
+			report.Writes += h.report.Writes
+			report.Deletes += h.report.Deletes
+			report.Iterations += h.report.Iterations
+			report.Retryable += h.report.Retryable
+
+			return err
+		})
+	}
+
+	err := g.Wait()
+	report.Elapsed = time.Since(start)
+	if err != nil {
+		return report, err
+	}
+
+	want := make(map[string]string)
+	for _, m := range models {
+		for k, v := range m {
+			want[k] = v
+		}
+	}
+
+	if err := hammerVerify(db, cfg.BucketPath, want, uncertain); err != nil {
+		return report, fmt.Errorf("hammering with seed %d left db in unexpected state: %w", cfg.Seed, err)
+	}
+
+	if cfg.Check != nil {
+		if err := cfg.Check(db); err != nil {
+			return report, fmt.Errorf("hammering with seed %d failed check: %w", cfg.Seed, err)
+		}
+	}
+
+	return report, nil
+}
+
+// hammerDefaults returns the config with its zero fields set to their defaults.
+func hammerDefaults(cfg HammerConfig) HammerConfig {
+	if cfg.BucketPath == nil {
+		cfg.BucketPath = []string{"hammer"}
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 8
+	}
+	if cfg.Ops <= 0 {
+		cfg.Ops = 1000
+	}
+	if cfg.Keys <= 0 {
+		cfg.Keys = 64
+	}
+	if cfg.Reads <= 0 && cfg.Writes <= 0 && cfg.Deletes <= 0 && cfg.Iterations <= 0 {
+		cfg.Reads, cfg.Writes, cfg.Deletes, cfg.Iterations = 4, 4, 1, 1
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+
+	return cfg
+}
+
+// hammerWorker issues the operations of a single Hammer worker.
+type hammerWorker struct {
+	db  quickbolt.DB
+	cfg HammerConfig
+	id  int
+	rng *rand.Rand
+	// model holds the value last written to each of the worker's keys, or is missing keys deleted or never written.
+	model map[string]string
+	// uncertain holds the keys whose last write or delete failed, which may or may not have taken effect.
+	uncertain map[string]bool
+	report    HammerReport
+}
+
+func (h *hammerWorker) run() error {
+	total := h.cfg.Reads + h.cfg.Writes + h.cfg.Deletes + h.cfg.Iterations
+
+	for i := 0; i < h.cfg.Ops; i++ {
+		key := fmt.Sprintf("w%d-k%d", h.id, h.rng.Intn(h.cfg.Keys))
+
+		var err error
+		switch n := h.rng.Intn(total); {
+		case n < h.cfg.Reads:
+			h.report.Reads++
+			err = h.read(key)
+		case n < h.cfg.Reads+h.cfg.Writes:
+			h.report.Writes++
+			err = h.write(key, i)
+		case n < h.cfg.Reads+h.cfg.Writes+h.cfg.Deletes:
+			h.report.Deletes++
+			err = h.delete(key)
+		default:
+			h.report.Iterations++
+			err = h.iterate()
+		}
+
+		if quickbolt.IsRetryable(err) {
+			h.report.Retryable++
+		} else if err != nil {
+			return fmt.Errorf("worker %d of hammering with seed %d failed at op %d: %w", h.id, h.cfg.Seed, i, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *hammerWorker) read(key string) error {
+	v, err := h.db.GetValue(key, h.cfg.BucketPath, false)
+	if err != nil {
+		return err
+	}
+
+	want, ok := h.model[key]
+	if h.uncertain[key] {
+		return nil
+	} else if !ok && v != nil {
+		return fmt.Errorf("read %s as %s after it was deleted", key, v)
+	} else if ok && string(v) != want {
+		return fmt.Errorf("read %s as %s rather than %s", key, v, want)
+	}
+
+	return nil
+}
+
+func (h *hammerWorker) write(key string, op int) error {
+	v := fmt.Sprintf("%s#%d", key, op)
+
+	if err := h.db.Insert(key, v, h.cfg.BucketPath); err != nil {
+		h.forget(key)
+		return err
+	}
+
+	h.model[key] = v
+	delete(h.uncertain, key)
+	return nil
+}
+
+func (h *hammerWorker) delete(key string) error {
+	if err := h.db.Delete(key, h.cfg.BucketPath); err != nil {
+		h.forget(key)
+		return err
+	}
+
+	delete(h.model, key)
+	delete(h.uncertain, key)
+	return nil
+}
+
+// forget marks the key as uncertain after a failed write or delete, which may have taken effect regardless,
+// e.g. if only the wait for its result timed out.
+func (h *hammerWorker) forget(key string) {
+	delete(h.model, key)
+	h.uncertain[key] = true
+}
+
+// iterate scans the bucket, checking that every entry's value was written to its key.
+func (h *hammerWorker) iterate() error {
+	buffer := make(chan [2][]byte)
+	errc := make(chan error, 1)
+	go func() { errc <- h.db.EntriesAt(h.cfg.BucketPath, false, buffer) }()
+
+	var malformed error
+	for e := range buffer {
+		if malformed == nil && !strings.HasPrefix(string(e[1]), string(e[0])+"#") {
+			malformed = fmt.Errorf("iteration found %s paired with %s", e[0], e[1])
+		}
+	}
+
+	if err := <-errc; err != nil {
+		return err
+	}
+	return malformed
+}
+
+// hammerVerify checks that the bucket at the given path holds exactly the given entries,
+// aside from the uncertain keys, which may hold any value written to them or none.
+func hammerVerify(db quickbolt.DB, bucketPath []string, want map[string]string, uncertain map[string]bool) error {
+	buffer := make(chan [2][]byte)
+	errc := make(chan error, 1)
+	go func() { errc <- db.EntriesAt(bucketPath, false, buffer) }()
+
+	seen := 0
+	var mismatch error
+	for e := range buffer {
+		if uncertain[string(e[0])] {
+			continue
+		}
+
+		seen++
+		if v, ok := want[string(e[0])]; mismatch == nil && !ok {
+			mismatch = fmt.Errorf("found %s, which was deleted or never written", e[0])
+		} else if mismatch == nil && v != string(e[1]) {
+			mismatch = fmt.Errorf("found %s paired with %s rather than %s", e[0], e[1], v)
+		}
+	}
+
+	if err := <-errc; err != nil {
+		return fmt.Errorf("error while scanning bucket: %w", err)
+	} else if mismatch != nil {
+		return mismatch
+	} else if seen != len(want) {
+		return fmt.Errorf("found %d entries rather than %d", seen, len(want))
+	}
+
+	return nil
+}
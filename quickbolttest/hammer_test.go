@@ -0,0 +1,35 @@
+package quickbolttest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Kindred87/quickbolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHammer(t *testing.T) {
+	db, err := quickbolt.CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	checked := false
+	report, err := Hammer(db, HammerConfig{Workers: 4, Ops: 200, Keys: 16, Seed: 1, Check: func(db quickbolt.DB) error {
+		checked = true
+		return nil
+	}})
+	assert.Nil(t, err)
+	assert.True(t, checked)
+	assert.Equal(t, int64(1), report.Seed)
+	assert.Equal(t, 4*200, report.Reads+report.Writes+report.Deletes+report.Iterations)
+	assert.Greater(t, report.Writes, report.Deletes)
+	assert.Zero(t, report.Retryable)
+
+	broken := errors.New("broken")
+	_, err = Hammer(db, HammerConfig{Workers: 2, Ops: 10, BucketPath: []string{"other"}, Check: func(db quickbolt.DB) error { return broken }})
+	assert.ErrorIs(t, err, broken)
+
+	_, err = Hammer(nil, HammerConfig{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,20 @@
+package quickbolttest
+
+import (
+	"testing"
+
+	"github.com/Kindred87/quickbolt"
+)
+
+// AssertInvariants fails the test if the db violates any of the given invariants, or any registered via
+// quickbolt.DB.AddInvariant, as checked by quickbolt.DB.Verify.
+func AssertInvariants(t testing.TB, db quickbolt.DB, invariants ...quickbolt.Invariant) bool {
+	t.Helper()
+
+	if err := db.Verify(invariants...); err != nil {
+		t.Errorf("db violates invariant: %v", err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,23 @@
+package quickbolttest
+
+import (
+	"testing"
+
+	"github.com/Kindred87/quickbolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssertInvariants(t *testing.T) {
+	db, err := quickbolt.CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("alice", `{"age": 30}`, []string{"users"}))
+	assert.True(t, AssertInvariants(t, db, quickbolt.DecodesAs[map[string]int]([]string{"users"})))
+
+	assert.Nil(t, db.Insert("bob", "not json", []string{"users"}))
+	r := &recordingT{TB: t}
+	assert.False(t, AssertInvariants(r, db, quickbolt.DecodesAs[map[string]int]([]string{"users"})))
+	assert.Len(t, r.failures, 1)
+}
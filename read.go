@@ -2,26 +2,49 @@ package quickbolt
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"time"
+	"regexp"
+	"regexp/syntax"
 
 	"go.etcd.io/bbolt"
 )
 
-// getValue returns the value paired with the given key.
+// GetOpts configures a value retrieval.
+type GetOpts struct {
+	// MustExist causes an error to be returned if the key could not be found.
+	MustExist bool
+	// Default is returned in place of a missing value. It is ignored if MustExist is true.
+	Default []byte
+	// NoCopy returns a slice referencing the memory-mapped db file instead of a copy of the value.
+	//
+	// The slice is only valid until the file is next remapped or unmapped, such as by a write growing
+	// the file, by Compact, or by closing the db, after which reading it may crash the process.
+	// It must not be retained or modified.
+	NoCopy bool
+	// Consistent performs the read within a read-write transaction, so it observes every write
+	// committed or pending before it, at the cost of waiting for exclusive access to the db.
+	Consistent bool
+}
+
+// getValue returns a copy of the value paired with the given key.
 // The returned value will be nil if the key could not be found.
 //
 // If mustExist is true, an error will be returned if the key could not be found.
 func getValue(db *bbolt.DB, key []byte, path [][]byte, mustExist bool) ([]byte, error) {
+	return getValueWith(db, key, path, GetOpts{MustExist: mustExist})
+}
+
+// getValueWith returns the value paired with the given key, as configured by opts.
+func getValueWith(db *bbolt.DB, key []byte, path [][]byte, opts GetOpts) ([]byte, error) {
 	if db == nil {
-		c := withCallerInfo(fmt.Sprintf("value retrieval for %s", key), 3)
-		return nil, fmt.Errorf("%s received nil db", c)
+		return nil, fmt.Errorf("value retrieval for %s received nil db", key)
 	}
 
 	var value []byte
 
-	err := db.View(func(tx *bbolt.Tx) error {
-		bkt, err := getBucket(tx, path, mustExist)
+	read := func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, opts.MustExist)
 		if err != nil {
 			return fmt.Errorf("error while navigating path: %w", err)
 		} else if bkt == nil {
@@ -29,24 +52,65 @@ func getValue(db *bbolt.DB, key []byte, path [][]byte, mustExist bool) ([]byte,
 		}
 
 		value = bkt.Get(key)
-		if value == nil && mustExist {
+		if value == nil && opts.MustExist {
 			return newErrLocate(fmt.Sprintf("key %s at %s", string(key), path))
 		}
 
+		if !opts.NoCopy {
+			value = copyBytes(value)
+		}
+
 		return nil
-	})
+	}
+
+	var err error
+	if opts.Consistent {
+		err = db.Update(read)
+	} else {
+		err = db.View(read)
+	}
 
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("value retrieval for %s", key), 3)
-		return nil, fmt.Errorf("%s experienced error while reading value: %w", c, err)
+		return nil, fmt.Errorf("value retrieval for %s experienced error while reading value: %w", key, err)
+	}
+
+	if value == nil {
+		return opts.Default, nil
 	}
 	return value, nil
 }
 
+// getValueOK returns a copy of the value paired with the given key and whether the key was found.
+//
+// Unlike getValue, a zero-length value is distinguished from a missing key.
+func getValueOK(db *bbolt.DB, key []byte, path [][]byte) ([]byte, bool, error) {
+	if db == nil {
+		return nil, false, fmt.Errorf("value retrieval for %s received nil db", key)
+	}
+
+	var value []byte
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		value = copyBytes(bkt.Get(key))
+		return nil
+	})
+
+	if err != nil {
+		return nil, false, fmt.Errorf("value retrieval for %s experienced error while reading value: %w", key, err)
+	}
+	return value, value != nil, nil
+}
+
 func getKey(db *bbolt.DB, value []byte, path [][]byte, mustExist bool) ([]byte, error) {
 	if db == nil {
-		c := withCallerInfo(fmt.Sprintf("key retrieval for %s", value), 3)
-		return nil, fmt.Errorf("%s received nil db", c)
+		return nil, fmt.Errorf("key retrieval for %s received nil db", value)
 	}
 
 	var key []byte
@@ -63,7 +127,7 @@ func getKey(db *bbolt.DB, value []byte, path [][]byte, mustExist bool) ([]byte,
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			if bytes.Equal(v, value) {
-				key = k
+				key = copyBytes(k)
 				return nil
 			}
 		}
@@ -76,16 +140,14 @@ func getKey(db *bbolt.DB, value []byte, path [][]byte, mustExist bool) ([]byte,
 	})
 
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("key retrieval for %s", value), 3)
-		return nil, fmt.Errorf("%s experienced error while getting value: %w", c, err)
+		return nil, fmt.Errorf("key retrieval for %s experienced error while getting value: %w", value, err)
 	}
 	return key, nil
 }
 
 func getKeys(db *bbolt.DB, value []byte, path [][]byte, mustExist bool) ([][]byte, error) {
 	if db == nil {
-		c := withCallerInfo(fmt.Sprintf("multiple key retrieval for %s", value), 3)
-		return nil, fmt.Errorf("%s received nil db", c)
+		return nil, fmt.Errorf("multiple key retrieval for %s received nil db", value)
 	}
 
 	var keys [][]byte
@@ -102,7 +164,7 @@ func getKeys(db *bbolt.DB, value []byte, path [][]byte, mustExist bool) ([][]byt
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			if bytes.Equal(v, value) {
-				keys = append(keys, k)
+				keys = append(keys, copyBytes(k))
 			}
 		}
 
@@ -114,8 +176,7 @@ func getKeys(db *bbolt.DB, value []byte, path [][]byte, mustExist bool) ([][]byt
 	})
 
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("multiple key retrieval for %s", value), 3)
-		return nil, fmt.Errorf("%s experienced error while getting value: %w", c, err)
+		return nil, fmt.Errorf("multiple key retrieval for %s experienced error while getting value: %w", value, err)
 	}
 	return keys, nil
 }
@@ -145,8 +206,7 @@ func getBucket(tx *bbolt.Tx, path [][]byte, mustExist bool) (*bbolt.Bucket, erro
 // If mustExist is true, an error will be returned if the key could not be found.
 func getFirstKeyAt(db *bbolt.DB, path [][]byte, mustExist bool) ([]byte, error) {
 	if db == nil {
-		c := withCallerInfo(fmt.Sprintf("first key retrieval for %s", path), 3)
-		return nil, fmt.Errorf("%s received nil db", c)
+		return nil, fmt.Errorf("first key retrieval for %s received nil db", path)
 	}
 
 	var key []byte
@@ -160,7 +220,8 @@ func getFirstKeyAt(db *bbolt.DB, path [][]byte, mustExist bool) ([]byte, error)
 		}
 
 		c := bkt.Cursor()
-		key, _ = c.First()
+		k, _ := c.First()
+		key = copyBytes(k)
 
 		if key == nil && mustExist {
 			return newErrLocate(fmt.Sprintf("first key at %#v", path))
@@ -170,20 +231,71 @@ func getFirstKeyAt(db *bbolt.DB, path [][]byte, mustExist bool) ([]byte, error)
 	})
 
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("first key retrieval for %s", path), 3)
-		return nil, fmt.Errorf("%s experienced error while scanning keys: %w", c, err)
+		return nil, fmt.Errorf("first key retrieval for %s experienced error while scanning keys: %w", path, err)
 	}
 
 	return key, nil
 }
 
+// getEdgeEntryAt returns the first key-value pair at the given path, or the last if last is true.
+// Nested buckets are skipped.
+//
+// If mustExist is true, an error will be returned if the pair could not be found.
+func getEdgeEntryAt(db *bbolt.DB, path [][]byte, mustExist, last bool) (Entry, error) {
+	edge := "first"
+	if last {
+		edge = "last"
+	}
+
+	if db == nil {
+		return Entry{}, fmt.Errorf("%s entry retrieval for %s received nil db", edge, path)
+	}
+
+	var e Entry
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, mustExist)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		c := bkt.Cursor()
+		k, v := c.First()
+		next := c.Next
+		if last {
+			k, v = c.Last()
+			next = c.Prev
+		}
+
+		for k != nil && v == nil {
+			k, v = next()
+		}
+
+		if k == nil {
+			if mustExist {
+				return newErrLocate(fmt.Sprintf("%s pair at %#v", edge, path))
+			}
+			return nil
+		}
+
+		e = Entry{Key: copyBytes(k), Value: copyBytes(v)}
+		return nil
+	})
+
+	if err != nil {
+		return Entry{}, fmt.Errorf("%s entry retrieval for %s experienced error while scanning entries: %w", edge, path, err)
+	}
+
+	return e, nil
+}
+
 func valuesAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan []byte, dbWrap dbWrapper) error {
 	if db == nil {
-		c := withCallerInfo(fmt.Sprintf("value iteration at %s", path), 3)
-		return fmt.Errorf("%s received nil db", c)
+		return fmt.Errorf("value iteration at %s received nil db", path)
 	} else if buffer == nil {
-		c := withCallerInfo(fmt.Sprintf("value iteration at %s", path), 3)
-		return fmt.Errorf("%s received nil channel", c)
+		return fmt.Errorf("value iteration at %s received nil channel", path)
 	}
 
 	defer close(buffer)
@@ -199,15 +311,7 @@ func valuesAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan []byte, d
 		c := bkt.Cursor()
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
-			timer := time.NewTimer(dbWrap.bufferTimeout)
-			select {
-			case buffer <- v:
-				timer.Stop()
-			case <-timer.C:
-				err := newErrTimeout("value iteration", "waiting to send to buffer")
-				logMutex.Lock()
-				dbWrap.logger.Err(err).Msg("")
-				logMutex.Unlock()
+			if err := sendTo(buffer, copyBytes(v), dbWrap, "value iteration", path, k); err != nil {
 				return err
 			}
 		}
@@ -216,8 +320,7 @@ func valuesAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan []byte, d
 	})
 
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("value iteration at %s", path), 3)
-		return fmt.Errorf("%s experienced error while scanning db: %w", c, err)
+		return fmt.Errorf("value iteration at %s experienced error while scanning db: %w", path, err)
 	}
 
 	return nil
@@ -225,11 +328,9 @@ func valuesAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan []byte, d
 
 func keysAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan []byte, dbWrap dbWrapper) error {
 	if db == nil {
-		c := withCallerInfo(fmt.Sprintf("key iteration at %s", path), 3)
-		return fmt.Errorf("%s received nil db", c)
+		return fmt.Errorf("key iteration at %s received nil db", path)
 	} else if buffer == nil {
-		c := withCallerInfo(fmt.Sprintf("key iteration at %s", path), 3)
-		return fmt.Errorf("%s received nil channel", c)
+		return fmt.Errorf("key iteration at %s received nil channel", path)
 	}
 
 	defer close(buffer)
@@ -249,15 +350,188 @@ func keysAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan []byte, dbW
 				continue
 			}
 
-			timer := time.NewTimer(dbWrap.bufferTimeout)
-			select {
-			case buffer <- k:
-				timer.Stop()
-			case <-timer.C:
-				err := newErrTimeout("quickbolt key retrieval", "waiting to send to buffer")
-				logMutex.Lock()
-				dbWrap.logger.Err(err).Msg("")
-				logMutex.Unlock()
+			if err := sendTo(buffer, copyBytes(k), dbWrap, "quickbolt key retrieval", path, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("key iteration at %s experienced error while scanning keys: %w", path, err)
+	}
+	return nil
+}
+
+// countAt returns the number of key-value pairs at the given path, not counting nested buckets.
+func countAt(db *bbolt.DB, path [][]byte) (int, error) {
+	if db == nil {
+		return 0, fmt.Errorf("counting at %s received nil db", path)
+	}
+
+	n := 0
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		c := bkt.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v != nil {
+				n++
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("counting at %s experienced error while scanning keys: %w", path, err)
+	}
+
+	return n, nil
+}
+
+// hasKey reports whether the key is paired with a value at the given path.
+func hasKey(db *bbolt.DB, key []byte, path [][]byte) (bool, error) {
+	if db == nil {
+		return false, fmt.Errorf("key lookup for %s received nil db", key)
+	}
+
+	found := false
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		found = bkt.Get(key) != nil
+		return nil
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("key lookup for %s experienced error: %w", key, err)
+	}
+
+	return found, nil
+}
+
+// hasBucket reports whether a bucket exists at the given path.
+func hasBucket(db *bbolt.DB, path [][]byte) (bool, error) {
+	if db == nil {
+		return false, fmt.Errorf("bucket lookup for %s received nil db", path)
+	}
+
+	found := false
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		found = bkt != nil
+		return nil
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("bucket lookup for %s experienced error: %w", path, err)
+	}
+
+	return found, nil
+}
+
+// entriesPage returns up to limit key-value pairs at the given path, starting after the given key,
+// along with the key to pass as after to fetch the next page, or nil if there are no more pairs.
+func entriesPage(db *bbolt.DB, path [][]byte, after []byte, limit int) ([][2][]byte, []byte, error) {
+	if db == nil {
+		return nil, nil, fmt.Errorf("paged retrieval at %s received nil db", path)
+	} else if limit <= 0 {
+		return nil, nil, fmt.Errorf("paged retrieval at %s received non-positive limit %d", path, limit)
+	}
+
+	var page [][2][]byte
+	var next []byte
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		c := bkt.Cursor()
+
+		k, v := c.First()
+		if after != nil {
+			k, v = c.Seek(after)
+			if k != nil && bytes.Equal(k, after) {
+				k, v = c.Next()
+			}
+		}
+
+		for ; k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+
+			if len(page) == limit {
+				next = copyBytes(page[len(page)-1][0])
+				break
+			}
+
+			page = append(page, [2][]byte{copyBytes(k), copyBytes(v)})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("paged retrieval at %s experienced error while scanning entries: %w", path, err)
+	}
+
+	return page, next, nil
+}
+
+// keysMatching sends the keys at the given path matched by the regular expression to the buffer, in key order.
+//
+// If the expression is anchored to the start of the key by a literal prefix, only the keys with that prefix are scanned.
+func keysMatching(db *bbolt.DB, path [][]byte, re *regexp.Regexp, buffer chan []byte, dbWrap dbWrapper) error {
+	if db == nil {
+		return fmt.Errorf("key matching at %s received nil db", path)
+	} else if buffer == nil {
+		return fmt.Errorf("key matching at %s received nil channel", path)
+	} else if re == nil {
+		return fmt.Errorf("key matching at %s received nil regexp", path)
+	}
+
+	defer close(buffer)
+
+	prefix := anchoredPrefix(re)
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		c := bkt.Cursor()
+
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if v == nil || !re.Match(k) {
+				continue
+			}
+
+			if err := sendTo(buffer, copyBytes(k), dbWrap, "quickbolt key matching", path, k); err != nil {
 				return err
 			}
 		}
@@ -265,19 +539,79 @@ func keysAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan []byte, dbW
 	})
 
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("key iteration at %s", path), 3)
-		return fmt.Errorf("%s experienced error while scanning keys: %w", c, err)
+		return fmt.Errorf("key matching at %s experienced error while scanning keys: %w", path, err)
+	}
+	return nil
+}
+
+// anchoredPrefix returns the literal prefix of every key the expression matches, or nil if the expression
+// isn't anchored to the start of the text.
+func anchoredPrefix(re *regexp.Regexp) []byte {
+	prefix, _ := re.LiteralPrefix()
+	if prefix == "" {
+		return nil
+	}
+
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	if parsed.Op == syntax.OpConcat && len(parsed.Sub) > 0 {
+		parsed = parsed.Sub[0]
+	}
+	if parsed.Op != syntax.OpBeginText {
+		return nil
+	}
+
+	return []byte(prefix)
+}
+
+// Child is a key or nested bucket within a bucket.
+type Child struct {
+	Name []byte
+	// IsBucket is true if the child is a nested bucket rather than a key.
+	IsBucket bool
+}
+
+// childrenAt sends the keys and nested bucket names at the given path to the buffer, in key order.
+func childrenAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan Child, dbWrap dbWrapper) error {
+	if db == nil {
+		return fmt.Errorf("child iteration at %s received nil db", path)
+	} else if buffer == nil {
+		return fmt.Errorf("child iteration at %s received nil channel", path)
+	}
+
+	defer close(buffer)
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, mustExist)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		c := bkt.Cursor()
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if err := sendTo(buffer, Child{Name: copyBytes(k), IsBucket: v == nil}, dbWrap, "quickbolt child retrieval", path, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("child iteration at %s experienced error while scanning keys: %w", path, err)
 	}
 	return nil
 }
 
 func entriesAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan [2][]byte, dbWrap dbWrapper) error {
 	if db == nil {
-		c := withCallerInfo(fmt.Sprintf("key-value iteration at %s", path), 3)
-		return fmt.Errorf("%s received nil db", c)
+		return fmt.Errorf("key-value iteration at %s received nil db", path)
 	} else if buffer == nil {
-		c := withCallerInfo(fmt.Sprintf("key-value iteration at %s", path), 3)
-		return fmt.Errorf("%s received nil channel", c)
+		return fmt.Errorf("key-value iteration at %s received nil channel", path)
 	}
 
 	defer close(buffer)
@@ -297,15 +631,7 @@ func entriesAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan [2][]byt
 				continue
 			}
 
-			timer := time.NewTimer(dbWrap.bufferTimeout)
-			select {
-			case buffer <- [2][]byte{k, v}:
-				timer.Stop()
-			case <-timer.C:
-				err := newErrTimeout("quickbolt key scanning", "waiting to send to buffer")
-				logMutex.Lock()
-				dbWrap.logger.Err(err).Msg("")
-				logMutex.Unlock()
+			if err := sendTo(buffer, [2][]byte{copyBytes(k), copyBytes(v)}, dbWrap, "quickbolt key scanning", path, k); err != nil {
 				return err
 			}
 		}
@@ -313,19 +639,92 @@ func entriesAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan [2][]byt
 	})
 
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("key-value iteration at %s", path), 3)
-		return fmt.Errorf("%s experienced error while scanning keys: %w", c, err)
+		return fmt.Errorf("key-value iteration at %s experienced error while scanning keys: %w", path, err)
+	}
+	return nil
+}
+
+// forEachEntry calls fn with each key-value pair at the given path, stopping at the first error fn returns.
+//
+// If fn returns ErrStop, iteration ends without error.
+func forEachEntry(db *bbolt.DB, path [][]byte, fn func(k, v []byte) error) error {
+	if db == nil {
+		return fmt.Errorf("key-value iteration at %s received nil db", path)
+	} else if fn == nil {
+		return fmt.Errorf("key-value iteration at %s received nil func", path)
+	}
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		c := bkt.Cursor()
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+
+			if err := fn(k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, ErrStop) {
+		return fmt.Errorf("key-value iteration at %s experienced error while visiting entries: %w", path, err)
+	}
+	return nil
+}
+
+// forEachKey calls fn with each key at the given path, stopping at the first error fn returns.
+//
+// If fn returns ErrStop, iteration ends without error.
+func forEachKey(db *bbolt.DB, path [][]byte, fn func(k []byte) error) error {
+	if db == nil {
+		return fmt.Errorf("key iteration at %s received nil db", path)
+	} else if fn == nil {
+		return fmt.Errorf("key iteration at %s received nil func", path)
+	}
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		c := bkt.Cursor()
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+
+			if err := fn(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, ErrStop) {
+		return fmt.Errorf("key iteration at %s experienced error while visiting keys: %w", path, err)
 	}
 	return nil
 }
 
 func bucketsAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan []byte, dbWrap dbWrapper) error {
 	if db == nil {
-		c := withCallerInfo(fmt.Sprintf("bucket iteration at %s", path), 3)
-		return fmt.Errorf("%s received nil db", c)
+		return fmt.Errorf("bucket iteration at %s received nil db", path)
 	} else if buffer == nil {
-		c := withCallerInfo(fmt.Sprintf("bucket iteration at %s", path), 3)
-		return fmt.Errorf("%s received nil channel", c)
+		return fmt.Errorf("bucket iteration at %s received nil channel", path)
 	}
 
 	defer close(buffer)
@@ -345,15 +744,7 @@ func bucketsAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan []byte,
 				continue
 			}
 
-			timer := time.NewTimer(dbWrap.bufferTimeout)
-			select {
-			case buffer <- k:
-				timer.Stop()
-			case <-timer.C:
-				err := newErrTimeout("quickbolt key scanning", "waiting to send to buffer")
-				logMutex.Lock()
-				dbWrap.logger.Err(err).Msg("")
-				logMutex.Unlock()
+			if err := sendTo(buffer, copyBytes(k), dbWrap, "quickbolt bucket scanning", path, k); err != nil {
 				return err
 			}
 		}
@@ -361,8 +752,132 @@ func bucketsAt(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan []byte,
 	})
 
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("bucket iteration at %s", path), 3)
-		return fmt.Errorf("%s experienced error while scanning buckets: %w", c, err)
+		return fmt.Errorf("bucket iteration at %s experienced error while scanning buckets: %w", path, err)
+	}
+	return nil
+}
+
+// bucketsAtRecursive sends the full path of every bucket nested under the given path to the buffer, depth-first.
+func bucketsAtRecursive(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan [][]byte, dbWrap dbWrapper) error {
+	if db == nil {
+		return fmt.Errorf("recursive bucket iteration at %s received nil db", path)
+	} else if buffer == nil {
+		return fmt.Errorf("recursive bucket iteration at %s received nil channel", path)
+	}
+
+	defer close(buffer)
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, mustExist)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		return walkBuckets(bkt, path, func(p [][]byte) error {
+			if err := sendTo(buffer, p, dbWrap, "quickbolt recursive bucket scanning", p[:len(p)-1], p[len(p)-1]); err != nil {
+				return err
+			}
+			return nil
+		})
+	})
+
+	if err != nil {
+		return fmt.Errorf("recursive bucket iteration at %s experienced error while scanning buckets: %w", path, err)
+	}
+	return nil
+}
+
+// walkBuckets calls visit with the full path of every bucket nested under bkt, depth-first.
+//
+// Path is the path of bkt. The paths given to visit are copies and remain valid after the transaction ends.
+func walkBuckets(bkt *bbolt.Bucket, path [][]byte, visit func([][]byte) error) error {
+	c := bkt.Cursor()
+
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v != nil {
+			continue
+		}
+
+		child := make([][]byte, len(path), len(path)+1)
+		copy(child, path)
+		child = append(child, copyBytes(k))
+
+		if err := visit(child); err != nil {
+			return err
+		}
+
+		if err := walkBuckets(bkt.Bucket(k), child, visit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PathedEntry is a key-value pair along with the path of the bucket containing it.
+type PathedEntry struct {
+	Path  [][]byte
+	Key   []byte
+	Value []byte
+}
+
+// entriesAtRecursive sends every key-value pair nested under the given path to the buffer, depth-first.
+func entriesAtRecursive(db *bbolt.DB, path [][]byte, mustExist bool, buffer chan PathedEntry, dbWrap dbWrapper) error {
+	if db == nil {
+		return fmt.Errorf("recursive key-value iteration at %s received nil db", path)
+	} else if buffer == nil {
+		return fmt.Errorf("recursive key-value iteration at %s received nil channel", path)
+	}
+
+	defer close(buffer)
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, mustExist)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		return walkEntries(bkt, copyPath(path), func(e PathedEntry) error {
+			if err := sendTo(buffer, e, dbWrap, "quickbolt recursive key scanning", e.Path, e.Key); err != nil {
+				return err
+			}
+			return nil
+		})
+	})
+
+	if err != nil {
+		return fmt.Errorf("recursive key-value iteration at %s experienced error while scanning keys: %w", path, err)
+	}
+	return nil
+}
+
+// walkEntries calls visit with every key-value pair nested under bkt, depth-first.
+//
+// Path is the path of bkt. Entries given to visit are copies and remain valid after the transaction ends.
+// Entries within the same bucket share their Path.
+func walkEntries(bkt *bbolt.Bucket, path [][]byte, visit func(PathedEntry) error) error {
+	c := bkt.Cursor()
+
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v != nil {
+			if err := visit(PathedEntry{Path: path, Key: copyBytes(k), Value: copyBytes(v)}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		child := make([][]byte, len(path), len(path)+1)
+		copy(child, path)
+		child = append(child, copyBytes(k))
+
+		if err := walkEntries(bkt.Bucket(k), child, visit); err != nil {
+			return err
+		}
 	}
+
 	return nil
 }
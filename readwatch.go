@@ -0,0 +1,122 @@
+package quickbolt
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithLongReadWarning tracks the read transactions opened by the db's methods, such as RunView, BeginRead,
+// ForEachEntry, and the iterations sending to a buffer, and logs a warning, with the caller info of the method,
+// for each that stays open longer than the given age. Such reads are also reported by LongReads.
+//
+// Long reads keep the pages freed by writes made since they began from being reused, so the file grows
+// instead. They are commonly caused by iterations whose buffers are drained slowly or not at all.
+//
+// Transactions opened via the raw bbolt.DB are not tracked.
+func WithLongReadWarning(age time.Duration) Option {
+	return func(o *options) {
+		o.longRead = age
+	}
+}
+
+// LongRead describes a read transaction that has been open longer than the age set via WithLongReadWarning.
+type LongRead struct {
+	// Op is the name of the DB method that opened the transaction.
+	Op string
+	// Caller is the file and line the method was called from, or empty if caller info is disabled.
+	Caller string
+	// Age is how long the transaction has been open.
+	Age time.Duration
+}
+
+// openRead is a read transaction tracked by a readTracker.
+type openRead struct {
+	op     *operation
+	start  time.Time
+	warned atomic.Bool
+}
+
+// readTracker records the read transactions open on a db.
+type readTracker struct {
+	next atomic.Uint64
+	open sync.Map // open maps IDs to *openRead.
+}
+
+func newReadTracker() *readTracker {
+	return &readTracker{}
+}
+
+// trackRead records that the given operation holds a read transaction, returning a func that must be called
+// once the transaction ends.
+//
+// Nothing is recorded unless WithLongReadWarning was given.
+func (d *dbWrapper) trackRead(o *operation) func() {
+	h := d.holder()
+	if h.state == nil || h.state.reads == nil {
+		return func() {}
+	}
+
+	t := h.state.reads
+	id := t.next.Add(1)
+	t.open.Store(id, &openRead{op: o, start: time.Now()})
+
+	return func() { t.open.Delete(id) }
+}
+
+// longReads returns the tracked reads open longer than the given age, oldest first.
+//
+// If warn is true, only the reads not yet warned of are returned, and they are marked as warned.
+func (t *readTracker) longReads(age time.Duration, warn bool) []LongRead {
+	var reads []LongRead
+
+	t.open.Range(func(_, v any) bool {
+		r := v.(*openRead)
+
+		a := time.Since(r.start)
+		if a <= age {
+			return true
+		}
+
+		if warn && !r.warned.CompareAndSwap(false, true) {
+			return true
+		}
+
+		reads = append(reads, LongRead{Op: r.op.name, Caller: describeCaller("", r.op.caller), Age: a})
+		return true
+	})
+
+	sort.Slice(reads, func(i, j int) bool { return reads[i].Age > reads[j].Age })
+
+	return reads
+}
+
+// watchReads periodically warns of reads open longer than the given age until background work is stopped.
+func (d *dbWrapper) watchReads(age time.Duration) {
+	interval := age / 2
+	if interval <= 0 {
+		interval = age
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.state.stop:
+			return
+		case <-ticker.C:
+			for _, r := range d.state.reads.longReads(age, true) {
+				desc := r.Op
+				if r.Caller != "" {
+					desc += " called from " + r.Caller
+				}
+
+				logMutex.Lock()
+				d.logger.Warn().Str("operation", r.Op).Dur("age", r.Age).Msg(desc + " has held a read transaction open longer than " + age.String() + ", keeping freed pages from being reused")
+				logMutex.Unlock()
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package quickbolt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func TestWithLongReadWarning(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithLongReadWarning(20*time.Millisecond))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	var log syncBuffer
+	db.AddLog(&log)
+
+	tx, err := db.BeginRead()
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool { return strings.Contains(log.String(), "has held a read transaction") }, time.Second, 10*time.Millisecond)
+	assert.Contains(t, log.String(), "BeginRead called from")
+	assert.Contains(t, log.String(), "readwatch_test.go")
+
+	reads := db.LongReads()
+	assert.Len(t, reads, 1)
+	assert.Equal(t, "BeginRead", reads[0].Op)
+	assert.Contains(t, reads[0].Caller, "readwatch_test.go")
+	assert.Greater(t, reads[0].Age, 20*time.Millisecond)
+
+	assert.Nil(t, tx.Rollback())
+	assert.Empty(t, db.LongReads())
+
+	err = db.RunView(func(tx *bbolt.Tx) error {
+		time.Sleep(40 * time.Millisecond)
+
+		reads := db.LongReads()
+		assert.Len(t, reads, 1)
+		assert.Equal(t, "RunView", reads[0].Op)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, db.LongReads())
+}
+
+func TestWithLongReadWarning_Callbacks(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir(), WithLongReadWarning(20*time.Millisecond))
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a"}
+	assert.Nil(t, db.Insert("k", "v", path))
+
+	longRead := func(op string) {
+		time.Sleep(40 * time.Millisecond)
+
+		reads := db.LongReads()
+		if assert.Len(t, reads, 1, op) {
+			assert.Equal(t, op, reads[0].Op)
+		}
+	}
+
+	assert.Nil(t, db.ForEachEntry(path, func(k, v []byte) error { longRead("ForEachEntry"); return nil }))
+	assert.Nil(t, db.ForEachKey(path, func(k []byte) error { longRead("ForEachKey"); return nil }))
+	assert.Empty(t, db.LongReads())
+
+	// Reads made without a callback are tracked as well.
+	var w blockingWriter
+	w.release = make(chan struct{})
+	done := make(chan error)
+	go func() { done <- db.Inspect(path, &w, InspectOptions{}) }()
+
+	assert.Eventually(t, func() bool {
+		reads := db.LongReads()
+		return len(reads) == 1 && reads[0].Op == "Inspect"
+	}, time.Second, 10*time.Millisecond)
+	close(w.release)
+	assert.Nil(t, <-done)
+	assert.Empty(t, db.LongReads())
+}
+
+// blockingWriter blocks each write until release is closed.
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func Test_dbWrapper_LongReads(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	tx, err := db.BeginRead()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	assert.Nil(t, db.LongReads())
+}
@@ -0,0 +1,262 @@
+package quickbolt
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// Re-encoding proceeds in phases, each made of transactions handling up to reencodeBatchSize keys.
+// Progress is recorded in the meta bucket so that a re-encoding interrupted by a crash resumes where it stopped.
+const (
+	// reencodeStaging copies each pair to a staging bucket under its new key, leaving the original bucket untouched.
+	reencodeStaging byte = iota
+	// reencodeClearing deletes the pairs from the original bucket.
+	reencodeClearing
+	// reencodeMoving moves the staged pairs into the original bucket.
+	reencodeMoving
+)
+
+var (
+	reencodePhaseKey  = []byte("phase")
+	reencodeLastKey   = []byte("last")
+	reencodeCountKey  = []byte("count")
+	reencodeStagedKey = []byte("staged")
+)
+
+// reencodeState returns the bucket recording the progress of the re-encoding of the bucket at the given path,
+// creating it if necessary.
+func reencodeState(tx *bbolt.Tx, path [][]byte) (*bbolt.Bucket, *bbolt.Bucket, error) {
+	all, err := getCreateMetaBucket(tx, reencodeBucket)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state, err := all.CreateBucketIfNotExists([]byte(pathKey(path)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while accessing re-encoding state: %w", err)
+	}
+
+	staged, err := state.CreateBucketIfNotExists(reencodeStagedKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while accessing staged keys: %w", err)
+	}
+
+	return state, staged, nil
+}
+
+// reencodeEnv returns the write environment of the re-encoding of the bucket at the given path,
+// whose rules permit its own writes while the re-encoding is in progress.
+func (d *dbWrapper) reencodeEnv(path [][]byte) writeEnv {
+	env := d.writeEnv(path)
+
+	rules := bucketRules{path: path}
+	if env.rules != nil {
+		rules = *env.rules
+	}
+	rules.reencoder = true
+	env.rules = &rules
+
+	return env
+}
+
+// reencodeStep performs a single transaction of the re-encoding of the bucket at the given path.
+//
+// Done is true once the re-encoding is complete, in which case count is the number of keys re-encoded.
+func reencodeStep(db *bbolt.DB, path [][]byte, transform func([]byte) ([]byte, error), env writeEnv) (done bool, count uint64, err error) {
+	err = db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		state, staged, err := reencodeState(tx, path)
+		if err != nil {
+			return err
+		}
+
+		var phase byte
+		if p := state.Get(reencodePhaseKey); len(p) == 1 {
+			phase = p[0]
+		}
+
+		switch phase {
+		case reencodeStaging:
+			return stageReencodedKeys(bkt, state, staged, transform)
+		case reencodeClearing:
+			return clearReencodedKeys(tx, path, bkt, state, env)
+		case reencodeMoving:
+			if done, err = moveReencodedKeys(tx, path, bkt, staged, env); err != nil || !done {
+				return err
+			}
+
+			if c := state.Get(reencodeCountKey); len(c) == 8 {
+				count = binary.BigEndian.Uint64(c)
+			}
+
+			all, err := getCreateMetaBucket(tx, reencodeBucket)
+			if err != nil {
+				return err
+			}
+			return all.DeleteBucket([]byte(pathKey(path)))
+		default:
+			return fmt.Errorf("unknown re-encoding phase %d", phase)
+		}
+	})
+
+	return done, count, err
+}
+
+// stageReencodedKeys copies the next batch of pairs to the staging bucket under their new keys.
+func stageReencodedKeys(bkt, state, staged *bbolt.Bucket, transform func([]byte) ([]byte, error)) error {
+	var count uint64
+	if c := state.Get(reencodeCountKey); len(c) == 8 {
+		count = binary.BigEndian.Uint64(c)
+	}
+
+	c := bkt.Cursor()
+
+	k, v := c.First()
+	if last := state.Get(reencodeLastKey); last != nil {
+		// The last key is copied since the cursor's bucket is written to below.
+		last = copyBytes(last)
+		if k, v = c.Seek(last); k != nil && string(k) == string(last) {
+			k, v = c.Next()
+		}
+	}
+
+	var last []byte
+	for n := 0; k != nil && n < reencodeBatchSize; k, v = c.Next() {
+		if v == nil {
+			continue
+		}
+
+		nk, err := transform(copyBytes(k))
+		if err != nil {
+			return newErrReencode(fmt.Sprintf("transform of key %s failed: %s", k, err))
+		} else if len(nk) == 0 {
+			return newErrReencode(fmt.Sprintf("transform of key %s returned an empty key", k))
+		} else if staged.Get(nk) != nil {
+			return newErrReencode(fmt.Sprintf("key %s collides with another key re-encoded as %s", k, nk))
+		} else if bkt.Bucket(nk) != nil {
+			return newErrReencode(fmt.Sprintf("key %s re-encoded as %s collides with a bucket", k, nk))
+		}
+
+		if err := staged.Put(nk, v); err != nil {
+			return fmt.Errorf("error while staging %s: %w", nk, err)
+		}
+
+		last = k
+		count++
+		n++
+	}
+
+	if last != nil {
+		if err := state.Put(reencodeLastKey, copyBytes(last)); err != nil {
+			return err
+		}
+	}
+
+	countBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(countBytes, count)
+	if err := state.Put(reencodeCountKey, countBytes); err != nil {
+		return err
+	}
+
+	if k == nil {
+		return state.Put(reencodePhaseKey, []byte{reencodeClearing})
+	}
+	return nil
+}
+
+// clearReencodedKeys deletes the next batch of pairs from the original bucket.
+func clearReencodedKeys(tx *bbolt.Tx, path [][]byte, bkt, state *bbolt.Bucket, env writeEnv) error {
+	var keys [][]byte
+
+	// Keys are collected before deleting since deleting during cursor iteration skips entries.
+	c := bkt.Cursor()
+	for k, v := c.First(); k != nil && len(keys) < reencodeBatchSize; k, v = c.Next() {
+		if v != nil {
+			keys = append(keys, k)
+		}
+	}
+
+	for _, k := range keys {
+		if err := env.rules.beforeDelete(tx, path, k, bkt.Get(k)); err != nil {
+			return err
+		}
+		if err := bkt.Delete(k); err != nil {
+			return fmt.Errorf("error while deleting %s: %w", k, err)
+		}
+		env.metrics.observeWrite(tx, 0)
+	}
+
+	if len(keys) < reencodeBatchSize {
+		return state.Put(reencodePhaseKey, []byte{reencodeMoving})
+	}
+	return nil
+}
+
+// moveReencodedKeys moves the next batch of staged pairs into the original bucket.
+func moveReencodedKeys(tx *bbolt.Tx, path [][]byte, bkt, staged *bbolt.Bucket, env writeEnv) (bool, error) {
+	var entries [][2][]byte
+
+	c := staged.Cursor()
+	for k, v := c.First(); k != nil && len(entries) < reencodeBatchSize; k, v = c.Next() {
+		entries = append(entries, [2][]byte{k, v})
+	}
+
+	for _, e := range entries {
+		k, err := env.rules.storeKey(tx, path, e[0])
+		if err != nil {
+			return false, err
+		}
+		if err := env.rules.beforePut(tx, path, k, bkt.Get(k), e[1]); err != nil {
+			return false, err
+		}
+		if err := bkt.Put(k, e[1]); err != nil {
+			return false, fmt.Errorf("error while writing %s: %w", e[0], err)
+		}
+		env.metrics.observeWrite(tx, len(k)+len(e[1]))
+
+		if err := staged.Delete(e[0]); err != nil {
+			return false, fmt.Errorf("error while unstaging %s: %w", e[0], err)
+		}
+	}
+
+	return len(entries) < reencodeBatchSize, nil
+}
+
+// checkNotReencoding returns an ErrAccess if the keys of the bucket at the given path are being re-encoded,
+// as a write interleaved with the re-encoding's transactions could be overwritten or deleted by it.
+func checkNotReencoding(tx *bbolt.Tx, path [][]byte) error {
+	meta := tx.Bucket([]byte(metaBucket))
+	if meta == nil {
+		return nil
+	}
+
+	all := meta.Bucket([]byte(reencodeBucket))
+	if all == nil || all.Bucket([]byte(pathKey(path))) == nil {
+		return nil
+	}
+
+	return newErrAccess(fmt.Sprintf("%s while its keys are being re-encoded", path))
+}
+
+// abandonReencode discards the staged progress of the re-encoding of the bucket at the given path.
+//
+// It must only be called while the re-encoding is staging, before the original bucket is modified.
+func abandonReencode(db *bbolt.DB, path [][]byte) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		all, err := getCreateMetaBucket(tx, reencodeBucket)
+		if err != nil {
+			return err
+		}
+
+		if all.Bucket([]byte(pathKey(path))) == nil {
+			return nil
+		}
+		return all.DeleteBucket([]byte(pathKey(path)))
+	})
+}
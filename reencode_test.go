@@ -0,0 +1,146 @@
+package quickbolt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+// fillDecimal writes n pairs keyed by their decimal index to the bucket at the given path.
+func fillDecimal(t *testing.T, db DB, path [][]byte, n int) {
+	err := db.RunUpdate(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateBucket(tx, path)
+		if err != nil {
+			return err
+		}
+		if _, err := bkt.CreateBucketIfNotExists([]byte("nested")); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := bkt.Put([]byte(strconv.Itoa(i)), []byte("v"+strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func decimalToBinary(old []byte) ([]byte, error) {
+	i, err := strconv.ParseUint(string(old), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, i)
+	return b, nil
+}
+
+func Test_dbWrapper_ReencodeKeys(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := [][]byte{[]byte("items")}
+	fillDecimal(t, db, path, 2500)
+
+	n, err := db.ReencodeKeys(path, decimalToBinary)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2500), n)
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, 1234)
+	v, err := db.GetValue(key, path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1234", string(v))
+
+	v, err = db.GetValue("1234", path, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	buckets := make(chan []byte)
+	go func() { assert.Nil(t, db.BucketsAt(path, true, buckets)) }()
+	var names []string
+	for b := range buckets {
+		names = append(names, string(b))
+	}
+	assert.Equal(t, []string{"nested"}, names)
+}
+
+func Test_dbWrapper_ReencodeKeysResume(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := [][]byte{[]byte("items")}
+	fillDecimal(t, db, path, 2500)
+
+	// Simulate a re-encoding interrupted partway through each phase.
+	d := db.(*dbWrapper)
+	for i := 0; i < 4; i++ {
+		done, _, err := reencodeStep(d.db, path, decimalToBinary, d.reencodeEnv(path))
+		assert.Nil(t, err)
+		assert.False(t, done)
+	}
+
+	// Writes that the re-encoding could lose are rejected until it completes.
+	assert.True(t, errors.Is(db.Insert("2500", "v2500", path), ErrAccess{}))
+	assert.True(t, errors.Is(db.Delete("1", path), ErrAccess{}))
+
+	n, err := db.ReencodeKeys(path, decimalToBinary)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2500), n)
+	assert.Nil(t, db.Insert("new", "v", path))
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, 2499)
+	v, err := db.GetValue(key, path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2499", string(v))
+}
+
+func Test_dbWrapper_ReencodeKeysCollision(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := [][]byte{[]byte("items")}
+	fillDecimal(t, db, path, 1500)
+
+	tests := []struct {
+		name      string
+		transform func([]byte) ([]byte, error)
+	}{
+		{name: "duplicate", transform: func(old []byte) ([]byte, error) { return old[:1], nil }},
+		{name: "bucket", transform: func(old []byte) ([]byte, error) { return []byte("nested"), nil }},
+		{name: "empty", transform: func(old []byte) ([]byte, error) { return nil, nil }},
+		{name: "failing", transform: func(old []byte) ([]byte, error) {
+			if string(old) == "1400" {
+				return nil, fmt.Errorf("bad key")
+			}
+			return decimalToBinary(old)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.ReencodeKeys(path, tt.transform)
+			assert.True(t, errors.As(err, &ErrReencode{}))
+
+			v, err := db.GetValue("1400", path, true)
+			assert.Nil(t, err)
+			assert.Equal(t, "v1400", string(v))
+		})
+	}
+
+	n, err := db.ReencodeKeys(path, decimalToBinary)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1500), n, "abandoned attempts must not leave staged keys behind")
+}
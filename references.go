@@ -0,0 +1,117 @@
+package quickbolt
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"go.etcd.io/bbolt"
+)
+
+// ReferenceViolation describes a value that does not match any key in the bucket it references.
+type ReferenceViolation struct {
+	// Path is the path of the bucket containing the value.
+	Path [][]byte
+	// Key is the key paired with the value.
+	Key []byte
+	// Value is the value lacking a matching key.
+	Value []byte
+	// Target is the path of the bucket the value should be a key in.
+	Target [][]byte
+}
+
+// checkReference returns an error if val is not a key in the bucket at the target path.
+func checkReference(tx *bbolt.Tx, path [][]byte, val []byte, target [][]byte) error {
+	bkt, err := getBucket(tx, target, false)
+	if err != nil {
+		return fmt.Errorf("error while navigating referenced path: %w", err)
+	}
+
+	if bkt == nil || bkt.Get(val) == nil {
+		return newErrInvalidReference(fmt.Sprintf("value %s at %s", val, path), target)
+	}
+
+	return nil
+}
+
+// checkReferences scans every bucket with registered references, sending a ReferenceViolation to the buffer
+// for each value lacking a matching key in a referenced bucket.
+func checkReferences(db *bbolt.DB, rules []*bucketRules, buffer chan ReferenceViolation, dbWrap dbWrapper) error {
+	if db == nil {
+		return fmt.Errorf("reference check received nil db")
+	} else if buffer == nil {
+		return fmt.Errorf("reference check received nil channel")
+	}
+
+	defer close(buffer)
+
+	// Paths are sorted so violations are reported in a stable order.
+	sort.Slice(rules, func(i, j int) bool { return pathKey(rules[i].path) < pathKey(rules[j].path) })
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		for _, r := range rules {
+			if len(r.references) == 0 {
+				continue
+			}
+
+			bkt, err := getBucket(tx, r.path, false)
+			if err != nil {
+				return fmt.Errorf("error while navigating path: %w", err)
+			} else if bkt == nil {
+				continue
+			}
+
+			for _, target := range r.references {
+				targetBkt, err := getBucket(tx, target, false)
+				if err != nil {
+					return fmt.Errorf("error while navigating referenced path: %w", err)
+				}
+
+				c := bkt.Cursor()
+
+				for k, v := c.First(); k != nil; k, v = c.Next() {
+					if v == nil || (targetBkt != nil && targetBkt.Get(v) != nil) {
+						continue
+					}
+
+					violation := ReferenceViolation{Path: r.path, Key: copyBytes(k), Value: copyBytes(v), Target: target}
+
+					if err := sendTo(buffer, violation, dbWrap, "reference check", r.path, k); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("reference check experienced error while scanning references: %w", err)
+	}
+
+	return nil
+}
+
+// containsPath returns true if the given path is present in paths.
+func containsPath(paths [][][]byte, path [][]byte) bool {
+	for _, p := range paths {
+		if len(p) != len(path) {
+			continue
+		}
+
+		equal := true
+		for i := range p {
+			if !bytes.Equal(p[i], path[i]) {
+				equal = false
+				break
+			}
+		}
+
+		if equal {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,42 @@
+package quickbolt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/errgroup"
+)
+
+func Test_dbWrapper_RegisterReference(t *testing.T) {
+	db, err := Create("foo.db")
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	orders, users := []string{"orders"}, []string{"users"}
+	assert.Nil(t, db.RegisterReference(orders, users))
+
+	err = db.Insert("order1", "alice", orders)
+	var invalid ErrInvalidReference
+	assert.True(t, errors.As(err, &invalid))
+
+	assert.Nil(t, db.Insert("alice", "Alice", users))
+	assert.Nil(t, db.Insert("bob", "Bob", users))
+	assert.Nil(t, db.Insert("order1", "alice", orders))
+	assert.Nil(t, db.Insert("order2", "bob", orders))
+	assert.Nil(t, db.Delete("bob", users))
+
+	var violations []ReferenceViolation
+	buffer := make(chan ReferenceViolation)
+
+	var eg errgroup.Group
+	eg.Go(func() error { return db.CheckReferences(buffer) })
+	eg.Go(func() error { return Capture(&violations, buffer, nil, nil, nil) })
+	assert.Nil(t, eg.Wait())
+
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, []byte("order2"), violations[0].Key)
+		assert.Equal(t, []byte("bob"), violations[0].Value)
+	}
+}
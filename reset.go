@@ -0,0 +1,56 @@
+package quickbolt
+
+import (
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// reset removes every bucket and key under the given path, along with their indexes, within a single transaction,
+// leaving an empty bucket at the path.
+//
+// Quickbolt's bookkeeping in the meta bucket, such as held locks, leases, applied seeds, and expiry registrations,
+// is kept even if the path is empty, as it reflects the rules registered with and locks held by open handles.
+func reset(db *bbolt.DB, path [][]byte, env writeEnv) error {
+	if db == nil {
+		return fmt.Errorf("reset received nil db")
+	}
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		if err := deleteIndexTree(tx, path); err != nil {
+			return fmt.Errorf("error while removing indexes: %w", err)
+		}
+
+		if len(path) == 0 {
+			if tx.Bucket([]byte(rootBucket)) != nil {
+				if err := tx.DeleteBucket([]byte(rootBucket)); err != nil {
+					return fmt.Errorf("error while removing %s bucket: %w", rootBucket, err)
+				}
+			}
+		} else {
+			parent, err := getBucket(tx, path[:len(path)-1], false)
+			if err != nil {
+				return fmt.Errorf("error while navigating path: %w", err)
+			}
+			if parent != nil && parent.Bucket(path[len(path)-1]) != nil {
+				if err := parent.DeleteBucket(path[len(path)-1]); err != nil {
+					return fmt.Errorf("error while removing %s: %w", path, err)
+				}
+			}
+		}
+
+		if _, err := getCreateBucket(tx, path); err != nil {
+			return fmt.Errorf("error while recreating %s: %w", path, err)
+		}
+
+		env.metrics.observeWrite(tx, 0)
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("reset of %s experienced error: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package quickbolt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func Test_dbWrapper_Reset(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.SetUnique([]string{"users"}))
+	assert.Nil(t, db.Insert("k1", "v1", []string{"users"}))
+	assert.Nil(t, db.Insert("k2", "v2", []string{"a", "nested"}))
+
+	seed := func(tx *bbolt.Tx) error { return nil }
+	ran, err := NewSeeder(db).Add("defaults", seed).Run()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"defaults"}, ran)
+
+	assert.Nil(t, db.Reset())
+
+	buckets := make(chan []byte, 10)
+	assert.Nil(t, db.BucketsAt([]string{}, true, buckets))
+	assert.Empty(t, buckets)
+
+	ran, err = NewSeeder(db).Add("defaults", seed).Run()
+	assert.Nil(t, err)
+	assert.Empty(t, ran, "records of applied seeds must be kept")
+
+	// The unique rule is kept, but the values indexed before the reset are forgotten.
+	assert.Nil(t, db.Insert("k3", "v1", []string{"users"}))
+	var dup ErrDuplicateValue
+	assert.ErrorAs(t, db.Insert("k4", "v1", []string{"users"}), &dup)
+}
+
+func Test_dbWrapper_ResetScope(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k1", "v", []string{"tenant", "a"}))
+	assert.Nil(t, db.Insert("k2", "v", []string{"other"}))
+
+	assert.Nil(t, db.Scope([]string{"tenant"}).Reset())
+
+	buckets := make(chan []byte, 10)
+	assert.Nil(t, db.BucketsAt([]string{"tenant"}, true, buckets))
+	assert.Empty(t, buckets)
+
+	v, err := db.GetValue("k2", []string{"other"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v", string(v))
+}
+
+func Test_dbWrapper_ResetKeepsBookkeeping(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := CreateWith("foo.db", dir)
+	assert.Nil(t, err)
+
+	path := []string{"sessions"}
+	assert.Nil(t, db.ExpireIndex(path))
+
+	unlock, err := db.Lock(context.Background(), "job", []string{"locks"}, time.Minute)
+	assert.Nil(t, err)
+
+	assert.Nil(t, db.Reset())
+
+	// The lock is still held, and can still be released by its holder.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = db.Lock(ctx, "job", []string{"locks"}, time.Minute)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, unlock())
+
+	// The expiry registration survives the reset and a reopen.
+	assert.Nil(t, db.Close())
+	db, err = OpenWith("foo.db", dir)
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("a", "1", path))
+	n, err := db.ExpireBefore(path, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+}
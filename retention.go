@@ -0,0 +1,160 @@
+package quickbolt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotStore is implemented by backup targets able to list and delete the snapshots they hold.
+type SnapshotStore interface {
+	// List returns the snapshots in the store. Only their Name and Time fields need be set.
+	List(ctx context.Context) ([]BackupInfo, error)
+	// Delete removes the snapshot with the given name.
+	Delete(ctx context.Context, name string) error
+}
+
+// RetentionRule marks the snapshots it retains.
+//
+// Snapshots are sorted newest first, and keep is the same length as snapshots.
+// Rules only ever set entries of keep to true.
+type RetentionRule func(snapshots []BackupInfo, keep []bool)
+
+// KeepLast retains the n most recent snapshots.
+func KeepLast(n int) RetentionRule {
+	return func(snapshots []BackupInfo, keep []bool) {
+		for i := 0; i < n && i < len(snapshots); i++ {
+			keep[i] = true
+		}
+	}
+}
+
+// KeepDaily retains the most recent snapshot of each of the last d days that have one.
+//
+// Days are measured in UTC.
+func KeepDaily(d int) RetentionRule {
+	return keepPerPeriod(d, func(t time.Time) string {
+		return t.UTC().Format("2006-01-02")
+	})
+}
+
+// KeepWeekly retains the most recent snapshot of each of the last w ISO weeks that have one.
+//
+// Weeks are measured in UTC.
+func KeepWeekly(w int) RetentionRule {
+	return keepPerPeriod(w, func(t time.Time) string {
+		year, week := t.UTC().ISOWeek()
+		return fmt.Sprintf("%d-%d", year, week)
+	})
+}
+
+// keepPerPeriod retains the most recent snapshot within each of the last n periods, as identified by period.
+func keepPerPeriod(n int, period func(time.Time) string) RetentionRule {
+	return func(snapshots []BackupInfo, keep []bool) {
+		seen := make(map[string]bool, n)
+
+		for i, s := range snapshots {
+			if len(seen) >= n {
+				return
+			}
+
+			p := period(s.Time)
+			if !seen[p] {
+				seen[p] = true
+				keep[i] = true
+			}
+		}
+	}
+}
+
+// Retain splits the snapshots into those retained by at least one of the rules and those that aren't.
+//
+// Every snapshot is retained if no rules are given. Both returned slices are sorted newest first.
+func Retain(snapshots []BackupInfo, rules ...RetentionRule) (keep, prune []BackupInfo) {
+	sorted := append([]BackupInfo(nil), snapshots...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.After(sorted[j].Time) })
+
+	if len(rules) == 0 {
+		return sorted, nil
+	}
+
+	marks := make([]bool, len(sorted))
+	for _, rule := range rules {
+		rule(sorted, marks)
+	}
+
+	for i, s := range sorted {
+		if marks[i] {
+			keep = append(keep, s)
+		} else {
+			prune = append(prune, s)
+		}
+	}
+
+	return keep, prune
+}
+
+// Prune deletes the snapshots in the store not retained by any of the rules and returns those deleted.
+//
+// Nothing is deleted if no rules are given. If a deletion fails, the snapshots deleted before it are returned alongside the error.
+func Prune(ctx context.Context, store SnapshotStore, rules ...RetentionRule) ([]BackupInfo, error) {
+	if store == nil {
+		return nil, fmt.Errorf("snapshot pruning received nil store")
+	}
+
+	snapshots, err := store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot pruning experienced error while listing snapshots: %w", err)
+	}
+
+	_, prune := Retain(snapshots, rules...)
+
+	var deleted []BackupInfo
+	for _, s := range prune {
+		if err := ctx.Err(); err != nil {
+			return deleted, fmt.Errorf("snapshot pruning was canceled: %w", err)
+		}
+
+		if err := store.Delete(ctx, s.Name); err != nil {
+			return deleted, fmt.Errorf("snapshot pruning experienced error while deleting %s: %w", s.Name, err)
+		}
+		deleted = append(deleted, s)
+	}
+
+	return deleted, nil
+}
+
+// List returns the snapshot files in the target's directory, timed by their modification time.
+//
+// The directory should hold nothing but snapshots, since every regular file within it is listed.
+func (t FileTarget) List(ctx context.Context) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(t.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []BackupInfo
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.Contains(e.Name(), ".partial-") {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, BackupInfo{Name: e.Name(), Size: info.Size(), Time: info.ModTime()})
+	}
+
+	return snapshots, nil
+}
+
+// Delete removes the snapshot file with the given name.
+func (t FileTarget) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(t.Dir, name))
+}
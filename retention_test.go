@@ -0,0 +1,76 @@
+package quickbolt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetain(t *testing.T) {
+	base := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC) // A Friday.
+	at := func(days, hours int) BackupInfo {
+		ts := base.AddDate(0, 0, -days).Add(-time.Duration(hours) * time.Hour)
+		return BackupInfo{Name: ts.Format(time.RFC3339), Time: ts}
+	}
+
+	snapshots := []BackupInfo{at(0, 0), at(0, 1), at(1, 0), at(1, 1), at(2, 0), at(8, 0), at(15, 0)}
+
+	names := func(infos []BackupInfo) []string {
+		var n []string
+		for _, i := range infos {
+			n = append(n, i.Name)
+		}
+		return n
+	}
+
+	tests := []struct {
+		name  string
+		rules []RetentionRule
+		want  []BackupInfo
+	}{
+		{name: "no rules", want: snapshots},
+		{name: "last", rules: []RetentionRule{KeepLast(3)}, want: []BackupInfo{at(0, 0), at(0, 1), at(1, 0)}},
+		{name: "daily", rules: []RetentionRule{KeepDaily(3)}, want: []BackupInfo{at(0, 0), at(1, 0), at(2, 0)}},
+		{name: "weekly", rules: []RetentionRule{KeepWeekly(2)}, want: []BackupInfo{at(0, 0), at(8, 0)}},
+		{name: "union", rules: []RetentionRule{KeepLast(1), KeepWeekly(3)}, want: []BackupInfo{at(0, 0), at(8, 0), at(15, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep, prune := Retain(snapshots, tt.rules...)
+			assert.Equal(t, names(tt.want), names(keep))
+			assert.Equal(t, len(snapshots), len(keep)+len(prune))
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	target := FileTarget{Dir: dir}
+
+	now := time.Now()
+	for i, name := range []string{"a.db", "b.db", "c.db"} {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(path, []byte(name), 0600))
+		ts := now.Add(-time.Duration(i) * time.Hour)
+		assert.Nil(t, os.Chtimes(path, ts, ts))
+	}
+
+	deleted, err := Prune(context.Background(), target, KeepLast(1))
+	assert.Nil(t, err)
+	assert.Len(t, deleted, 2)
+	assert.Equal(t, "b.db", deleted[0].Name)
+	assert.Equal(t, "c.db", deleted[1].Name)
+
+	remaining, err := target.List(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "a.db", remaining[0].Name)
+
+	deleted, err = Prune(context.Background(), target)
+	assert.Nil(t, err)
+	assert.Empty(t, deleted)
+}
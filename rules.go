@@ -0,0 +1,437 @@
+package quickbolt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// bucketRules holds the constraints registered for a single bucket path.
+type bucketRules struct {
+	path       [][]byte
+	validators []func(k, v []byte) error
+	unique     bool
+	references [][][]byte        // references holds the paths of buckets whose keys this bucket's values must match.
+	codec      Codec             // codec overrides the db's codec for this bucket, if set.
+	expiry     bool              // expiry is true if the bucket's keys are indexed by write time.
+	onExpire   func(k, v []byte) // onExpire receives pairs removed by ExpireBefore, if set.
+	order      OrderFunc         // order maintains an order index for EntriesInOrder, if set.
+	foldCase   bool              // foldCase is true if keys are case-folded on write and lookup.
+	reencoder  bool              // reencoder is true for the rules used by ReencodeKeys's own writes, which checkNotReencoding permits.
+}
+
+// beforePut checks the key-value pair about to be written to the bucket at the given path,
+// maintaining any indexes the rules depend on.
+//
+// Old is the value currently paired with the key, or nil if there is none.
+//
+// A nil *bucketRules permits every write, unless the bucket's keys are being re-encoded.
+func (r *bucketRules) beforePut(tx *bbolt.Tx, path [][]byte, key, old, val []byte) error {
+	if r == nil || !r.reencoder {
+		if err := checkNotReencoding(tx, path); err != nil {
+			return err
+		}
+	}
+
+	if r == nil {
+		return nil
+	}
+
+	for _, validate := range r.validators {
+		if err := validate(key, val); err != nil {
+			return newErrValidation(fmt.Sprintf("value for key %s", key), err)
+		}
+	}
+
+	for _, target := range r.references {
+		if err := checkReference(tx, path, val, target); err != nil {
+			return err
+		}
+	}
+
+	if r.unique {
+		if err := putUniqueIndex(tx, path, key, old, val); err != nil {
+			return err
+		}
+	}
+
+	if r.expiry {
+		if err := putExpiryIndex(tx, path, key, val, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if r.order != nil && old == nil {
+		if err := putOrderIndex(tx, path, key, r.order); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// beforeDelete maintains any indexes the rules depend on for the key about to be removed from
+// the bucket at the given path.
+//
+// Old is the value currently paired with the key, or nil if there is none.
+//
+// A nil *bucketRules permits every deletion, unless the bucket's keys are being re-encoded.
+func (r *bucketRules) beforeDelete(tx *bbolt.Tx, path [][]byte, key, old []byte) error {
+	if r == nil || !r.reencoder {
+		if err := checkNotReencoding(tx, path); err != nil {
+			return err
+		}
+	}
+
+	if r == nil || old == nil {
+		return nil
+	}
+
+	if r.unique {
+		if err := deleteUniqueIndex(tx, path, key, old); err != nil {
+			return err
+		}
+	}
+
+	if r.expiry {
+		if err := deleteExpiryIndex(tx, path, key); err != nil {
+			return err
+		}
+	}
+
+	if r.order != nil {
+		if err := deleteOrderIndex(tx, path, key, r.order); err != nil {
+			return err
+		}
+	}
+
+	if r.foldCase {
+		if err := deleteOriginalKey(tx, path, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ruleRegistry stores the rules registered for each bucket path.
+type ruleRegistry struct {
+	mu         sync.RWMutex
+	byPath     map[string]*bucketRules
+	invariants []boundInvariant
+	paths      map[string][][]byte // paths holds the paths registered via RegisterPath by name.
+}
+
+func newRuleRegistry() *ruleRegistry {
+	return &ruleRegistry{byPath: make(map[string]*bucketRules)}
+}
+
+// clone returns a copy of the registry, sharing the registered rules, which are replaced rather than modified.
+func (r *ruleRegistry) clone() *ruleRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c := newRuleRegistry()
+	for k, v := range r.byPath {
+		c.byPath[k] = v
+	}
+	c.invariants = append([]boundInvariant(nil), r.invariants...)
+	if r.paths != nil {
+		c.paths = make(map[string][][]byte, len(r.paths))
+		for k, v := range r.paths {
+			c.paths[k] = v
+		}
+	}
+
+	return c
+}
+
+// update applies the given func to the rules for the given path, creating them if needed.
+func (r *ruleRegistry) update(path [][]byte, f func(*bucketRules)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := pathKey(path)
+
+	// Rules are replaced rather than modified so that snapshots returned by forPath stay consistent.
+	rules := &bucketRules{path: path}
+	if existing, ok := r.byPath[key]; ok {
+		*rules = *existing
+		rules.validators = append([]func(k, v []byte) error(nil), existing.validators...)
+		rules.references = append([][][]byte(nil), existing.references...)
+	}
+
+	f(rules)
+	r.byPath[key] = rules
+}
+
+// addInvariant registers the invariant to be checked by every call to Verify.
+func (r *ruleRegistry) addInvariant(inv boundInvariant) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.invariants = append(r.invariants, inv)
+}
+
+// allInvariants returns the invariants registered via addInvariant.
+func (r *ruleRegistry) allInvariants() []boundInvariant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]boundInvariant(nil), r.invariants...)
+}
+
+// forPath returns the rules registered for the given path, or nil if there are none.
+func (r *ruleRegistry) forPath(path [][]byte) *bucketRules {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.byPath[pathKey(path)]
+}
+
+// all returns the rules registered for every path.
+func (r *ruleRegistry) all() []*bucketRules {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules := make([]*bucketRules, 0, len(r.byPath))
+	for _, b := range r.byPath {
+		rules = append(rules, b)
+	}
+
+	return rules
+}
+
+// getCreateIndexBucket returns the bucket holding the value index for the given path, creating buckets if needed.
+//
+// Index buckets mirror the data path under a separate top-level bucket so that they are invisible to
+// iteration over the db root.
+func getCreateIndexBucket(tx *bbolt.Tx, path [][]byte) (*bbolt.Bucket, error) {
+	return getCreateIndexChild(tx, path, indexEntries)
+}
+
+// getCreateIndexChild returns the index bucket with the given name for the given path, creating buckets if needed.
+func getCreateIndexChild(tx *bbolt.Tx, path [][]byte, name string) (*bbolt.Bucket, error) {
+	bkt, err := tx.CreateBucketIfNotExists([]byte(indexBucket))
+	if err != nil {
+		return nil, fmt.Errorf("error while accessing index bucket: %w", err)
+	}
+
+	for _, p := range path {
+		bkt, err = bkt.CreateBucketIfNotExists(p)
+		if err != nil {
+			return nil, fmt.Errorf("error while accessing index %s in %s: %w", p, path, err)
+		}
+	}
+
+	bkt, err = bkt.CreateBucketIfNotExists([]byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("error while accessing index entries for %s: %w", path, err)
+	}
+
+	return bkt, nil
+}
+
+// getIndexBucket returns the bucket holding the value index for the given path, or nil if it does not exist.
+func getIndexBucket(tx *bbolt.Tx, path [][]byte) *bbolt.Bucket {
+	return getIndexChild(tx, path, indexEntries)
+}
+
+// getIndexChild returns the index bucket with the given name for the given path, or nil if it does not exist.
+func getIndexChild(tx *bbolt.Tx, path [][]byte, name string) *bbolt.Bucket {
+	bkt := tx.Bucket([]byte(indexBucket))
+
+	for _, p := range path {
+		if bkt == nil {
+			return nil
+		}
+		bkt = bkt.Bucket(p)
+	}
+
+	if bkt == nil {
+		return nil
+	}
+
+	return bkt.Bucket([]byte(name))
+}
+
+// deleteIndexTree removes the indexes for the bucket at the given path and all buckets nested within it.
+func deleteIndexTree(tx *bbolt.Tx, path [][]byte) error {
+	if len(path) == 0 {
+		if tx.Bucket([]byte(indexBucket)) == nil {
+			return nil
+		}
+		return tx.DeleteBucket([]byte(indexBucket))
+	}
+
+	bkt := tx.Bucket([]byte(indexBucket))
+	for _, p := range path[:len(path)-1] {
+		if bkt == nil {
+			return nil
+		}
+		bkt = bkt.Bucket(p)
+	}
+
+	if bkt == nil || bkt.Bucket(path[len(path)-1]) == nil {
+		return nil
+	}
+
+	return bkt.DeleteBucket(path[len(path)-1])
+}
+
+// uniqueIndexKey returns the key under which val is recorded in a unique index.
+//
+// Values are indexed by their SHA-256 digest so that empty values and values longer than
+// bbolt.MaxKeySize can be indexed like any other.
+func uniqueIndexKey(val []byte) []byte {
+	sum := sha256.Sum256(val)
+	return sum[:]
+}
+
+// holdsValue reports whether key is paired with val in the bucket at the given path.
+// It tells a value indexed under a digest apart from another value sharing that digest.
+func holdsValue(tx *bbolt.Tx, path [][]byte, key, val []byte) bool {
+	bkt, err := getBucket(tx, path, false)
+	if err != nil || bkt == nil {
+		return false
+	}
+
+	stored := bkt.Get(key)
+	return stored != nil && bytes.Equal(stored, val)
+}
+
+// putUniqueIndex records val as belonging to key, returning ErrDuplicateValue if another key already stores val.
+func putUniqueIndex(tx *bbolt.Tx, path [][]byte, key, old, val []byte) error {
+	idx, err := getCreateIndexBucket(tx, path)
+	if err != nil {
+		return fmt.Errorf("error while navigating index: %w", err)
+	}
+
+	h := uniqueIndexKey(val)
+
+	if owner := idx.Get(h); owner != nil && !bytes.Equal(owner, key) {
+		if holdsValue(tx, path, owner, val) {
+			return newErrDuplicateValue(fmt.Sprintf("value %s at %s", val, path))
+		}
+		return fmt.Errorf("error while indexing %s: digest collides with the value of key %s", val, owner)
+	}
+
+	if old != nil && !bytes.Equal(old, val) {
+		if err := deleteUniqueIndex(tx, path, key, old); err != nil {
+			return err
+		}
+	}
+
+	if err := idx.Put(h, key); err != nil {
+		return fmt.Errorf("error while indexing %s: %w", val, err)
+	}
+
+	return nil
+}
+
+// deleteUniqueIndex removes old from the index if it belongs to key.
+func deleteUniqueIndex(tx *bbolt.Tx, path [][]byte, key, old []byte) error {
+	idx := getIndexBucket(tx, path)
+	if idx == nil {
+		return nil
+	}
+
+	h := uniqueIndexKey(old)
+
+	if owner := idx.Get(h); owner != nil && bytes.Equal(owner, key) {
+		if err := idx.Delete(h); err != nil {
+			return fmt.Errorf("error while removing %s from index: %w", old, err)
+		}
+	}
+
+	return nil
+}
+
+// rebuildUniqueIndex discards the index for the given path and rebuilds it from the bucket's current contents.
+//
+// ErrDuplicateValue is returned if the bucket already contains duplicate values.
+func rebuildUniqueIndex(db *bbolt.DB, path [][]byte) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	return db.Update(func(tx *bbolt.Tx) error {
+		idx, err := getCreateIndexBucket(tx, path)
+		if err != nil {
+			return fmt.Errorf("error while navigating index: %w", err)
+		}
+
+		var stale [][]byte
+		idx.ForEach(func(k, _ []byte) error {
+			stale = append(stale, k)
+			return nil
+		})
+
+		for _, k := range stale {
+			if err := idx.Delete(k); err != nil {
+				return fmt.Errorf("error while clearing index: %w", err)
+			}
+		}
+
+		bkt, err := getCreateBucket(tx, path)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		c := bkt.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+
+			if err := putUniqueIndex(tx, path, k, nil, v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// getIndexedKey returns the key paired with the given value using the value index for the given path.
+// The returned key will be nil if the value could not be found.
+//
+// If mustExist is true, an error will be returned if the value could not be found.
+func getIndexedKey(db *bbolt.DB, value []byte, path [][]byte, mustExist bool) ([]byte, error) {
+	if db == nil {
+		return nil, fmt.Errorf("indexed key retrieval for %s received nil db", value)
+	}
+
+	var key []byte
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		if idx := getIndexBucket(tx, path); idx != nil {
+			if owner := idx.Get(uniqueIndexKey(value)); owner != nil && holdsValue(tx, path, owner, value) {
+				key = copyBytes(owner)
+			}
+		}
+
+		if key == nil && mustExist {
+			return newErrLocate(fmt.Sprintf("value %s at %#v", string(value), path))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("indexed key retrieval for %s experienced error while getting key: %w", value, err)
+	}
+	return key, nil
+}
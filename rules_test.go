@@ -0,0 +1,94 @@
+package quickbolt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func Test_dbWrapper_SetUnique(t *testing.T) {
+	db, err := Create("foo.db")
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"emails"}
+	var duplicate ErrDuplicateValue
+
+	assert.Nil(t, db.Insert("a", "x@example.com", path))
+	assert.Nil(t, db.SetUnique(path))
+
+	err = db.Insert("b", "x@example.com", path)
+	assert.True(t, errors.As(err, &duplicate))
+
+	// Rewriting the same pair is not a duplicate.
+	assert.Nil(t, db.Insert("a", "x@example.com", path))
+
+	// Changing a's value frees the old one.
+	assert.Nil(t, db.Insert("a", "y@example.com", path))
+	assert.Nil(t, db.Insert("b", "x@example.com", path))
+
+	k, err := db.GetKey("y@example.com", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("a"), k)
+
+	assert.Nil(t, db.Delete("a", path))
+	assert.Nil(t, db.InsertValue("y@example.com", path))
+
+	err = db.InsertValue("y@example.com", path)
+	assert.True(t, errors.As(err, &duplicate))
+
+	assert.Nil(t, db.DeleteValues("y@example.com", path))
+	assert.Nil(t, db.Insert("c", "y@example.com", path))
+
+	dupePath := []string{"dupes"}
+	assert.Nil(t, db.Insert("a", "same", dupePath))
+	assert.Nil(t, db.Insert("b", "same", dupePath))
+	assert.True(t, errors.As(db.SetUnique(dupePath), &duplicate))
+	assert.Nil(t, db.Insert("c", "same", dupePath))
+}
+
+func Test_dbWrapper_SetUnique_EdgeValues(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"blobs"}
+	var duplicate ErrDuplicateValue
+
+	large := strings.Repeat("x", bbolt.MaxKeySize+1)
+
+	// The index is rebuilt over values that cannot be bbolt keys.
+	assert.Nil(t, db.Insert("empty", "", path))
+	assert.Nil(t, db.Insert("large", large, path))
+	assert.Nil(t, db.SetUnique(path))
+
+	err = db.Insert("b", "", path)
+	assert.True(t, errors.As(err, &duplicate))
+
+	err = db.Insert("b", large, path)
+	assert.True(t, errors.As(err, &duplicate))
+
+	k, err := db.GetKey("", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("empty"), k)
+
+	k, err = db.GetKey(large, path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("large"), k)
+
+	// Changing and deleting the pairs frees their values.
+	assert.Nil(t, db.Insert("empty", "now set", path))
+	assert.Nil(t, db.Delete("large", path))
+	assert.Nil(t, db.Insert("b", "", path))
+	assert.Nil(t, db.Insert("c", large, path))
+
+	dupePath := []string{"dupes"}
+	assert.Nil(t, db.Insert("a", large, dupePath))
+	assert.Nil(t, db.Insert("b", large, dupePath))
+	assert.True(t, errors.As(db.SetUnique(dupePath), &duplicate))
+}
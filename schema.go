@@ -0,0 +1,76 @@
+package quickbolt
+
+import (
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// Schema describes the bucket tree expected in a database.
+type Schema struct {
+	// Buckets lists the paths of the buckets expected in the database.
+	// Every bucket along each path is created if it does not already exist.
+	Buckets [][]string
+	// Strict, if true, causes an error to be returned if the database contains
+	// a top-level bucket that is not part of any path in Buckets.
+	Strict bool
+}
+
+// WithSchema applies the given schema when the database is opened.
+func WithSchema(s Schema) Option {
+	return func(o *options) {
+		o.schema = &s
+	}
+}
+
+// applySchema creates the buckets described by the schema and, if the schema is strict,
+// verifies that no unknown top-level buckets exist.
+func applySchema(db *bbolt.DB, s Schema) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	known := make(map[string]bool)
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		for _, p := range s.Buckets {
+			path, err := resolveBucketPath(p)
+			if err != nil {
+				return fmt.Errorf("error while resolving %s: %w", p, err)
+			}
+
+			if _, err := getCreateBucket(tx, path); err != nil {
+				return fmt.Errorf("error while creating %s: %w", p, err)
+			}
+
+			if len(p) > 0 {
+				known[p[0]] = true
+			}
+		}
+
+		if !s.Strict {
+			return nil
+		}
+
+		root, err := tx.CreateBucketIfNotExists([]byte(rootBucket))
+		if err != nil {
+			return fmt.Errorf("error while accessing root bucket: %w", err)
+		}
+
+		c := root.Cursor()
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil && !known[string(k)] {
+				return newErrSchemaViolation(fmt.Sprintf("bucket %s", k))
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("error while applying schema: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package quickbolt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func TestWithSchema(t *testing.T) {
+	dir := t.TempDir()
+	schema := Schema{Buckets: [][]string{{"users", "active"}, {"orders"}}, Strict: true}
+
+	db, err := CreateWith("foo.db", dir, WithSchema(schema))
+	assert.Nil(t, err)
+
+	err = db.RunView(func(tx *bbolt.Tx) error {
+		_, err := getBucket(tx, [][]byte{[]byte("users"), []byte("active")}, true)
+		return err
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, db.Insert("foo", "bar", []string{"userz"}))
+	assert.Nil(t, db.Close())
+
+	_, err = OpenWith("foo.db", dir, WithSchema(schema))
+	var violation ErrSchemaViolation
+	assert.True(t, errors.As(err, &violation))
+
+	schema.Strict = false
+	db, err = OpenWith("foo.db", dir, WithSchema(schema))
+	assert.Nil(t, err)
+	assert.Nil(t, db.RemoveFile())
+}
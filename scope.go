@@ -0,0 +1,25 @@
+package quickbolt
+
+// scopePath returns the given path prefixed with the wrapper's scope, if it has one.
+func (d *dbWrapper) scopePath(path [][]byte) [][]byte {
+	if len(d.scope) == 0 {
+		return path
+	}
+
+	return append(append(make([][]byte, 0, len(d.scope)+len(path)), d.scope...), path...)
+}
+
+// resolveBucketPath resolves the given bucket path relative to the wrapper's scope,
+// normalizing string segments if WithUnicodeNormalization is used.
+func (d *dbWrapper) resolveBucketPath(p any) ([][]byte, error) {
+	if d.scopeErr != nil {
+		return nil, d.scopeErr
+	}
+
+	resolved, err := resolveNormalizedPath(p, d.opts.normalization.normalizer())
+	if err != nil {
+		return nil, err
+	}
+
+	return d.scopePath(resolved), nil
+}
@@ -0,0 +1,50 @@
+package quickbolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_Scope(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	users := db.Scope([]string{"tenants", "acme"}).Scope([]string{"users"})
+	assert.Nil(t, users.Insert("alice", "1", []string{"ids"}))
+
+	v, err := db.GetValue("alice", []string{"tenants", "acme", "users", "ids"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+
+	v, err = users.GetValue("alice", []string{"ids"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+
+	assert.Nil(t, users.Apply([]Op{InsertOp("bob", "2", []string{"ids"})}))
+	v, err = db.GetValue("bob", []string{"tenants", "acme", "users", "ids"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("2"), v)
+
+	assert.Nil(t, users.SetBucketCodec([]string{"profiles"}, prefixCodec{prefix: "bkt:"}))
+	assert.Nil(t, Put(users, "alice", 30, []string{"profiles"}))
+	raw, err := db.GetValue("alice", []string{"tenants", "acme", "users", "profiles"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "bkt:30", string(raw))
+	age, err := Get[int](db.Scope([]string{"tenants", "acme", "users"}), "alice", []string{"profiles"})
+	assert.Nil(t, err)
+	assert.Equal(t, 30, age)
+
+	tx, err := users.BeginRead()
+	assert.Nil(t, err)
+	v, err = tx.Get("bob", []string{"ids"})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("2"), v)
+	assert.Nil(t, tx.Rollback())
+
+	invalid := db.Scope(42)
+	_, err = invalid.GetValue("alice", []string{"ids"}, false)
+	assert.ErrorIs(t, err, ErrBucketPathResolution{What: "error"})
+}
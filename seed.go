@@ -0,0 +1,108 @@
+package quickbolt
+
+import (
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// SeedFunc populates the db within the given transaction.
+//
+// Use the RootBucket method to get the database's root bucket.
+type SeedFunc func(tx *bbolt.Tx) error
+
+// Seeder runs named seed funcs exactly once per database.
+//
+// Completion of each seed is recorded in the same transaction the seed runs in,
+// so a seed that fails is rolled back and attempted again on the next Run.
+type Seeder struct {
+	db    DB
+	seeds []namedSeed
+}
+
+type namedSeed struct {
+	name string
+	fn   SeedFunc
+}
+
+// NewSeeder returns a Seeder for the given db.
+func NewSeeder(db DB) *Seeder {
+	return &Seeder{db: db}
+}
+
+// Add registers a seed under the given name. Seeds are run in the order they were added.
+//
+// A seed's name identifies it across runs and must not change once the seed has been deployed.
+func (s *Seeder) Add(name string, fn SeedFunc) *Seeder {
+	s.seeds = append(s.seeds, namedSeed{name: name, fn: fn})
+	return s
+}
+
+// Run executes every seed that has not yet completed on the db and returns the names of those that ran.
+//
+// Run stops at the first seed that fails.
+func (s *Seeder) Run() ([]string, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("seeding received nil db")
+	}
+
+	seen := make(map[string]bool, len(s.seeds))
+	for _, sd := range s.seeds {
+		if sd.name == "" {
+			return nil, fmt.Errorf("seeding received seed without a name")
+		} else if sd.fn == nil {
+			return nil, fmt.Errorf("seeding received nil func for seed %s", sd.name)
+		} else if seen[sd.name] {
+			return nil, fmt.Errorf("seeding received seed %s more than once", sd.name)
+		}
+		seen[sd.name] = true
+	}
+
+	var ran []string
+	for _, sd := range s.seeds {
+		applied := false
+
+		err := s.db.RunUpdate(func(tx *bbolt.Tx) error {
+			bkt, err := getCreateMetaBucket(tx, seedsBucket)
+			if err != nil {
+				return err
+			}
+
+			if bkt.Get([]byte(sd.name)) != nil {
+				return nil
+			}
+
+			if err := sd.fn(tx); err != nil {
+				return err
+			}
+
+			applied = true
+			return bkt.Put([]byte(sd.name), []byte(time.Now().UTC().Format(time.RFC3339Nano)))
+		})
+		if err != nil {
+			return ran, fmt.Errorf("seed %s experienced error: %w", sd.name, err)
+		}
+
+		if applied {
+			ran = append(ran, sd.name)
+		}
+	}
+
+	return ran, nil
+}
+
+// getCreateMetaBucket returns the bucket with the given name within the meta bucket, creating both if necessary.
+func getCreateMetaBucket(tx *bbolt.Tx, name string) (*bbolt.Bucket, error) {
+	meta, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+	if err != nil {
+		return nil, fmt.Errorf("error while accessing meta bucket: %w", err)
+	}
+
+	bkt, err := meta.CreateBucketIfNotExists([]byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("error while accessing %s within meta bucket: %w", name, err)
+	}
+
+	return bkt, nil
+}
@@ -0,0 +1,58 @@
+package quickbolt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func TestSeeder_Run(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	calls := 0
+	defaults := func(tx *bbolt.Tx) error {
+		calls++
+		bkt, err := getCreateBucket(tx, [][]byte{[]byte("settings")})
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte("theme"), []byte("dark"))
+	}
+	failing := func(tx *bbolt.Tx) error {
+		bkt, err := getCreateBucket(tx, [][]byte{[]byte("settings")})
+		if err != nil {
+			return err
+		}
+		if err := bkt.Put([]byte("partial"), []byte("x")); err != nil {
+			return err
+		}
+		return fmt.Errorf("boom")
+	}
+
+	ran, err := NewSeeder(db).Add("defaults", defaults).Run()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"defaults"}, ran)
+
+	assert.Nil(t, db.Insert("theme", "light", []string{"settings"}))
+
+	ran, err = NewSeeder(db).Add("defaults", defaults).Add("failing", failing).Run()
+	assert.NotNil(t, err)
+	assert.Empty(t, ran)
+	assert.Equal(t, 1, calls)
+
+	v, err := db.GetValue("theme", []string{"settings"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "light", string(v))
+
+	v, err = db.GetValue("partial", []string{"settings"}, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	_, err = NewSeeder(db).Add("a", defaults).Add("a", defaults).Run()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,207 @@
+package quickbolt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// ShardedBucket distributes its keys across a fixed number of sub-buckets by key hash.
+//
+// Splitting a very large bucket keeps each sub-bucket's B+tree shallow and limits rebalancing to
+// the shard being written. Iteration visits the shards in turn, so keys are not returned in global order.
+//
+// Validators and other rules registered for the sharded bucket's path do not apply to its shards.
+type ShardedBucket struct {
+	db     DB
+	path   [][]byte
+	shards int
+}
+
+// NewShardedBucket returns a ShardedBucket spreading keys across the given number of shards at the given path.
+//
+// The shard count is recorded in the db on first use, and an error is returned if a later call gives a different one.
+//
+// BucketPath must be of type []string, [][]byte, []any, or Path.
+func NewShardedBucket(db DB, bucketPath any, shards int) (*ShardedBucket, error) {
+	if db == nil {
+		return nil, fmt.Errorf("sharded bucket creation received nil db")
+	} else if shards < 1 || shards > 0xffff {
+		return nil, fmt.Errorf("sharded bucket creation received invalid shard count %d", shards)
+	}
+
+	p, err := resolveBucketPath(bucketPath)
+	if err != nil {
+		return nil, fmt.Errorf("sharded bucket creation experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	err = db.RunUpdate(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateMetaBucket(tx, shardsBucket)
+		if err != nil {
+			return err
+		}
+
+		if existing := bkt.Get([]byte(pathKey(p))); existing != nil {
+			if n := int(binary.BigEndian.Uint16(existing)); n != shards {
+				return fmt.Errorf("bucket is already sharded %d ways", n)
+			}
+			return nil
+		}
+
+		n := make([]byte, 2)
+		binary.BigEndian.PutUint16(n, uint16(shards))
+		return bkt.Put([]byte(pathKey(p)), n)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sharded bucket creation for %s experienced error: %w", p, err)
+	}
+
+	return &ShardedBucket{db: db, path: p, shards: shards}, nil
+}
+
+// shardPath returns the path of the shard holding the given key.
+func (s *ShardedBucket) shardPath(key []byte) [][]byte {
+	h := fnv.New32a()
+	h.Write(key)
+
+	return s.pathOf(int(h.Sum32() % uint32(s.shards)))
+}
+
+// pathOf returns the path of the shard with the given index.
+func (s *ShardedBucket) pathOf(shard int) [][]byte {
+	name := make([]byte, 2)
+	binary.BigEndian.PutUint16(name, uint16(shard))
+
+	return append(append(make([][]byte, 0, len(s.path)+1), s.path...), name)
+}
+
+// Get returns the value paired with the given key, or nil if the key could not be found.
+//
+// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+func (s *ShardedBucket) Get(key any) ([]byte, error) {
+	k, err := resolveRecord(key)
+	if err != nil {
+		return nil, fmt.Errorf("sharded value retrieval %w", newErrRecordResolution("key", key))
+	}
+
+	return s.db.GetValue(k, s.shardPath(k), false)
+}
+
+// Insert writes the key-value pair to the key's shard.
+//
+// Key and value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+func (s *ShardedBucket) Insert(key, value any) error {
+	k, err := resolveRecord(key)
+	if err != nil {
+		return fmt.Errorf("sharded insert %w", newErrRecordResolution("key", key))
+	}
+
+	return s.db.Insert(k, value, s.shardPath(k))
+}
+
+// Upsert merges the value into any existing value for the key in its shard.
+//
+// Key and value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+func (s *ShardedBucket) Upsert(key, value any, add MergeFunc) error {
+	k, err := resolveRecord(key)
+	if err != nil {
+		return fmt.Errorf("sharded upsert %w", newErrRecordResolution("key", key))
+	}
+
+	return s.db.Upsert(k, value, s.shardPath(k), add)
+}
+
+// Delete removes the key from its shard.
+//
+// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+func (s *ShardedBucket) Delete(key any) error {
+	k, err := resolveRecord(key)
+	if err != nil {
+		return fmt.Errorf("sharded deletion %w", newErrRecordResolution("key", key))
+	}
+
+	return s.db.Delete(k, s.shardPath(k))
+}
+
+// EntriesAt returns the key-value pairs of every shard.
+func (s *ShardedBucket) EntriesAt(buffer chan [2][]byte) error {
+	if buffer == nil {
+		return fmt.Errorf("sharded key-value iteration at %s received nil channel", s.path)
+	}
+	defer close(buffer)
+
+	return s.each(func(k, v []byte, timeout time.Duration) error {
+		return sendWithTimeout(buffer, [2][]byte{k, v}, timeout, "sharded key-value iteration")
+	})
+}
+
+// KeysAt returns the keys of every shard.
+func (s *ShardedBucket) KeysAt(buffer chan []byte) error {
+	if buffer == nil {
+		return fmt.Errorf("sharded key iteration at %s received nil channel", s.path)
+	}
+	defer close(buffer)
+
+	return s.each(func(k, v []byte, timeout time.Duration) error {
+		return sendWithTimeout(buffer, k, timeout, "sharded key iteration")
+	})
+}
+
+// each calls visit with a copy of every pair in every shard within a single read transaction.
+func (s *ShardedBucket) each(visit func(k, v []byte, timeout time.Duration) error) error {
+	timeout := bufferTimeoutOf(s.db)
+
+	err := s.db.RunView(func(tx *bbolt.Tx) error {
+		for i := 0; i < s.shards; i++ {
+			bkt, err := getBucket(tx, s.pathOf(i), false)
+			if err != nil {
+				return err
+			} else if bkt == nil {
+				continue
+			}
+
+			c := bkt.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				if v == nil {
+					continue
+				}
+				if err := visit(copyBytes(k), copyBytes(v), timeout); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("sharded iteration at %s experienced error while scanning shards: %w", s.path, err)
+	}
+	return nil
+}
+
+// sendWithTimeout sends the item to the buffer, returning an ErrTimeout for the given task if it isn't received within the timeout.
+func sendWithTimeout[T any](buffer chan T, item T, timeout time.Duration, task string) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case buffer <- item:
+		return nil
+	case <-timer.C:
+		return newErrTimeout(task, "waiting to send to buffer")
+	}
+}
+
+// bufferTimeoutOf returns the buffer timeout of the db.
+//
+// The default is used for DB implementations from outside this package.
+func bufferTimeoutOf(db DB) time.Duration {
+	if d, ok := db.(*dbWrapper); ok && d.bufferTimeout > 0 {
+		return d.bufferTimeout
+	}
+
+	return defaultBufferTimeout
+}
@@ -0,0 +1,56 @@
+package quickbolt
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedBucket(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	s, err := NewShardedBucket(db, []string{"hot"}, 8)
+	assert.Nil(t, err)
+
+	for i := 0; i < 50; i++ {
+		assert.Nil(t, s.Insert(strconv.Itoa(i), "v"+strconv.Itoa(i)))
+	}
+	assert.Nil(t, s.Delete("7"))
+
+	v, err := s.Get("42")
+	assert.Nil(t, err)
+	assert.Equal(t, "v42", string(v))
+
+	v, err = s.Get("7")
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	buckets := make(chan []byte)
+	go func() { assert.Nil(t, db.BucketsAt([]string{"hot"}, true, buckets)) }()
+	shards := 0
+	for range buckets {
+		shards++
+	}
+	assert.Greater(t, shards, 1, "keys must be spread across shards")
+
+	keys := make(chan []byte)
+	go func() { assert.Nil(t, s.KeysAt(keys)) }()
+	var got []int
+	for k := range keys {
+		i, err := strconv.Atoi(string(k))
+		assert.Nil(t, err)
+		got = append(got, i)
+	}
+	sort.Ints(got)
+	assert.Len(t, got, 49)
+
+	_, err = NewShardedBucket(db, []string{"hot"}, 8)
+	assert.Nil(t, err)
+	_, err = NewShardedBucket(db, []string{"hot"}, 4)
+	assert.NotNil(t, err, "shard count must not change once recorded")
+}
@@ -1,19 +1,172 @@
 package quickbolt
 
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
 type Size interface {
 	Megabytes() int
+	Bytes() int64
 }
 
 type sizeStore struct {
-	mb int
+	bytes int64
 }
 
-func newSizeStore(mb int) sizeStore {
+func newSizeStore(bytes int64) sizeStore {
 	return sizeStore{
-		mb: mb,
+		bytes: bytes,
 	}
 }
 
 func (s sizeStore) Megabytes() int {
-	return s.mb
+	return int(s.bytes / 1048576)
+}
+
+func (s sizeStore) Bytes() int64 {
+	return s.bytes
+}
+
+// sizeCheckInterval is the time between the file size checks made for OnSizeExceeds.
+var sizeCheckInterval = 10 * time.Second
+
+// dbFileSize returns the size of the database file.
+func dbFileSize(db *bbolt.DB) (int64, error) {
+	if db == nil {
+		return 0, fmt.Errorf("db is nil")
+	}
+
+	stats, err := os.Stat(db.Path())
+	if err != nil {
+		return 0, fmt.Errorf("error while reading file info: %w", err)
+	}
+
+	return stats.Size(), nil
+}
+
+// watchSize calls f each time the file size crosses above the threshold, checking immediately and then
+// every sizeCheckInterval until background work is stopped.
+func (d *dbWrapper) watchSize(threshold int64, f func(Size)) {
+	ticker := time.NewTicker(sizeCheckInterval)
+	defer ticker.Stop()
+
+	above := false
+	for {
+		db, release := d.acquire()
+		size, err := dbFileSize(db)
+		release()
+
+		select {
+		case <-d.state.stop:
+			// The file may have been removed while the size was read.
+			return
+		default:
+		}
+
+		if err != nil {
+			logMutex.Lock()
+			d.logger.Err(err).Str("operation", "OnSizeExceeds").Msg("size check")
+			logMutex.Unlock()
+			d.notifyErr("OnSizeExceeds", nil, err)
+		} else {
+			if size > threshold && !above {
+				f(newSizeStore(size))
+			}
+			above = size > threshold
+		}
+
+		select {
+		case <-d.state.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SizeBreakdown describes the approximate on-disk footprint of a bucket and everything nested under it.
+type SizeBreakdown struct {
+	// Bytes is the total size of the pages allocated to the subtree.
+	Bytes int
+	// LeafBytes is the size of the leaf pages allocated to the subtree.
+	LeafBytes int
+	// BranchBytes is the size of the branch pages allocated to the subtree.
+	BranchBytes int
+	// InUse is the number of allocated bytes holding data, including buckets stored inline in their parent.
+	InUse int
+	// Keys is the number of key-value pairs in the subtree.
+	Keys int
+	// Buckets is the number of buckets nested under the subtree's root.
+	Buckets int
+	// Depth is the maximum page depth of the subtree.
+	Depth int
+}
+
+// sizeOf returns the size breakdown of the bucket at the given path.
+func sizeOf(db *bbolt.DB, path [][]byte) (SizeBreakdown, error) {
+	if db == nil {
+		return SizeBreakdown{}, fmt.Errorf("size estimation for %s received nil db", path)
+	}
+
+	var s bbolt.BucketStats
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		s = bkt.Stats()
+		return nil
+	})
+	if err != nil {
+		return SizeBreakdown{}, fmt.Errorf("size estimation for %s experienced error while reading stats: %w", path, err)
+	}
+
+	return newSizeBreakdown(s), nil
+}
+
+// bucketSizes returns the size breakdown of each bucket directly within the bucket at the given path, by name.
+func bucketSizes(db *bbolt.DB, path [][]byte) (map[string]SizeBreakdown, error) {
+	if db == nil {
+		return nil, fmt.Errorf("size estimation for buckets in %s received nil db", path)
+	}
+
+	sizes := make(map[string]SizeBreakdown)
+	err := db.View(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		return bkt.ForEach(func(k, v []byte) error {
+			if v == nil {
+				sizes[string(k)] = newSizeBreakdown(bkt.Bucket(k).Stats())
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("size estimation for buckets in %s experienced error while reading stats: %w", path, err)
+	}
+
+	return sizes, nil
+}
+
+// newSizeBreakdown returns the size breakdown described by the stats of a bucket.
+func newSizeBreakdown(s bbolt.BucketStats) SizeBreakdown {
+	// Stats counts the bucket itself, and every nested bucket also occupies a key in its parent.
+	return SizeBreakdown{
+		Bytes:       s.LeafAlloc + s.BranchAlloc,
+		LeafBytes:   s.LeafAlloc,
+		BranchBytes: s.BranchAlloc,
+		InUse:       s.LeafInuse + s.BranchInuse + s.InlineBucketInuse,
+		Keys:        s.KeyN - (s.BucketN - 1),
+		Buckets:     s.BucketN - 1,
+		Depth:       s.Depth,
+	}
 }
@@ -0,0 +1,99 @@
+package quickbolt
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func Test_dbWrapper_SizeOf(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	value := []byte(strings.Repeat("x", 256))
+	err = db.RunUpdate(func(tx *bbolt.Tx) error {
+		for _, path := range [][][]byte{
+			{[]byte("big")},
+			{[]byte("big"), []byte("nested")},
+			{[]byte("small")},
+		} {
+			bkt, err := getCreateBucket(tx, path)
+			if err != nil {
+				return err
+			}
+			n := 100
+			if string(path[0]) == "small" {
+				n = 1
+			}
+			for i := 0; i < n; i++ {
+				if err := bkt.Put([]byte(strconv.Itoa(i)), value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	big, err := db.SizeOf([]string{"big"})
+	assert.Nil(t, err)
+	assert.Equal(t, 200, big.Keys)
+	assert.Equal(t, 1, big.Buckets)
+	assert.Equal(t, big.LeafBytes+big.BranchBytes, big.Bytes)
+
+	small, err := db.SizeOf([]string{"small"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, small.Keys)
+	assert.Equal(t, 0, small.Buckets)
+	assert.Less(t, small.InUse, big.InUse)
+
+	_, err = db.SizeOf([]string{"missing"})
+	assert.NotNil(t, err)
+}
+
+func Test_dbWrapper_OnSizeExceeds(t *testing.T) {
+	defer func(interval time.Duration) { sizeCheckInterval = interval }(sizeCheckInterval)
+	sizeCheckInterval = 10 * time.Millisecond
+
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	exceeded := make(chan Size, 10)
+	db.OnSizeExceeds(db.Size().Bytes()+1<<20, func(s Size) { exceeded <- s })
+
+	time.Sleep(5 * sizeCheckInterval)
+	assert.Len(t, exceeded, 0)
+
+	value := []byte(strings.Repeat("x", 4096))
+	err = db.RunUpdate(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateBucket(tx, [][]byte{[]byte("big")})
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 1024; i++ {
+			if err := bkt.Put([]byte(strconv.Itoa(i)), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	select {
+	case s := <-exceeded:
+		assert.Greater(t, s.Bytes(), int64(1<<20))
+	case <-time.After(time.Second):
+		t.Fatal("size threshold crossing was not reported")
+	}
+
+	time.Sleep(5 * sizeCheckInterval)
+	assert.Len(t, exceeded, 0, "a crossing should be reported once")
+}
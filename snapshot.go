@@ -0,0 +1,219 @@
+package quickbolt
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// snapshotServer serves read-only queries from a periodically refreshed copy of a database,
+// so that queries never open transactions on the database itself.
+type snapshotServer struct {
+	src *dbWrapper
+	// dir is the temporary directory holding the snapshot files.
+	dir string
+
+	mu    sync.RWMutex
+	snap  *bbolt.DB
+	taken time.Time
+	// n numbers the snapshot files, so that a refresh never overwrites the file being served.
+	n int
+}
+
+// snapshotInfo is the body served by the snapshot server's info page.
+type snapshotInfo struct {
+	Source string    `json:"source"`
+	Taken  time.Time `json:"taken"`
+	Size   int64     `json:"size"`
+}
+
+// newSnapshotServer returns a server for the wrapper's database, having taken its first snapshot.
+func newSnapshotServer(d *dbWrapper) (*snapshotServer, error) {
+	dir, err := os.MkdirTemp("", "quickbolt-snapshot-*")
+	if err != nil {
+		return nil, fmt.Errorf("error while creating snapshot directory: %w", err)
+	}
+
+	s := &snapshotServer{src: d, dir: dir}
+	if err := s.refresh(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// snapshotOp is the operation the reads made to refresh snapshots are tracked under.
+var snapshotOp = &operation{name: "ServeSnapshot"}
+
+// refresh copies the source database to a new file and swaps it in place of the snapshot being served.
+func (s *snapshotServer) refresh() error {
+	s.n++
+	path := filepath.Join(s.dir, fmt.Sprintf("snapshot-%d.db", s.n))
+
+	db, release := s.src.acquire()
+	untrack := s.src.trackRead(snapshotOp)
+	err := db.View(func(tx *bbolt.Tx) error { return tx.CopyFile(path, defaultFileMode) })
+	untrack()
+	release()
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("error while copying db to snapshot: %w", err)
+	}
+
+	snap, err := bbolt.Open(path, defaultFileMode, &bbolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("error while opening snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	old := s.snap
+	s.snap, s.taken = snap, time.Now()
+	s.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+		os.Remove(old.Path())
+	}
+
+	return nil
+}
+
+// close closes the snapshot being served and removes the snapshot files.
+func (s *snapshotServer) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.snap != nil {
+		s.snap.Close()
+		s.snap = nil
+	}
+	os.RemoveAll(s.dir)
+}
+
+// handler returns the handler serving queries against the snapshot.
+func (s *snapshotServer) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprintf(w, "quickbolt snapshot of %s\n\n", s.src.Path())
+		fmt.Fprintf(w, "/info\t\tsource, time taken, and size of the snapshot\n")
+		fmt.Fprintf(w, "/keys?path=a&path=b&after=k&limit=n\tbuckets and key-value pairs within a bucket\n")
+		fmt.Fprintf(w, "/value?path=a&path=b&key=k\tvalue of a key\n")
+	})
+
+	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		info := snapshotInfo{Source: s.src.Path(), Taken: s.taken}
+		if fi, err := os.Stat(s.snap.Path()); err == nil {
+			info.Size = fi.Size()
+		}
+		writeDebugJSON(w, info)
+	})
+
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		limit := debugBrowseLimit
+		if l := r.URL.Query().Get("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n <= 0 {
+				http.Error(w, fmt.Sprintf("invalid limit %q", l), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		s.mu.RLock()
+		page, err := browse(s.snap, debugPath(r), []byte(r.URL.Query().Get("after")), limit)
+		s.mu.RUnlock()
+		if err != nil {
+			writeDebugError(w, err)
+			return
+		}
+		writeDebugJSON(w, page)
+	})
+
+	mux.HandleFunc("/value", func(w http.ResponseWriter, r *http.Request) {
+		key := []byte(r.URL.Query().Get("key"))
+
+		// The value is rendered under the lock, as a refresh closes the snapshot it was read from.
+		s.mu.RLock()
+		v, err := getValue(s.snap, key, debugPath(r), true)
+		entry := debugEntry{Key: string(key), Value: render(v, RenderAuto, 0)}
+		s.mu.RUnlock()
+		if err != nil {
+			if errors.Is(err, ErrLocate{}) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			writeDebugError(w, err)
+			return
+		}
+		writeDebugJSON(w, entry)
+	})
+
+	return mux
+}
+
+// serveSnapshot serves queries against snapshots of the wrapper's database on the listener,
+// refreshing the snapshot every interval until the database is closed or the server fails.
+func (d *dbWrapper) serveSnapshot(ln net.Listener, interval time.Duration) error {
+	s, err := newSnapshotServer(d)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	defer s.close()
+
+	srv := &http.Server{Handler: s.handler()}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer close(done)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-d.state.stop:
+				srv.Close()
+				return
+			case <-ticker.C:
+				if err := s.refresh(); err != nil {
+					logMutex.Lock()
+					d.logger.Err(err).Str("operation", "ServeSnapshot").Msg("snapshot refresh")
+					logMutex.Unlock()
+				}
+			}
+		}
+	}()
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
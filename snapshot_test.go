@@ -0,0 +1,67 @@
+package quickbolt
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbWrapper_ServeSnapshot(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("k1", "v1", []string{"a"}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	errc := make(chan error, 1)
+	go func() { errc <- db.(*dbWrapper).serveSnapshot(ln, time.Millisecond*20) }()
+
+	get := func(path string, v any) int {
+		resp, err := http.Get("http://" + ln.Addr().String() + path)
+		if !assert.Nil(t, err) {
+			return 0
+		}
+		defer resp.Body.Close()
+
+		if v != nil && resp.StatusCode == http.StatusOK {
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	var e debugEntry
+	assert.Equal(t, http.StatusOK, get("/value?path=a&key=k1", &e))
+	assert.Equal(t, `"v1"`, e.Value)
+	assert.Equal(t, http.StatusNotFound, get("/value?path=a&key=k2", nil))
+	assert.Equal(t, http.StatusNotFound, get("/value?path=missing&key=k1", nil))
+
+	assert.Nil(t, db.Insert("k2", "v2", []string{"a"}))
+	assert.Eventually(t, func() bool { return get("/value?path=a&key=k2", nil) == http.StatusOK }, time.Second, time.Millisecond*10)
+
+	var page debugPage
+	assert.Equal(t, http.StatusOK, get("/keys?path=a", &page))
+	assert.Len(t, page.Entries, 2)
+
+	var info snapshotInfo
+	assert.Equal(t, http.StatusOK, get("/info", &info))
+	assert.Equal(t, db.Path(), info.Source)
+	assert.Greater(t, info.Size, int64(0))
+
+	assert.Nil(t, db.Close())
+	select {
+	case err := <-errc:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("serving must end once the db is closed")
+	}
+
+	assert.NotNil(t, db.ServeSnapshot("127.0.0.1:0", 0))
+}
@@ -0,0 +1,86 @@
+package quickbolt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// snowflakeEpoch is the time from which snowflake timestamps are measured, giving them about 69 years of range.
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	// MaxSnowflakeNode is the largest node ID accepted by NewSnowflakeGenerator.
+	MaxSnowflakeNode = 1<<snowflakeNodeBits - 1
+)
+
+// NewSnowflakeGenerator returns a KeyGenerator of snowflake IDs for the given node: 8 byte keys made of
+// a 41 bit millisecond timestamp, the 10 bit node ID, and a 12 bit sequence, in that order.
+//
+// Keys sort by creation time and, as long as each process writing keys is given its own node ID,
+// never collide across dbs, so that dbs written separately can later be merged.
+//
+// A generator produces up to 4096 keys per millisecond, after which it borrows from the next millisecond
+// rather than waiting. Should the clock move backwards, the previous timestamp is reused.
+func NewSnowflakeGenerator(node uint16) (KeyGenerator, error) {
+	if node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("snowflake node %d exceeds maximum of %d", node, MaxSnowflakeNode)
+	}
+
+	var (
+		mu     sync.Mutex
+		lastMs int64
+		seq    uint64
+	)
+
+	return func() ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		ms := time.Since(snowflakeEpoch).Milliseconds()
+		if ms < 0 {
+			return nil, fmt.Errorf("snowflake generation experienced error: clock precedes epoch %s", snowflakeEpoch)
+		}
+
+		if ms <= lastMs {
+			ms = lastMs
+			seq++
+			if seq == 1<<snowflakeSeqBits {
+				ms++
+				seq = 0
+			}
+		} else {
+			seq = 0
+		}
+		lastMs = ms
+
+		id := uint64(ms)<<(snowflakeNodeBits+snowflakeSeqBits) | uint64(node)<<snowflakeSeqBits | seq
+
+		return SortableUint64(id), nil
+	}, nil
+}
+
+// ParseSnowflake returns the creation time and node ID encoded in a key generated by NewSnowflakeGenerator.
+func ParseSnowflake(key []byte) (time.Time, uint16, error) {
+	if len(key) != 8 {
+		return time.Time{}, 0, fmt.Errorf("snowflake %x has length %d rather than 8", key, len(key))
+	}
+
+	id := binary.BigEndian.Uint64(key)
+	ms := int64(id >> (snowflakeNodeBits + snowflakeSeqBits))
+	node := uint16(id >> snowflakeSeqBits & MaxSnowflakeNode)
+
+	return snowflakeEpoch.Add(time.Duration(ms) * time.Millisecond), node, nil
+}
+
+// WithKeyGenerator sets the KeyGenerator used by InsertValue, and by InsertValueWith when opts.Generator is nil,
+// in place of each bucket's sequence. Keys generated via the db's sequence collide across dbs, whereas keys
+// from e.g. NewSnowflakeGenerator, given a node ID per db, do not.
+func WithKeyGenerator(gen KeyGenerator) Option {
+	return func(o *options) {
+		o.keyGenerator = gen
+	}
+}
@@ -0,0 +1,71 @@
+package quickbolt
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSnowflakeGenerator(t *testing.T) {
+	_, err := NewSnowflakeGenerator(MaxSnowflakeNode + 1)
+	assert.NotNil(t, err)
+
+	gen, err := NewSnowflakeGenerator(42)
+	assert.Nil(t, err)
+
+	before := time.Now().Truncate(time.Millisecond)
+	var prev []byte
+	for i := 0; i < 10000; i++ {
+		id, err := gen()
+		assert.Nil(t, err)
+
+		if prev != nil {
+			assert.Equal(t, -1, bytes.Compare(prev, id))
+		}
+		prev = id
+	}
+
+	created, node, err := ParseSnowflake(prev)
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(42), node)
+	assert.False(t, created.Before(before))
+
+	_, _, err = ParseSnowflake([]byte("short"))
+	assert.NotNil(t, err)
+}
+
+func TestWithKeyGenerator(t *testing.T) {
+	dir := t.TempDir()
+
+	var dbs []DB
+	for node := uint16(1); node <= 2; node++ {
+		gen, err := NewSnowflakeGenerator(node)
+		assert.Nil(t, err)
+
+		db, err := CreateWith("node.db", filepath.Join(dir, fmt.Sprint(node)), WithKeyGenerator(gen))
+		assert.Nil(t, err)
+		defer db.RemoveFile()
+
+		assert.Nil(t, db.InsertValue("v", []string{"events"}))
+		dbs = append(dbs, db)
+	}
+
+	var keys [][]byte
+	for i, db := range dbs {
+		buffer := make(chan []byte, 1)
+		assert.Nil(t, db.KeysAt([]string{"events"}, true, buffer))
+		for k := range buffer {
+			_, node, err := ParseSnowflake(k)
+			assert.Nil(t, err)
+			assert.Equal(t, uint16(i+1), node)
+			keys = append(keys, k)
+		}
+	}
+
+	assert.Len(t, keys, 2)
+	assert.NotEqual(t, keys[0], keys[1])
+}
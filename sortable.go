@@ -0,0 +1,72 @@
+package quickbolt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// SortableUint64 encodes the integer as 8 big-endian bytes, so that encoded integers sort in numeric order.
+//
+// Unlike keys resolved from uint64, which follow the host's byte order, the encoding is the same on every host.
+func SortableUint64(u uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, u)
+	return b
+}
+
+// ParseSortableUint64 decodes an integer encoded by SortableUint64.
+func ParseSortableUint64(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("sortable uint64 %x has length %d rather than 8", b, len(b))
+	}
+
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// SortableInt64 encodes the integer as 8 big-endian bytes with the sign bit flipped,
+// so that encoded integers, negative ones included, sort in numeric order.
+func SortableInt64(i int64) []byte {
+	return SortableUint64(uint64(i) ^ 1<<63)
+}
+
+// ParseSortableInt64 decodes an integer encoded by SortableInt64.
+func ParseSortableInt64(b []byte) (int64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("sortable int64 %x has length %d rather than 8", b, len(b))
+	}
+
+	return int64(binary.BigEndian.Uint64(b) ^ 1<<63), nil
+}
+
+// SortableFloat64 encodes the float as 8 big-endian bytes, so that encoded floats sort in numeric order.
+//
+// Positive floats have their sign bit flipped and negative floats have every bit flipped,
+// placing negatives before positives and reversing the order of negatives' magnitudes.
+// -0 sorts immediately before +0, infinities sort at either end, and NaNs sort beyond the infinity of their sign.
+func SortableFloat64(f float64) []byte {
+	u := math.Float64bits(f)
+	if u&(1<<63) != 0 {
+		u = ^u
+	} else {
+		u ^= 1 << 63
+	}
+
+	return SortableUint64(u)
+}
+
+// ParseSortableFloat64 decodes a float encoded by SortableFloat64.
+func ParseSortableFloat64(b []byte) (float64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("sortable float64 %x has length %d rather than 8", b, len(b))
+	}
+
+	u := binary.BigEndian.Uint64(b)
+	if u&(1<<63) != 0 {
+		u ^= 1 << 63
+	} else {
+		u = ^u
+	}
+
+	return math.Float64frombits(u), nil
+}
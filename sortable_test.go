@@ -0,0 +1,64 @@
+package quickbolt
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortableUint64(t *testing.T) {
+	values := []uint64{0, 1, 255, 256, 1 << 32, math.MaxUint64}
+	for i, u := range values {
+		b := SortableUint64(u)
+		got, err := ParseSortableUint64(b)
+		assert.Nil(t, err)
+		assert.Equal(t, u, got)
+
+		if i > 0 {
+			assert.Equal(t, -1, bytes.Compare(SortableUint64(values[i-1]), b))
+		}
+	}
+
+	_, err := ParseSortableUint64([]byte{1, 2})
+	assert.NotNil(t, err)
+}
+
+func TestSortableInt64(t *testing.T) {
+	values := []int64{math.MinInt64, -1 << 32, -256, -1, 0, 1, 256, 1 << 32, math.MaxInt64}
+	for i, n := range values {
+		b := SortableInt64(n)
+		got, err := ParseSortableInt64(b)
+		assert.Nil(t, err)
+		assert.Equal(t, n, got)
+
+		if i > 0 {
+			assert.Equal(t, -1, bytes.Compare(SortableInt64(values[i-1]), b))
+		}
+	}
+
+	_, err := ParseSortableInt64(nil)
+	assert.NotNil(t, err)
+}
+
+func TestSortableFloat64(t *testing.T) {
+	values := []float64{math.Inf(-1), -math.MaxFloat64, -1.5, -1, -math.SmallestNonzeroFloat64, math.Copysign(0, -1), 0, math.SmallestNonzeroFloat64, 1, 1.5, math.MaxFloat64, math.Inf(1)}
+	for i, f := range values {
+		b := SortableFloat64(f)
+		got, err := ParseSortableFloat64(b)
+		assert.Nil(t, err)
+		assert.Equal(t, math.Float64bits(f), math.Float64bits(got))
+
+		if i > 0 {
+			assert.Equal(t, -1, bytes.Compare(SortableFloat64(values[i-1]), b), "%v should sort before %v", values[i-1], f)
+		}
+	}
+
+	got, err := ParseSortableFloat64(SortableFloat64(math.NaN()))
+	assert.Nil(t, err)
+	assert.True(t, math.IsNaN(got))
+
+	_, err = ParseSortableFloat64(make([]byte, 9))
+	assert.NotNil(t, err)
+}
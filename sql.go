@@ -0,0 +1,49 @@
+package quickbolt
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+// RowMapper converts a SQL row, read via scan, into an entry and the path of the bucket it belongs in.
+type RowMapper func(scan func(dest ...any) error) (path [][]byte, key, value []byte, err error)
+
+// sqlSource is a Source reading from SQL query results.
+type sqlSource struct {
+	rows   *sql.Rows
+	mapRow RowMapper
+}
+
+// SQLSource returns a Source supplying an entry for each of the rows, as mapped by mapRow.
+//
+// The rows are not closed by the Source.
+func SQLSource(rows *sql.Rows, mapRow RowMapper) Source {
+	return &sqlSource{rows: rows, mapRow: mapRow}
+}
+
+func (s *sqlSource) Next() ([][]byte, []byte, []byte, error) {
+	if !s.rows.Next() {
+		if err := s.rows.Err(); err != nil {
+			return nil, nil, nil, fmt.Errorf("error while reading rows: %w", err)
+		}
+		return nil, nil, nil, io.EOF
+	}
+
+	return s.mapRow(s.rows.Scan)
+}
+
+// ImportSQL writes an entry to the db for each of the rows, as mapped by mapRow, and returns the number written.
+//
+// Entries are written in batches as with ImportFrom. The rows are not closed by ImportSQL.
+func ImportSQL(db DB, rows *sql.Rows, mapRow RowMapper, opts ...ImportOption) (int, error) {
+	if db == nil {
+		return 0, fmt.Errorf("sql import received nil db")
+	} else if rows == nil {
+		return 0, fmt.Errorf("sql import received nil rows")
+	} else if mapRow == nil {
+		return 0, fmt.Errorf("sql import received nil row mapper")
+	}
+
+	return db.ImportFrom(SQLSource(rows, mapRow), opts...)
+}
@@ -0,0 +1,90 @@
+package quickbolt
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rowsDriver is a database/sql driver whose every query returns n rows of (id, name).
+type rowsDriver struct {
+	n int
+}
+
+func (d rowsDriver) Open(name string) (driver.Conn, error) { return rowsConn(d), nil }
+
+type rowsConn rowsDriver
+
+func (c rowsConn) Prepare(query string) (driver.Stmt, error) { return rowsStmt(c), nil }
+func (c rowsConn) Close() error                              { return nil }
+func (c rowsConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("unsupported") }
+
+type rowsStmt rowsConn
+
+func (s rowsStmt) Close() error  { return nil }
+func (s rowsStmt) NumInput() int { return 0 }
+func (s rowsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("unsupported")
+}
+func (s rowsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{n: s.n}, nil
+}
+
+type fakeRows struct {
+	i, n int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id", "name"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= r.n {
+		return io.EOF
+	}
+	dest[0] = int64(r.i)
+	dest[1] = "user" + strconv.Itoa(r.i)
+	r.i++
+	return nil
+}
+
+func init() {
+	sql.Register("quickbolt-rows", rowsDriver{n: 2500})
+}
+
+func TestImportSQL(t *testing.T) {
+	sqlDB, err := sql.Open("quickbolt-rows", "")
+	assert.Nil(t, err)
+	defer sqlDB.Close()
+
+	rows, err := sqlDB.Query("SELECT id, name FROM users")
+	assert.Nil(t, err)
+	defer rows.Close()
+
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	var progress []int
+	n, err := ImportSQL(db, rows, func(scan func(dest ...any) error) ([][]byte, []byte, []byte, error) {
+		var id int
+		var name string
+		if err := scan(&id, &name); err != nil {
+			return nil, nil, nil, err
+		}
+		return [][]byte{[]byte("users")}, []byte(strconv.Itoa(id)), []byte(name), nil
+	}, WithImportProgress(func(imported int) {
+		progress = append(progress, imported)
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, 2500, n)
+	assert.Equal(t, []int{1000, 2000, 2500}, progress)
+
+	v, err := db.GetValue("2499", []string{"users"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "user2499", string(v))
+}
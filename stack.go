@@ -31,3 +31,35 @@ func withCallerInfo(task string, offset int) string {
 
 	return fmt.Sprintf("%s on line %d", file, line)
 }
+
+// callerPC returns the program counter of the caller at the given offset, or 0 if it could not be determined.
+//
+// Offsets are interpreted as in withCallerInfo.
+// Unlike withCallerInfo, the program counter is not resolved to a file and line, which is left to describeCaller.
+func callerPC(offset int) uintptr {
+	var pc [1]uintptr
+	if runtime.Callers(offset+1, pc[:]) == 0 {
+		return 0
+	}
+
+	return pc[0]
+}
+
+// describeCaller returns a string describing the file and line number of the given program counter,
+// formatted as in withCallerInfo.
+func describeCaller(task string, pc uintptr) string {
+	if pc == 0 {
+		return task
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return task
+	}
+
+	if task != "" {
+		return fmt.Sprintf("%s called at line %d in %s", task, frame.Line, frame.File)
+	}
+
+	return fmt.Sprintf("%s on line %d", frame.File, frame.Line)
+}
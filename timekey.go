@@ -0,0 +1,52 @@
+package quickbolt
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeKey encodes the time, truncated to a multiple of the resolution since the Unix epoch, as 8 bytes
+// that sort chronologically, for use as or as the prefix of a key. A resolution of zero or less keeps nanoseconds.
+//
+// The encoding is that of SortableInt64 applied to the time's Unix nanoseconds, regardless of resolution,
+// so keys of differing resolutions may share a bucket and be queried together via EntriesBetween.
+// Bytes appended after the encoded time, such as a sequence number to keep keys of equal times unique,
+// do not affect the key's chronological order.
+//
+// Times must fall between the years 1678 and 2262, as with time.Time.UnixNano.
+func TimeKey(t time.Time, resolution time.Duration) []byte {
+	n := t.UnixNano()
+
+	if resolution > 0 {
+		r := int64(resolution)
+		// Truncation rounds toward negative infinity, so that times before the epoch truncate as those after it do.
+		if m := n % r; m < 0 {
+			n -= m + r
+		} else {
+			n -= m
+		}
+	}
+
+	return SortableInt64(n)
+}
+
+// ParseTimeKey decodes the time encoded at the start of a key by TimeKey.
+//
+// The returned time is in UTC.
+func ParseTimeKey(key []byte) (time.Time, error) {
+	if len(key) < 8 {
+		return time.Time{}, fmt.Errorf("time key %x has length %d rather than at least 8", key, len(key))
+	}
+
+	n, err := ParseSortableInt64(key[:8])
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, n).UTC(), nil
+}
+
+// timeRange returns the range of keys beginning with a TimeKey from the given time, inclusive, to the other, exclusive.
+func timeRange(from, to time.Time) KeyRange {
+	return KeyRange{Start: TimeKey(from, 0), End: TimeKey(to, 0)}
+}
@@ -0,0 +1,54 @@
+package quickbolt
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeKey(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 12, 34, 56, 789, time.UTC)
+
+	got, err := ParseTimeKey(TimeKey(ts, 0))
+	assert.Nil(t, err)
+	assert.True(t, ts.Equal(got))
+
+	got, err = ParseTimeKey(append(TimeKey(ts, time.Second), "-suffix"...))
+	assert.Nil(t, err)
+	assert.True(t, ts.Truncate(time.Second).Equal(got))
+
+	before := time.Date(1969, 12, 31, 23, 59, 59, 500, time.UTC)
+	got, err = ParseTimeKey(TimeKey(before, time.Second))
+	assert.Nil(t, err)
+	assert.True(t, time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC).Equal(got))
+
+	assert.Equal(t, -1, bytes.Compare(TimeKey(before, 0), TimeKey(ts, 0)))
+
+	_, err = ParseTimeKey([]byte("short"))
+	assert.NotNil(t, err)
+}
+
+func Test_dbWrapper_EntriesBetween(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"events"}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		key := append(TimeKey(base.Add(time.Duration(i)*time.Hour), time.Minute), byte(i))
+		assert.Nil(t, db.Insert(key, []byte{byte(i)}, path))
+	}
+
+	buffer := make(chan [2][]byte, 10)
+	assert.Nil(t, db.EntriesBetween(path, base.Add(2*time.Hour), base.Add(5*time.Hour), buffer))
+
+	var values []byte
+	for e := range buffer {
+		values = append(values, e[1]...)
+	}
+	assert.Equal(t, []byte{2, 3, 4}, values)
+}
@@ -0,0 +1,372 @@
+package quickbolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// MapFunc transforms a key-value pair read by MapBucket into the pair written to the destination.
+//
+// A nil nk or nv keeps the original key or value. If skip is true, nothing is written for the pair.
+//
+// The given key and value are only valid until MapFunc returns and must be copied to be retained.
+type MapFunc func(k, v []byte) (nk, nv []byte, skip bool, err error)
+
+var (
+	mapLastKey  = []byte("last")
+	mapCountKey = []byte("count")
+)
+
+// mapStateKey returns the key of the bucket recording the progress of mapping src into dst.
+func mapStateKey(src, dst [][]byte) []byte {
+	return []byte(pathKey(src) + " " + pathKey(dst))
+}
+
+// mapStep maps the next batch of pairs from the bucket at src into the bucket at dst in a single transaction.
+//
+// Done is true once every pair has been mapped, in which case count is the number of pairs written by
+// the whole mapping, including any transactions made by earlier, interrupted calls.
+func mapStep(db *bbolt.DB, src, dst [][]byte, fn MapFunc, env writeEnv) (done bool, count uint64, err error) {
+	if db == nil {
+		return false, 0, fmt.Errorf("mapping of %s received nil db", src)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		from, err := getBucket(tx, src, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating source path: %w", err)
+		}
+
+		all, err := getCreateMetaBucket(tx, mapsBucket)
+		if err != nil {
+			return err
+		}
+
+		state, err := all.CreateBucketIfNotExists(mapStateKey(src, dst))
+		if err != nil {
+			return fmt.Errorf("error while accessing mapping state: %w", err)
+		}
+
+		if c := state.Get(mapCountKey); len(c) == 8 {
+			count = binary.BigEndian.Uint64(c)
+		}
+
+		// Mapped pairs are collected before writing since the destination may be nested within the source.
+		var mapped [][2][]byte
+		var last []byte
+
+		c := from.Cursor()
+		k, v := c.First()
+		if l := state.Get(mapLastKey); l != nil {
+			if k, v = c.Seek(l); k != nil && bytes.Equal(k, l) {
+				k, v = c.Next()
+			}
+		}
+
+		for n := 0; k != nil && n < mapBatchSize; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+			n++
+			last = k
+
+			nk, nv, skip, err := fn(k, v)
+			if err != nil {
+				return fmt.Errorf("error while mapping %s: %w", k, err)
+			} else if skip {
+				continue
+			}
+
+			if nk == nil {
+				nk = k
+			}
+			if nv == nil {
+				nv = v
+			}
+			mapped = append(mapped, [2][]byte{copyBytes(nk), copyBytes(nv)})
+		}
+		last = copyBytes(last)
+		done = k == nil
+
+		to, err := getCreateBucket(tx, dst)
+		if err != nil {
+			return fmt.Errorf("error while navigating destination path: %w", err)
+		}
+
+		for _, e := range mapped {
+			k, err := env.rules.storeKey(tx, dst, e[0])
+			if err != nil {
+				return err
+			}
+			if err := env.rules.beforePut(tx, dst, k, to.Get(k), e[1]); err != nil {
+				return err
+			}
+			if err := to.Put(k, e[1]); err != nil {
+				return fmt.Errorf("error while writing %s: %w", e[0], err)
+			}
+			env.metrics.observeWrite(tx, len(k)+len(e[1]))
+		}
+		count += uint64(len(mapped))
+
+		if done {
+			return all.DeleteBucket(mapStateKey(src, dst))
+		}
+
+		if err := state.Put(mapLastKey, last); err != nil {
+			return err
+		}
+		return state.Put(mapCountKey, uint64Bytes(count))
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("mapping of %s into %s experienced error: %w", src, dst, err)
+	}
+
+	return done, count, nil
+}
+
+// copyWhere copies the key-value pairs at src that match to dst within a single transaction, returning the number copied.
+func copyWhere(db *bbolt.DB, src, dst [][]byte, match func(k, v []byte) bool, env writeEnv) (int, error) {
+	if db == nil {
+		return 0, fmt.Errorf("conditional copy of %s received nil db", src)
+	}
+
+	var copied int
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		from, err := getBucket(tx, src, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating source path: %w", err)
+		}
+
+		// Matching pairs are collected before writing since the destination may be nested within the source.
+		var matched [][2][]byte
+
+		c := from.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v != nil && match(k, v) {
+				matched = append(matched, [2][]byte{copyBytes(k), copyBytes(v)})
+			}
+		}
+
+		to, err := getCreateBucket(tx, dst)
+		if err != nil {
+			return fmt.Errorf("error while navigating destination path: %w", err)
+		}
+
+		for _, e := range matched {
+			k, err := env.rules.storeKey(tx, dst, e[0])
+			if err != nil {
+				return err
+			}
+			if err := env.rules.beforePut(tx, dst, k, to.Get(k), e[1]); err != nil {
+				return err
+			}
+			if err := to.Put(k, e[1]); err != nil {
+				return fmt.Errorf("error while writing %s: %w", e[0], err)
+			}
+			env.metrics.observeWrite(tx, len(k)+len(e[1]))
+		}
+		copied = len(matched)
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("conditional copy of %s into %s experienced error: %w", src, dst, err)
+	}
+
+	return copied, nil
+}
+
+// scanWhere collects up to whereBatchSize copies of the key-value pairs in the bucket that match, starting after the given key.
+//
+// Next is the last key scanned, from which the following batch should start, or nil if the bucket was scanned to its end.
+func scanWhere(bkt *bbolt.Bucket, after []byte, match func(k, v []byte) bool) (matched [][2][]byte, next []byte) {
+	c := bkt.Cursor()
+
+	k, v := c.First()
+	if after != nil {
+		if k, v = c.Seek(after); k != nil && bytes.Equal(k, after) {
+			k, v = c.Next()
+		}
+	}
+
+	for ; k != nil; k, v = c.Next() {
+		if v == nil || !match(k, v) {
+			continue
+		}
+
+		matched = append(matched, [2][]byte{copyBytes(k), copyBytes(v)})
+		if len(matched) == whereBatchSize {
+			return matched, copyBytes(k)
+		}
+	}
+
+	return matched, nil
+}
+
+// deleteWhereBatch deletes the next batch of matching pairs at the given path, starting after the given key.
+//
+// Next is the key the following batch should start after, or nil if no pairs remain to be checked.
+func deleteWhereBatch(db *bbolt.DB, path [][]byte, after []byte, match func(k, v []byte) bool, env writeEnv) (deleted int, next []byte, err error) {
+	if db == nil {
+		return 0, nil, fmt.Errorf("conditional deletion at %s received nil db", path)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		// Pairs are collected before deleting since deleting during cursor iteration skips entries.
+		var matched [][2][]byte
+		matched, next = scanWhere(bkt, after, match)
+
+		for _, e := range matched {
+			if err := env.rules.beforeDelete(tx, path, e[0], e[1]); err != nil {
+				return err
+			}
+			if err := bkt.Delete(e[0]); err != nil {
+				return fmt.Errorf("error while deleting %s: %w", e[0], err)
+			}
+			env.metrics.observeWrite(tx, 0)
+		}
+		deleted = len(matched)
+
+		return nil
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("conditional deletion at %s experienced error: %w", path, err)
+	}
+
+	return deleted, next, nil
+}
+
+// updateWhereBatch rewrites the next batch of matching pairs at the given path with the values returned by transform,
+// starting after the given key.
+//
+// Next is the key the following batch should start after, or nil if no pairs remain to be checked.
+func updateWhereBatch(db *bbolt.DB, path [][]byte, after []byte, match func(k, v []byte) bool, transform func(v []byte) ([]byte, error), env writeEnv) (updated int, next []byte, err error) {
+	if db == nil {
+		return 0, nil, fmt.Errorf("conditional update at %s received nil db", path)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		} else if bkt == nil {
+			return nil
+		}
+
+		// Pairs are collected before writing since writing during cursor iteration may move the cursor.
+		var matched [][2][]byte
+		matched, next = scanWhere(bkt, after, match)
+
+		for _, e := range matched {
+			nv, err := transform(e[1])
+			if err != nil {
+				return fmt.Errorf("error while transforming value of %s: %w", e[0], err)
+			} else if nv == nil {
+				return fmt.Errorf("transform of value of %s returned nil", e[0])
+			}
+
+			if err := env.rules.beforePut(tx, path, e[0], e[1], nv); err != nil {
+				return err
+			}
+			if err := bkt.Put(e[0], nv); err != nil {
+				return fmt.Errorf("error while writing %s: %w", e[0], err)
+			}
+			env.metrics.observeWrite(tx, len(e[0])+len(nv))
+		}
+		updated = len(matched)
+
+		return nil
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("conditional update at %s experienced error: %w", path, err)
+	}
+
+	return updated, next, nil
+}
+
+// groupBatch copies the next batch of pairs at src into the sub-buckets of dst named by groupKey, starting after the given key.
+//
+// Next is the key the following batch should start after, or nil if no pairs remain to be grouped.
+func groupBatch(db *bbolt.DB, src, dst [][]byte, after []byte, groupKey func(k, v []byte) []byte, envOf func([][]byte) writeEnv) (grouped int, next []byte, err error) {
+	if db == nil {
+		return 0, nil, fmt.Errorf("grouping of %s received nil db", src)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		from, err := getBucket(tx, src, true)
+		if err != nil {
+			return fmt.Errorf("error while navigating source path: %w", err)
+		}
+
+		type groupedEntry struct {
+			group, key, value []byte
+		}
+
+		// Pairs are collected before writing since the destination may be the source or nested within it.
+		var entries []groupedEntry
+
+		c := from.Cursor()
+		k, v := c.First()
+		if after != nil {
+			if k, v = c.Seek(after); k != nil && bytes.Equal(k, after) {
+				k, v = c.Next()
+			}
+		}
+
+		for ; k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+
+			if group := groupKey(k, v); group != nil {
+				entries = append(entries, groupedEntry{group: copyBytes(group), key: copyBytes(k), value: copyBytes(v)})
+			}
+
+			if len(entries) == groupBatchSize {
+				next = copyBytes(k)
+				break
+			}
+		}
+
+		for _, e := range entries {
+			path := append(copyPath(dst), e.group)
+
+			bkt, err := getCreateBucket(tx, path)
+			if err != nil {
+				return fmt.Errorf("error while navigating group %s: %w", e.group, err)
+			}
+
+			env := envOf(path)
+			k, err := env.rules.storeKey(tx, path, e.key)
+			if err != nil {
+				return err
+			}
+			if err := env.rules.beforePut(tx, path, k, bkt.Get(k), e.value); err != nil {
+				return err
+			}
+			if err := bkt.Put(k, e.value); err != nil {
+				return fmt.Errorf("error while writing %s to group %s: %w", e.key, e.group, err)
+			}
+			env.metrics.observeWrite(tx, len(k)+len(e.value))
+		}
+		grouped = len(entries)
+
+		return nil
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("grouping of %s into %s experienced error: %w", src, dst, err)
+	}
+
+	return grouped, next, nil
+}
@@ -0,0 +1,217 @@
+package quickbolt
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+// fillBucket writes n pairs of the form k%05d=v%d, plus a nested bucket, to the bucket at the given path.
+func fillBucket(t *testing.T, db DB, path []string, n int) {
+	err := db.RunUpdate(func(tx *bbolt.Tx) error {
+		p, _ := resolveBucketPath(path)
+		bkt, err := getCreateBucket(tx, p)
+		if err != nil {
+			return err
+		}
+		if _, err := bkt.CreateBucketIfNotExists([]byte("nested")); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := bkt.Put([]byte(fmt.Sprintf("k%05d", i)), []byte(fmt.Sprintf("v%d", i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func Test_dbWrapper_MapBucket(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	src, dst := []string{"src"}, []string{"dst"}
+	n := mapBatchSize*2 + 10
+	fillBucket(t, db, src, n)
+
+	failAt := []byte(fmt.Sprintf("k%05d", mapBatchSize+5))
+	seen := 0
+	fn := func(k, v []byte) ([]byte, []byte, bool, error) {
+		seen++
+		if failAt != nil && bytes.Equal(k, failAt) {
+			return nil, nil, false, fmt.Errorf("boom")
+		}
+		if k[len(k)-1] == '0' {
+			return nil, nil, true, nil
+		}
+		return append([]byte("new-"), k...), bytes.ToUpper(v), false, nil
+	}
+
+	_, err = db.MapBucket(src, dst, fn)
+	assert.NotNil(t, err)
+
+	failAt = nil
+	seen = 0
+	count, err := db.MapBucket(src, dst, fn)
+	assert.Nil(t, err)
+	assert.Equal(t, n-mapBatchSize, seen, "completed batches must not be mapped again")
+	assert.Equal(t, uint64(n-n/10), count)
+
+	v, err := db.GetValue("new-k01234", dst, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "V1234", string(v))
+
+	v, err = db.GetValue("new-k01230", dst, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v, "skipped pairs must not be written")
+
+	v, err = db.GetValue("k01234", src, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1234", string(v), "the source must be left unchanged")
+
+	_, err = db.MapBucket(src, src, fn)
+	assert.NotNil(t, err)
+}
+
+func Test_dbWrapper_CopyWhere(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	src, dst := []string{"orders"}, []string{"orders", "customer-7"}
+	fillBucket(t, db, src, 100)
+
+	n, err := db.CopyWhere(src, dst, func(k, v []byte) bool { return bytes.HasSuffix(v, []byte("7")) })
+	assert.Nil(t, err)
+	assert.Equal(t, 10, n)
+
+	var got []string
+	assert.Nil(t, db.ForEachKey(dst, func(k []byte) error {
+		got = append(got, string(k))
+		return nil
+	}))
+	assert.Len(t, got, 10)
+	assert.Equal(t, "k00007", got[0])
+
+	assert.Nil(t, db.SetUnique([]string{"unique"}))
+	assert.Nil(t, db.Insert("taken", "v7", []string{"unique"}))
+	n, err = db.CopyWhere(src, []string{"unique"}, func(k, v []byte) bool { return bytes.HasSuffix(v, []byte("7")) })
+	assert.NotNil(t, err, "copies must respect the destination's rules")
+	assert.Equal(t, 0, n)
+}
+
+func Test_dbWrapper_DeleteWhere(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a"}
+	n := whereBatchSize*3 + 10
+	fillBucket(t, db, path, n)
+
+	deleted, err := db.DeleteWhere(path, func(k, v []byte) bool { return k[len(k)-1] != '0' })
+	assert.Nil(t, err)
+	assert.Equal(t, n-n/10, deleted)
+
+	remaining := 0
+	assert.Nil(t, db.ForEachKey(path, func(k []byte) error {
+		remaining++
+		assert.Equal(t, byte('0'), k[len(k)-1])
+		return nil
+	}))
+	assert.Equal(t, n/10, remaining)
+
+	_, found, err := db.GetValueOK("nested", path)
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Nil(t, db.RunView(func(tx *bbolt.Tx) error {
+		assert.NotNil(t, tx.Bucket([]byte(rootBucket)).Bucket([]byte("a")).Bucket([]byte("nested")), "nested buckets must be left as is")
+		return nil
+	}))
+
+	deleted, err = db.DeleteWhere([]string{"missing"}, func(k, v []byte) bool { return true })
+	assert.Nil(t, err)
+	assert.Equal(t, 0, deleted)
+}
+
+func Test_dbWrapper_UpdateWhere(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"a"}
+	n := whereBatchSize*2 + 10
+	fillBucket(t, db, path, n)
+
+	even := func(k, v []byte) bool { return (k[len(k)-1]-'0')%2 == 0 }
+	updated, err := db.UpdateWhere(path, even, func(v []byte) ([]byte, error) {
+		return append(v, "-even"...), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, n/2, updated)
+
+	v, err := db.GetValue("k01234", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1234-even", string(v))
+
+	v, err = db.GetValue("k01235", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1235", string(v))
+
+	calls := 0
+	updated, err = db.UpdateWhere(path, even, func(v []byte) ([]byte, error) {
+		if calls++; calls > whereBatchSize {
+			return nil, fmt.Errorf("boom")
+		}
+		return v, nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, whereBatchSize, updated, "completed batches must be reported")
+}
+
+func Test_dbWrapper_GroupBy(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	src := []string{"events"}
+	n := groupBatchSize*2 + 10
+	fillBucket(t, db, src, n)
+
+	// Group by the last digit of the key, skipping those ending in 9.
+	byDigit := func(k, v []byte) []byte {
+		if d := k[len(k)-1]; d != '9' {
+			return []byte{'d', d}
+		}
+		return nil
+	}
+
+	grouped, err := db.GroupBy(src, src, byDigit)
+	assert.Nil(t, err)
+	assert.Equal(t, n-n/10, grouped)
+
+	v, err := db.GetValue("k01234", []string{"events", "d4"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1234", string(v))
+
+	a, err := db.Aggregate([]string{"events", "d0"}, Agg{Count: true})
+	assert.Nil(t, err)
+	assert.Equal(t, n/10, a.Count)
+
+	a, err = db.Aggregate(src, Agg{Count: true})
+	assert.Nil(t, err)
+	assert.Equal(t, n, a.Count, "the source must be left unchanged")
+
+	_, err = db.GetValue("k01239", []string{"events", "d9"}, true)
+	assert.NotNil(t, err, "pairs with a nil group key must be skipped")
+}
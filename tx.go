@@ -0,0 +1,257 @@
+package quickbolt
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// ReadTx is a read-only transaction begun via BeginRead.
+//
+// A ReadTx must be used by a single goroutine and ended with Rollback. Until it ends, compaction and
+// closing of the db wait on it.
+type ReadTx interface {
+	// Get returns a copy of the value paired with the given key at the given path,
+	// or nil if the key or bucket could not be found.
+	//
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	Get(key, bucketPath any) ([]byte, error)
+	// Cursor returns a cursor over the bucket at the given path.
+	//
+	// The cursor, and the keys and values it returns, are only valid until the transaction ends.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	Cursor(bucketPath any) (*bbolt.Cursor, error)
+	// Tx returns the underlying bbolt transaction.
+	Tx() *bbolt.Tx
+	// Rollback ends the transaction, discarding any writes made within it.
+	Rollback() error
+}
+
+// WriteTx is a read-write transaction begun via BeginWrite.
+//
+// A WriteTx must be used by a single goroutine and ended with Commit or Rollback. Until it ends, every
+// other write to the db waits on it.
+type WriteTx interface {
+	ReadTx
+	// Put writes the key-value pair to the given path, creating buckets as needed.
+	//
+	// Rules registered for the path, such as validators and unique values, are applied as with Insert.
+	//
+	// Key and value must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	Put(key, value, bucketPath any) error
+	// Delete removes the key from the given path.
+	//
+	// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+	//
+	// BucketPath must be of type []string, [][]byte, []any, or Path.
+	Delete(key, bucketPath any) error
+	// Commit ends the transaction, writing its changes to disk.
+	Commit() error
+}
+
+// txHandle implements ReadTx and WriteTx.
+type txHandle struct {
+	d       *dbWrapper
+	tx      *bbolt.Tx
+	release func()
+	untrack func()
+	done    bool
+	// name and caller describe where the transaction was begun, for the leak warning.
+	name   string
+	caller uintptr
+}
+
+// beginTx begins a transaction for the given operation, holding the database until the transaction ends.
+//
+// If the returned handle is garbage collected before it is ended, a warning is logged and the transaction is rolled back.
+func (d *dbWrapper) beginTx(o *operation, writable bool) (*txHandle, error) {
+	db, release := d.acquire()
+
+	tx, err := db.Begin(writable)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("error while beginning transaction: %w", err)
+	}
+
+	h := &txHandle{d: d, tx: tx, release: release, untrack: d.holdTx(o), name: o.name, caller: o.caller}
+	if !writable {
+		untrackRead, unhold := d.trackRead(o), h.untrack
+		h.untrack = func() {
+			untrackRead()
+			unhold()
+		}
+	}
+	runtime.SetFinalizer(h, (*txHandle).leaked)
+
+	return h, nil
+}
+
+// leaked warns of and rolls back a transaction that was never ended.
+func (h *txHandle) leaked() {
+	if h.done {
+		return
+	}
+
+	logMutex.Lock()
+	h.d.logger.Warn().Str("operation", h.name).Msg(describeCaller(h.name, h.caller) + " began a transaction that was garbage collected without being committed or rolled back")
+	logMutex.Unlock()
+
+	h.tx.Rollback()
+	h.end()
+}
+
+// end marks the transaction as ended and releases the database.
+func (h *txHandle) end() {
+	h.done = true
+	runtime.SetFinalizer(h, nil)
+	h.untrack()
+	h.release()
+}
+
+func (h *txHandle) Tx() *bbolt.Tx {
+	return h.tx
+}
+
+func (h *txHandle) Get(key, bucketPath any) ([]byte, error) {
+	p, err := h.d.resolveBucketPath(bucketPath)
+	if err != nil {
+		return nil, fmt.Errorf("transaction value retrieval experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	k, err := h.d.resolveKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("transaction value retrieval %w", newErrRecordResolution("key", key))
+	}
+
+	bkt, err := getBucket(h.tx, p, false)
+	if err != nil {
+		return nil, fmt.Errorf("transaction value retrieval for %s experienced error while navigating path: %w", k, err)
+	} else if bkt == nil {
+		return nil, nil
+	}
+
+	return copyBytes(bkt.Get(h.d.rules.forPath(p).lookupKey(k))), nil
+}
+
+func (h *txHandle) Cursor(bucketPath any) (*bbolt.Cursor, error) {
+	p, err := h.d.resolveBucketPath(bucketPath)
+	if err != nil {
+		return nil, fmt.Errorf("transaction cursor creation experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	bkt, err := getBucket(h.tx, p, true)
+	if err != nil {
+		return nil, fmt.Errorf("transaction cursor creation for %s experienced error while navigating path: %w", p, err)
+	}
+
+	return bkt.Cursor(), nil
+}
+
+func (h *txHandle) Put(key, value, bucketPath any) error {
+	p, err := h.d.resolveBucketPath(bucketPath)
+	if err != nil {
+		return fmt.Errorf("transaction write experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	k, err := h.d.resolveKey(key)
+	if err != nil {
+		return fmt.Errorf("transaction write %w", newErrRecordResolution("key", key))
+	}
+
+	v, err := resolveRecord(value)
+	if err != nil {
+		return fmt.Errorf("transaction write %w", newErrRecordResolution("value", value))
+	}
+
+	bkt, err := getCreateBucket(h.tx, p)
+	if err != nil {
+		return fmt.Errorf("transaction write of %s experienced error while navigating path: %w", k, err)
+	}
+
+	env := h.d.writeEnv(p)
+	sk, err := env.rules.storeKey(h.tx, p, k)
+	if err != nil {
+		return fmt.Errorf("transaction write of %s experienced error: %w", k, err)
+	}
+	k = sk
+
+	if err := env.rules.beforePut(h.tx, p, k, bkt.Get(k), v); err != nil {
+		return fmt.Errorf("transaction write of %s experienced error: %w", k, err)
+	}
+
+	if err := bkt.Put(k, v); err != nil {
+		return fmt.Errorf("transaction write of %s experienced error while writing: %w", k, err)
+	}
+
+	env.metrics.observeWrite(h.tx, len(k)+len(v))
+
+	return nil
+}
+
+func (h *txHandle) Delete(key, bucketPath any) error {
+	p, err := h.d.resolveBucketPath(bucketPath)
+	if err != nil {
+		return fmt.Errorf("transaction deletion experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	k, err := h.d.resolveKey(key)
+	if err != nil {
+		return fmt.Errorf("transaction deletion %w", newErrRecordResolution("key", key))
+	}
+
+	bkt, err := getBucket(h.tx, p, false)
+	if err != nil {
+		return fmt.Errorf("transaction deletion of %s experienced error while navigating path: %w", k, err)
+	} else if bkt == nil {
+		return nil
+	}
+
+	env := h.d.writeEnv(p)
+	k = env.rules.lookupKey(k)
+	if err := env.rules.beforeDelete(h.tx, p, k, bkt.Get(k)); err != nil {
+		return fmt.Errorf("transaction deletion of %s experienced error: %w", k, err)
+	}
+
+	if err := bkt.Delete(k); err != nil {
+		return fmt.Errorf("transaction deletion of %s experienced error while deleting: %w", k, err)
+	}
+
+	env.metrics.observeWrite(h.tx, 0)
+
+	return nil
+}
+
+func (h *txHandle) Commit() error {
+	if h.done {
+		return fmt.Errorf("transaction commit experienced error: %w", bbolt.ErrTxClosed)
+	}
+	defer h.end()
+
+	start := time.Now()
+	err := h.tx.Commit()
+	h.d.metrics.observeLatency(start, err)
+
+	if err != nil {
+		return fmt.Errorf("transaction commit experienced error: %w", err)
+	}
+	return nil
+}
+
+func (h *txHandle) Rollback() error {
+	if h.done {
+		return fmt.Errorf("transaction rollback experienced error: %w", bbolt.ErrTxClosed)
+	}
+	defer h.end()
+
+	if err := h.tx.Rollback(); err != nil {
+		return fmt.Errorf("transaction rollback experienced error: %w", err)
+	}
+	return nil
+}
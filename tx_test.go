@@ -0,0 +1,134 @@
+package quickbolt
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func Test_dbWrapper_BeginWrite(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	tx, err := db.BeginWrite()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Put("a", "1", []string{"tx"}))
+	assert.Nil(t, tx.Put("b", "2", []string{"tx"}))
+	assert.Nil(t, tx.Delete("b", []string{"tx"}))
+
+	v, err := tx.Get("a", []string{"tx"})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+	assert.Nil(t, tx.Commit())
+	assert.ErrorIs(t, tx.Commit(), bbolt.ErrTxClosed)
+
+	v, err = db.GetValue("a", []string{"tx"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+	v, err = db.GetValue("b", []string{"tx"}, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	tx, err = db.BeginWrite()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Put("c", "3", []string{"tx"}))
+	assert.Nil(t, tx.Rollback())
+
+	v, err = db.GetValue("c", []string{"tx"}, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	assert.Nil(t, db.RegisterValidator([]string{"tx"}, func(k, v []byte) error {
+		if len(v) == 0 {
+			return errors.New("empty")
+		}
+		return nil
+	}))
+	tx, err = db.BeginWrite()
+	assert.Nil(t, err)
+	err = tx.Put("d", "", []string{"tx"})
+	var validation ErrValidation
+	assert.ErrorAs(t, err, &validation)
+	assert.Nil(t, tx.Rollback())
+}
+
+func Test_dbWrapper_BeginRead(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	for _, k := range []string{"a", "b", "c"} {
+		assert.Nil(t, db.Insert(k, k, []string{"tx"}))
+	}
+
+	tx, err := db.BeginRead()
+	assert.Nil(t, err)
+
+	c, err := tx.Cursor([]string{"tx"})
+	assert.Nil(t, err)
+	var keys []string
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		keys = append(keys, string(k))
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+
+	_, err = tx.Cursor([]string{"missing"})
+	assert.NotNil(t, err)
+
+	v, err := tx.Get("z", []string{"missing"})
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+	assert.Nil(t, tx.Rollback())
+}
+
+// syncBuffer is a bytes.Buffer safe for use as a log writer while being read.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func Test_dbWrapper_BeginReadLeak(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	var log syncBuffer
+	db.AddLog(&log)
+
+	func() {
+		_, err := db.BeginRead()
+		assert.Nil(t, err)
+	}()
+
+	for i := 0; i < 50 && !strings.Contains(log.String(), "garbage collected"); i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Contains(t, log.String(), "BeginRead called at line")
+
+	// The leaked transaction was rolled back, so the database can be compacted.
+	assert.Nil(t, db.Compact())
+}
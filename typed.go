@@ -0,0 +1,63 @@
+package quickbolt
+
+import (
+	"fmt"
+)
+
+// Get returns the value paired with the given key at the given path, decoded into a T via the db's codec.
+//
+// An ErrLocate is returned if the key could not be found.
+//
+// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+//
+// BucketPath must be of type []string, [][]byte, []any, or Path.
+func Get[T any](db DB, key, bucketPath any) (T, error) {
+	var v T
+
+	if db == nil {
+		return v, fmt.Errorf("typed retrieval received nil db")
+	}
+
+	p, err := resolveBucketPath(bucketPath)
+	if err != nil {
+		return v, fmt.Errorf("typed retrieval experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	// GetValue returns a copy, so decoding after its transaction has ended is safe, even for codecs
+	// whose results alias their input.
+	b, err := db.GetValue(key, p, false)
+	if err != nil {
+		return v, err
+	} else if b == nil {
+		return v, newErrLocate(fmt.Sprintf("key %v at %s", key, p))
+	}
+
+	if err := codecOf(db, p).Unmarshal(b, &v); err != nil {
+		return v, fmt.Errorf("typed retrieval of %v at %s experienced error while decoding value: %w", key, p, err)
+	}
+
+	return v, nil
+}
+
+// Put writes the value, encoded via the db's codec, to the db at the given path.
+//
+// Key must be of type []byte, string, bool, time.Time, an integer or float type, or a marshaler as described on DB.
+//
+// BucketPath must be of type []string, [][]byte, []any, or Path.
+func Put[T any](db DB, key any, value T, bucketPath any) error {
+	if db == nil {
+		return fmt.Errorf("typed write received nil db")
+	}
+
+	p, err := resolveBucketPath(bucketPath)
+	if err != nil {
+		return fmt.Errorf("typed write experienced %w", newErrBucketPathResolution("error"))
+	}
+
+	b, err := codecOf(db, p).Marshal(value)
+	if err != nil {
+		return fmt.Errorf("typed write of %v at %s experienced error while encoding value: %w", key, p, err)
+	}
+
+	return db.Insert(key, b, p)
+}
@@ -0,0 +1,189 @@
+package quickbolt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type testBlob struct {
+	Data []byte
+}
+
+func TestGet(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert("alice", `{"name":"alice","age":30}`, []string{"users"}))
+	assert.Nil(t, db.Insert("broken", `{`, []string{"users"}))
+
+	u, err := Get[testUser](db, "alice", []string{"users"})
+	assert.Nil(t, err)
+	assert.Equal(t, testUser{Name: "alice", Age: 30}, u)
+
+	_, err = Get[testUser](db, "bob", []string{"users"})
+	assert.True(t, errors.Is(err, ErrLocate{}))
+
+	_, err = Get[testUser](db, "bob", []string{"missing"})
+	assert.True(t, errors.Is(err, ErrLocate{}))
+
+	_, err = Get[testUser](db, "broken", []string{"users"})
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrLocate{}))
+}
+
+func TestPut(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"users"}
+
+	assert.Nil(t, Put(db, "alice", testUser{Name: "alice", Age: 30}, path))
+	u, err := Get[testUser](db, "alice", path)
+	assert.Nil(t, err)
+	assert.Equal(t, testUser{Name: "alice", Age: 30}, u)
+
+	assert.Nil(t, Put(db, "bob", &testUser{Name: "bob"}, path))
+	ptr, err := Get[*testUser](db, "bob", path)
+	assert.Nil(t, err)
+	assert.Equal(t, &testUser{Name: "bob"}, ptr)
+
+	var none *testUser
+	assert.Nil(t, Put(db, "nobody", none, path))
+	ptr, err = Get[*testUser](db, "nobody", path)
+	assert.Nil(t, err)
+	assert.Nil(t, ptr)
+
+	assert.Nil(t, Put(db, "tags", []string{"a", "b"}, path))
+	tags, err := Get[[]string](db, "tags", path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, tags)
+
+	assert.NotNil(t, Put(db, "chan", make(chan int), path))
+}
+
+// prefixCodec is a JSON codec that marks encoded values with a prefix.
+type prefixCodec struct {
+	prefix string
+}
+
+func (c prefixCodec) Marshal(v any) ([]byte, error) {
+	b, err := JSONCodec{}.Marshal(v)
+	return append([]byte(c.prefix), b...), err
+}
+
+func (c prefixCodec) Unmarshal(data []byte, v any) error {
+	return JSONCodec{}.Unmarshal(data[len(c.prefix):], v)
+}
+
+func Test_dbWrapper_SetCodec(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	db.SetCodec(prefixCodec{prefix: "db:"})
+	assert.Nil(t, db.SetBucketCodec([]string{"special"}, prefixCodec{prefix: "bkt:"}))
+
+	assert.Nil(t, Put(db, "k", 1, []string{"plain"}))
+	assert.Nil(t, Put(db, "k", 2, []string{"special"}))
+	assert.Nil(t, Put(db, "k", 3, []string{"special", "nested"}))
+
+	tests := []struct {
+		path []string
+		raw  string
+		want int
+	}{
+		{path: []string{"plain"}, raw: "db:1", want: 1},
+		{path: []string{"special"}, raw: "bkt:2", want: 2},
+		{path: []string{"special", "nested"}, raw: "db:3", want: 3},
+	}
+	for _, tt := range tests {
+		raw, err := db.GetValue("k", tt.path, true)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.raw, string(raw))
+
+		v, err := Get[int](db, "k", tt.path)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.want, v)
+	}
+
+	db.SetCodec(nil)
+	assert.Nil(t, Put(db, "k", 4, []string{"plain"}))
+	raw, err := db.GetValue("k", []string{"plain"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "4", string(raw))
+}
+
+func Test_dbWrapper_PutObject(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	for _, codec := range []Codec{JSONCodec{}, GobCodec{}, MsgpackCodec{}} {
+		t.Run(fmt.Sprintf("%T", codec), func(t *testing.T) {
+			path := []string{fmt.Sprintf("%T", codec)}
+			assert.Nil(t, db.SetBucketCodec(path, codec))
+
+			assert.Nil(t, db.PutObject("alice", testUser{Name: "alice", Age: 30}, path))
+
+			var u testUser
+			assert.Nil(t, db.GetObject("alice", path, &u))
+			assert.Equal(t, testUser{Name: "alice", Age: 30}, u)
+
+			// Get and Put use the same codec.
+			got, err := Get[testUser](db, "alice", path)
+			assert.Nil(t, err)
+			assert.Equal(t, u, got)
+
+			assert.True(t, errors.Is(db.GetObject("bob", path, &u), ErrLocate{}))
+
+			// Decoded objects remain valid once the db is remapped.
+			assert.Nil(t, db.PutObject("carol", testBlob{Data: []byte("data")}, path))
+			var b testBlob
+			assert.Nil(t, db.GetObject("carol", path, &b))
+			assert.Nil(t, db.Compact())
+			for i := 0; i < 100; i++ {
+				assert.Nil(t, db.PutObject(i, testBlob{Data: make([]byte, 1024)}, path))
+			}
+			assert.Equal(t, "data", string(b.Data))
+			b.Data[0] = 'x'
+		})
+	}
+
+	assert.NotNil(t, db.PutObject("chan", make(chan int), []string{"users"}))
+}
+
+func TestGet_AfterRemap(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"blobs"}
+	assert.Nil(t, Put(db, "b", testBlob{Data: []byte("data")}, path))
+
+	got, err := Get[testBlob](db, "b", path)
+	assert.Nil(t, err)
+
+	// Compacting remaps the db, unmapping any memory the decoded value referenced.
+	assert.Nil(t, db.Compact())
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, Put(db, i, testBlob{Data: make([]byte, 1024)}, path))
+	}
+
+	assert.Equal(t, "data", string(got.Data))
+	got.Data[0] = 'x'
+}
@@ -0,0 +1,109 @@
+package quickbolt
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyGenerator returns a new key for InsertValueWith each time it is called.
+//
+// A KeyGenerator may be called concurrently and, as writes are batched, more than once per insertion.
+type KeyGenerator func() ([]byte, error)
+
+// crockford is the Crockford base32 alphabet used by ULIDs, which is in ascending byte order.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidLength is the length of a ULID's text encoding.
+const ulidLength = 26
+
+// NewULIDGenerator returns a KeyGenerator of ULIDs: 26 character keys made of a millisecond timestamp followed by
+// 80 random bits, which are unique across dbs and sort by creation time.
+//
+// ULIDs from the same generator within the same millisecond increment the random bits of the previous one,
+// so that they sort in the order generated. Should the clock move backwards, the previous timestamp is reused.
+func NewULIDGenerator() KeyGenerator {
+	var (
+		mu     sync.Mutex
+		lastMs uint64
+		hi     uint16
+		lo     uint64
+	)
+
+	return func() ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		ms := uint64(time.Now().UnixMilli())
+
+		if ms <= lastMs {
+			ms = lastMs
+			lo++
+			if lo == 0 {
+				hi++
+				if hi == 0 {
+					return nil, fmt.Errorf("ULID generation experienced error: random bits overflowed within millisecond %d", ms)
+				}
+			}
+		} else {
+			var b [10]byte
+			if _, err := rand.Read(b[:]); err != nil {
+				return nil, fmt.Errorf("ULID generation experienced error while reading random bits: %w", err)
+			}
+			hi, lo = binary.BigEndian.Uint16(b[:2]), binary.BigEndian.Uint64(b[2:])
+		}
+		lastMs = ms
+
+		return encodeULID(ms, hi, lo), nil
+	}
+}
+
+// encodeULID returns the text encoding of the ULID of the given 48 bit timestamp and 80 random bits.
+func encodeULID(ms uint64, hi uint16, lo uint64) []byte {
+	var raw [16]byte
+	binary.BigEndian.PutUint64(raw[0:8], ms<<16|uint64(hi))
+	binary.BigEndian.PutUint64(raw[8:16], lo)
+
+	// The 128 bits are encoded 5 at a time from the least significant, the first character carrying only 3.
+	b := make([]byte, ulidLength)
+	for i := ulidLength - 1; i >= 0; i-- {
+		b[i] = crockford[raw[15]&0x1f]
+		shiftRight5(&raw)
+	}
+
+	return b
+}
+
+// shiftRight5 shifts the 128 bit big-endian number right by 5 bits.
+func shiftRight5(raw *[16]byte) {
+	for i := 15; i > 0; i-- {
+		raw[i] = raw[i]>>5 | raw[i-1]<<3
+	}
+	raw[0] >>= 5
+}
+
+// ParseULIDTime returns the creation time encoded in a ULID generated by NewULIDGenerator.
+func ParseULIDTime(key []byte) (time.Time, error) {
+	if len(key) != ulidLength {
+		return time.Time{}, fmt.Errorf("ULID %s has length %d rather than %d", key, len(key), ulidLength)
+	}
+
+	// The timestamp occupies the first 10 characters, which hold 50 bits, the first 2 of which are unused.
+	var ms uint64
+	for _, c := range key[:10] {
+		i := strings.IndexByte(crockford, c)
+		if i < 0 {
+			return time.Time{}, fmt.Errorf("ULID %s contains invalid character %q", key, c)
+		}
+		ms = ms<<5 | uint64(i)
+	}
+
+	if ms >= 1<<48 {
+		return time.Time{}, fmt.Errorf("ULID %s has a timestamp beyond 48 bits", key)
+	}
+
+	return time.UnixMilli(int64(ms)).UTC(), nil
+}
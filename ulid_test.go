@@ -0,0 +1,53 @@
+package quickbolt
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewULIDGenerator(t *testing.T) {
+	gen := NewULIDGenerator()
+
+	before := time.Now().Truncate(time.Millisecond)
+	var prev []byte
+	for i := 0; i < 1000; i++ {
+		id, err := gen()
+		assert.Nil(t, err)
+		assert.Len(t, id, ulidLength)
+
+		if prev != nil {
+			assert.Equal(t, -1, bytes.Compare(prev, id), "%s should sort before %s", prev, id)
+		}
+		prev = id
+	}
+
+	created, err := ParseULIDTime(prev)
+	assert.Nil(t, err)
+	assert.False(t, created.Before(before))
+	assert.False(t, created.After(time.Now()))
+}
+
+func Test_encodeULID(t *testing.T) {
+	assert.Equal(t, "00000000000000000000000000", string(encodeULID(0, 0, 0)))
+	assert.Equal(t, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", string(encodeULID(1<<48-1, 1<<16-1, 1<<64-1)))
+	assert.Equal(t, "0000000001000000000000000Z", string(encodeULID(1, 0, 31)))
+}
+
+func TestParseULIDTime(t *testing.T) {
+	ms := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	got, err := ParseULIDTime(encodeULID(uint64(ms.UnixMilli()), 1, 2))
+	assert.Nil(t, err)
+	assert.True(t, ms.Equal(got))
+
+	_, err = ParseULIDTime([]byte("short"))
+	assert.NotNil(t, err)
+
+	_, err = ParseULIDTime([]byte("U0000000000000000000000000"))
+	assert.NotNil(t, err)
+
+	_, err = ParseULIDTime([]byte("80000000000000000000000000"))
+	assert.NotNil(t, err)
+}
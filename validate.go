@@ -0,0 +1,42 @@
+package quickbolt
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ValidJSON is a validator that rejects values which are not well-formed JSON.
+func ValidJSON(k, v []byte) error {
+	if !json.Valid(v) {
+		return fmt.Errorf("value is not valid JSON")
+	}
+	return nil
+}
+
+// JSONSchema returns a validator that rejects values not conforming to the given JSON Schema.
+//
+// The following keywords are supported: type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength, pattern,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum. The annotations $schema, $id, $comment,
+// title, description, default, examples, deprecated, readOnly, and writeOnly are accepted and have no effect.
+//
+// An error is returned if the schema uses any other keyword, such as $ref, allOf, anyOf, oneOf, not,
+// or format, rather than compiling a validator that would silently skip it.
+//
+// Patterns use Go's RE2 syntax, as described by the regexp package, rather than ECMA-262.
+// Most patterns are written the same in both, but lookarounds and backreferences are not supported.
+func JSONSchema(schema []byte) (func(k, v []byte) error, error) {
+	s, err := compileJSONSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("error while compiling JSON schema: %w", err)
+	}
+
+	return func(k, v []byte) error {
+		var doc interface{}
+		if err := json.Unmarshal(v, &doc); err != nil {
+			return fmt.Errorf("value is not valid JSON: %w", err)
+		}
+
+		return s.validate(doc, "$")
+	}, nil
+}
@@ -0,0 +1,92 @@
+package quickbolt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSchema(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"required": ["name"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"enum": ["a", "b"]}}
+		}
+	}`)
+
+	validate, err := JSONSchema(schema)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "Valid", value: `{"name": "foo", "age": 3, "tags": ["a"]}`, wantErr: false},
+		{name: "Malformed", value: `{"name": `, wantErr: true},
+		{name: "Missing required", value: `{"age": 3}`, wantErr: true},
+		{name: "Wrong type", value: `{"name": 3}`, wantErr: true},
+		{name: "Fractional integer", value: `{"name": "foo", "age": 1.5}`, wantErr: true},
+		{name: "Below minimum", value: `{"name": "foo", "age": -1}`, wantErr: true},
+		{name: "Additional property", value: `{"name": "foo", "extra": true}`, wantErr: true},
+		{name: "Enum mismatch", value: `{"name": "foo", "tags": ["c"]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate(nil, []byte(tt.value)); (err != nil) != tt.wantErr {
+				t.Errorf("JSONSchema() validator error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJSONSchema_Unsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{name: "Ref", schema: `{"$ref": "#/definitions/user"}`},
+		{name: "Combinator", schema: `{"anyOf": [{"type": "string"}, {"type": "null"}]}`},
+		{name: "Nested", schema: `{"properties": {"email": {"type": "string", "format": "email"}}}`},
+		{name: "Unknown type", schema: `{"type": "int"}`},
+		{name: "ECMA-262 lookahead", schema: `{"pattern": "^(?=a)"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := JSONSchema([]byte(tt.schema))
+			assert.NotNil(t, err)
+		})
+	}
+
+	_, err := JSONSchema([]byte(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "user", "type": "object"}`))
+	assert.Nil(t, err)
+}
+
+func Test_dbWrapper_RegisterValidator(t *testing.T) {
+	db, err := Create("foo.db")
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"docs"}
+	assert.Nil(t, db.RegisterValidator(path, ValidJSON))
+
+	assert.Nil(t, db.Insert("valid", `{"foo": 1}`, path))
+	assert.Nil(t, db.Insert("unvalidated", "not json", []string{"other"}))
+
+	err = db.Insert("invalid", "not json", path)
+	var validation ErrValidation
+	assert.True(t, errors.As(err, &validation))
+
+	err = db.Upsert("valid", "}", path, func(a, b []byte) ([]byte, error) { return append(a, b...), nil })
+	assert.True(t, errors.As(err, &validation))
+
+	v, err := db.GetValue("invalid", path, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+}
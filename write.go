@@ -8,29 +8,52 @@ import (
 	"golang.org/x/exp/slices"
 )
 
+// writeEnv holds the per-database state consulted by write operations.
+type writeEnv struct {
+	// rules, if not nil, are checked before writing.
+	rules   *bucketRules
+	metrics *metrics
+}
+
 // upsert adds the key-value pair to the db at the given path.
 // If the key is already present in the db, then the sum of the existing and given values will be added to the db instead.
-func upsert(db *bbolt.DB, key []byte, val []byte, path [][]byte, add func(a, b []byte) ([]byte, error)) error {
+func upsert(db *bbolt.DB, key []byte, val []byte, path [][]byte, add func(a, b []byte) ([]byte, error), env writeEnv) error {
 	err := db.Batch(func(tx *bbolt.Tx) error {
 		bkt, err := getCreateBucket(tx, path)
 		if err != nil {
 			return fmt.Errorf("error while navigating path: %w", err)
 		}
 
-		oldVal := bkt.Get(key)
+		k, err := env.rules.storeKey(tx, path, key)
+		if err != nil {
+			return err
+		}
+
+		// The merge result is kept local to the closure, as Batch reruns it if another call in the batch fails.
+		v := val
+		oldVal := bkt.Get(k)
 		if oldVal != nil {
-			new, err := add(oldVal, val)
+			if add == nil {
+				return fmt.Errorf("merge func is nil and no default is set")
+			}
+
+			v, err = add(oldVal, val)
 			if err != nil {
 				return fmt.Errorf("error while adding %s and %s: %w", oldVal, val, err)
 			}
-			val = new
 		}
 
-		err = bkt.Put(key, val)
+		if err := env.rules.beforePut(tx, path, k, oldVal, v); err != nil {
+			return err
+		}
+
+		err = bkt.Put(k, v)
 		if err != nil {
 			return fmt.Errorf("error while writing: %w", err)
 		}
 
+		env.metrics.observeWrite(tx, len(k)+len(v))
+
 		return nil
 	})
 
@@ -41,6 +64,67 @@ func upsert(db *bbolt.DB, key []byte, val []byte, path [][]byte, add func(a, b [
 	return nil
 }
 
+// Entry is a key-value pair.
+type Entry struct {
+	Key   []byte
+	Value []byte
+}
+
+// MergeFunc combines the existing value for a key with a newly written one, returning the value to store.
+type MergeFunc func(existing, new []byte) ([]byte, error)
+
+// upsertMany upserts every entry to the db at the given path within a single transaction.
+//
+// Entries sharing a key are merged in order, each seeing the result of the last.
+func upsertMany(db *bbolt.DB, entries []Entry, path [][]byte, add MergeFunc, env writeEnv) error {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateBucket(tx, path)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		for _, e := range entries {
+			val := e.Value
+
+			key, err := env.rules.storeKey(tx, path, e.Key)
+			if err != nil {
+				return err
+			}
+
+			oldVal := bkt.Get(key)
+			if oldVal != nil {
+				if add == nil {
+					return fmt.Errorf("merge func for %s is nil and no default is set", e.Key)
+				}
+
+				new, err := add(oldVal, val)
+				if err != nil {
+					return fmt.Errorf("error while adding %s and %s for %s: %w", oldVal, val, e.Key, err)
+				}
+				val = new
+			}
+
+			if err := env.rules.beforePut(tx, path, key, oldVal, val); err != nil {
+				return err
+			}
+
+			if err := bkt.Put(key, val); err != nil {
+				return fmt.Errorf("error while writing %s: %w", key, err)
+			}
+
+			env.metrics.observeWrite(tx, len(key)+len(val))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("error while upserting %d entries to db: %w", len(entries), err)
+	}
+
+	return nil
+}
+
 // getCreateBucket returns the bucket at the end of the given path, creating buckets if needed.
 //
 // The path will automatically be prepended with the db root.
@@ -61,18 +145,29 @@ func getCreateBucket(tx *bbolt.Tx, path [][]byte) (*bbolt.Bucket, error) {
 }
 
 // insert adds the given key-value pair to the db at the given path.
-func insert(db *bbolt.DB, key, value []byte, path [][]byte) error {
+func insert(db *bbolt.DB, key, value []byte, path [][]byte, env writeEnv) error {
 	err := db.Batch(func(tx *bbolt.Tx) error {
 		bkt, err := getCreateBucket(tx, path)
 		if err != nil {
 			return fmt.Errorf("error while navigating path: %w", err)
 		}
 
-		err = bkt.Put(key, value)
+		k, err := env.rules.storeKey(tx, path, key)
+		if err != nil {
+			return err
+		}
+
+		if err := env.rules.beforePut(tx, path, k, bkt.Get(k), value); err != nil {
+			return err
+		}
+
+		err = bkt.Put(k, value)
 		if err != nil {
 			return fmt.Errorf("error while writing: %w", err)
 		}
 
+		env.metrics.observeWrite(tx, len(k)+len(value))
+
 		return nil
 	})
 
@@ -83,36 +178,124 @@ func insert(db *bbolt.DB, key, value []byte, path [][]byte) error {
 	return nil
 }
 
-// insertValue writes the given value to the db at the given path using an auto-generated key.
-func insertValue(db *bbolt.DB, value []byte, path [][]byte) error {
+// InsertValueOpts configures a value insertion.
+type InsertValueOpts struct {
+	// Sortable generates keys via SortableUint64, so that they iterate in insertion order,
+	// rather than as string-converted integers.
+	//
+	// A bucket's keys should be generated one way or the other, as the two formats don't sort together.
+	Sortable bool
+	// Generator, if not nil, generates keys in place of the bucket's sequence, e.g. NewULIDGenerator.
+	// Sortable is then ignored.
+	//
+	// The insertion fails with ErrDuplicateValue if a generated key is already in use.
+	Generator KeyGenerator
+}
+
+// insertValue writes the given value to the db at the given path using an auto-generated key, returning the key.
+func insertValue(db *bbolt.DB, value []byte, path [][]byte, opts InsertValueOpts, env writeEnv) ([]byte, error) {
+	var key []byte
+
 	err := db.Batch(func(tx *bbolt.Tx) error {
 		bkt, err := getCreateBucket(tx, path)
 		if err != nil {
-			c := withCallerInfo(fmt.Sprintf("value insertion for %v", value), 3)
-			return fmt.Errorf("%s experienced error while navigating path: %w", c, err)
+			return fmt.Errorf("value insertion for %v experienced error while navigating path: %w", value, err)
 		}
 
-		k, _ := bkt.NextSequence()
+		switch {
+		case opts.Generator != nil:
+			if key, err = opts.Generator(); err != nil {
+				return fmt.Errorf("value insertion for %v experienced error while generating key: %w", value, err)
+			}
+			if bkt.Get(key) != nil || bkt.Bucket(key) != nil {
+				return newErrDuplicateValue(fmt.Sprintf("generated key %s at %s", key, path))
+			}
+		case opts.Sortable:
+			k, _ := bkt.NextSequence()
+			key = SortableUint64(k)
+		default:
+			k, _ := bkt.NextSequence()
+			key = []byte(strconv.FormatUint(k, 10))
+		}
+
+		if err := env.rules.beforePut(tx, path, key, nil, value); err != nil {
+			return err
+		}
 
-		err = bkt.Put([]byte(strconv.FormatUint(k, 10)), value)
+		err = bkt.Put(key, value)
 		if err != nil {
-			c := withCallerInfo(fmt.Sprintf("value insertion for %v", value), 3)
-			return fmt.Errorf("%s experienced error while writing: %w", c, err)
+			return fmt.Errorf("value insertion for %v experienced error while writing: %w", value, err)
 		}
 
+		env.metrics.observeWrite(tx, len(key)+len(value))
+
 		return nil
 	})
 
 	if err != nil {
-		c := withCallerInfo(fmt.Sprintf("value insertion for %v", value), 3)
-		return fmt.Errorf("%s experienced error while writing %s to db: %w", c, string(value), err)
+		return nil, fmt.Errorf("value insertion for %v experienced error while writing %s to db: %w", value, string(value), err)
 	}
 
-	return nil
+	return key, nil
+}
+
+// insertValues writes the given values to the db at the given path in a single transaction using auto-generated keys,
+// returning the keys in the order of the values.
+//
+// If gen is nil, a contiguous block of the bucket's sequence is reserved and the keys are string-converted integers.
+func insertValues(db *bbolt.DB, values [][]byte, path [][]byte, gen KeyGenerator, env writeEnv) ([][]byte, error) {
+	var keys [][]byte
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := getCreateBucket(tx, path)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		keys = make([][]byte, len(values))
+
+		first := bkt.Sequence() + 1
+		if gen == nil {
+			if err := bkt.SetSequence(bkt.Sequence() + uint64(len(values))); err != nil {
+				return fmt.Errorf("error while reserving %d sequence numbers: %w", len(values), err)
+			}
+		}
+
+		for i, value := range values {
+			if gen == nil {
+				keys[i] = []byte(strconv.FormatUint(first+uint64(i), 10))
+			} else {
+				if keys[i], err = gen(); err != nil {
+					return fmt.Errorf("error while generating key: %w", err)
+				}
+				if bkt.Get(keys[i]) != nil || bkt.Bucket(keys[i]) != nil {
+					return newErrDuplicateValue(fmt.Sprintf("generated key %s at %s", keys[i], path))
+				}
+			}
+
+			if err := env.rules.beforePut(tx, path, keys[i], nil, value); err != nil {
+				return err
+			}
+
+			if err := bkt.Put(keys[i], value); err != nil {
+				return fmt.Errorf("error while writing %s: %w", keys[i], err)
+			}
+
+			env.metrics.observeWrite(tx, len(keys[i])+len(value))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error while inserting %d values to db: %w", len(values), err)
+	}
+
+	return keys, nil
 }
 
 // insertBucket creates a bucket of the given key at the given path.
-func insertBucket(db *bbolt.DB, key []byte, path [][]byte) error {
+func insertBucket(db *bbolt.DB, key []byte, path [][]byte, env writeEnv) error {
 	err := db.Batch(func(tx *bbolt.Tx) error {
 		bkt, err := getCreateBucket(tx, path)
 		if err != nil {
@@ -124,6 +307,8 @@ func insertBucket(db *bbolt.DB, key []byte, path [][]byte) error {
 			return fmt.Errorf("error while creating bucket: %w", err)
 		}
 
+		env.metrics.observeWrite(tx, len(key))
+
 		return nil
 	})
 
@@ -135,14 +320,24 @@ func insertBucket(db *bbolt.DB, key []byte, path [][]byte) error {
 }
 
 // delete removes the key-value pair in the db at the given path.
-func delete(db *bbolt.DB, key []byte, path [][]byte) error {
+func delete(db *bbolt.DB, key []byte, path [][]byte, env writeEnv) error {
 	err := db.Batch(func(tx *bbolt.Tx) error {
 		bkt, err := getCreateBucket(tx, path)
 		if err != nil {
 			return fmt.Errorf("error while navigating path: %w", err)
 		}
 
-		return bkt.Delete(key)
+		if err := env.rules.beforeDelete(tx, path, key, bkt.Get(key)); err != nil {
+			return err
+		}
+
+		if err := bkt.Delete(key); err != nil {
+			return err
+		}
+
+		env.metrics.observeWrite(tx, 0)
+
+		return nil
 	})
 
 	if err != nil {
@@ -152,14 +347,79 @@ func delete(db *bbolt.DB, key []byte, path [][]byte) error {
 	return nil
 }
 
-func deleteBucket(db *bbolt.DB, bucket []byte, path [][]byte) error {
+// deleteKeys removes the given keys from the db at the given path within a single transaction.
+//
+// Report holds an entry per key, to which the error keeping the key from being removed, such as an ErrLocate
+// for a missing key, is written. Keys whose entry is already set are skipped.
+func deleteKeys(db *bbolt.DB, keys [][]byte, path [][]byte, report []error, env writeEnv) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := getBucket(tx, path, false)
+		if err != nil {
+			return fmt.Errorf("error while navigating path: %w", err)
+		}
+
+		for i, key := range keys {
+			if report[i] != nil {
+				continue
+			}
+
+			var old []byte
+			if bkt != nil {
+				old = bkt.Get(key)
+			}
+
+			if old == nil {
+				if bkt != nil && bkt.Bucket(key) != nil {
+					report[i] = fmt.Errorf("key %s at %s names a bucket: %w", key, path, bbolt.ErrIncompatibleValue)
+				} else {
+					report[i] = newErrLocate(fmt.Sprintf("key %s at %s", key, path))
+				}
+				continue
+			}
+
+			if err := env.rules.beforeDelete(tx, path, key, old); err != nil {
+				return err
+			}
+
+			if err := bkt.Delete(key); err != nil {
+				return fmt.Errorf("error while deleting %s: %w", key, err)
+			}
+
+			env.metrics.observeWrite(tx, 0)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("error while deleting %d keys from db: %w", len(keys), err)
+	}
+
+	return nil
+}
+
+func deleteBucket(db *bbolt.DB, bucket []byte, path [][]byte, env writeEnv) error {
 	err := db.Batch(func(tx *bbolt.Tx) error {
 		bkt, err := getCreateBucket(tx, path)
 		if err != nil {
 			return fmt.Errorf("error while navigating path: %w", err)
 		}
 
-		return bkt.DeleteBucket(bucket)
+		if err := deleteIndexTree(tx, append(append([][]byte{}, path...), bucket)); err != nil {
+			return fmt.Errorf("error while removing indexes: %w", err)
+		}
+
+		if err := bkt.DeleteBucket(bucket); err != nil {
+			return err
+		}
+
+		env.metrics.observeWrite(tx, 0)
+
+		return nil
 	})
 
 	if err != nil {
@@ -170,7 +430,7 @@ func deleteBucket(db *bbolt.DB, bucket []byte, path [][]byte) error {
 }
 
 // deleteValues removes all key-value pairs in the db at the given path where the value matches the one given.
-func deleteValues(db *bbolt.DB, value []byte, path [][]byte) error {
+func deleteValues(db *bbolt.DB, value []byte, path [][]byte, env writeEnv) error {
 	if db == nil {
 		return fmt.Errorf("db is nil")
 	}
@@ -192,12 +452,18 @@ func deleteValues(db *bbolt.DB, value []byte, path [][]byte) error {
 	for k, v := c.First(); k != nil; k, v = c.Next() {
 
 		if slices.Equal(v, value) {
+			if err := env.rules.beforeDelete(tx, path, k, v); err != nil {
+				return err
+			}
+
 			if err := c.Delete(); err != nil {
 				return fmt.Errorf("error while deleting key %s: %w", string(k), err)
 			}
 		}
 	}
 
+	env.metrics.observeWrite(tx, 0)
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error while committing entry removals: %w", err)
 	}
@@ -1,7 +1,12 @@
 package quickbolt
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,6 +23,7 @@ func Test_insertValue(t *testing.T) {
 		db    *bbolt.DB
 		value []byte
 		path  [][]byte
+		opts  InsertValueOpts
 	}
 	type check struct {
 		key   []byte
@@ -31,12 +37,15 @@ func Test_insertValue(t *testing.T) {
 		check   check
 	}{
 		{name: "Basic", args: args{db: db, value: []byte("test-value"), path: [][]byte{}}, wantErr: false, check: check{key: []byte("1"), value: []byte("test-value"), path: [][]byte{}}},
+		{name: "Sortable", args: args{db: db, value: []byte("sorted"), path: [][]byte{}, opts: InsertValueOpts{Sortable: true}}, wantErr: false, check: check{key: SortableUint64(2), value: []byte("sorted"), path: [][]byte{}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := insertValue(tt.args.db, tt.args.value, tt.args.path); (err != nil) != tt.wantErr {
+			key, err := insertValue(tt.args.db, tt.args.value, tt.args.path, tt.args.opts, writeEnv{})
+			if (err != nil) != tt.wantErr {
 				t.Errorf("insertValue() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			assert.Equal(t, tt.check.key, key)
 
 			b, err := getValue(tt.args.db, tt.check.key, tt.args.path, true)
 			if !tt.wantErr {
@@ -47,3 +56,311 @@ func Test_insertValue(t *testing.T) {
 		})
 	}
 }
+
+func Test_upsertMany(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "foo.db"), 0600, nil)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	concat := func(a, b []byte) ([]byte, error) {
+		return append(append([]byte{}, a...), b...), nil
+	}
+	reject := func(a, b []byte) ([]byte, error) {
+		return nil, fmt.Errorf("rejected")
+	}
+	path := [][]byte{[]byte("counters")}
+
+	err = upsertMany(db, []Entry{
+		{Key: []byte("a"), Value: []byte("1")},
+		{Key: []byte("b"), Value: []byte("1")},
+		{Key: []byte("a"), Value: []byte("2")},
+	}, path, concat, writeEnv{})
+	assert.Nil(t, err)
+
+	v, err := getValue(db, []byte("a"), path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "12", string(v))
+
+	err = upsertMany(db, []Entry{
+		{Key: []byte("c"), Value: []byte("1")},
+		{Key: []byte("a"), Value: []byte("3")},
+	}, path, reject, writeEnv{})
+	assert.NotNil(t, err)
+
+	v, err = getValue(db, []byte("c"), path, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v, "failed upsert must not leave partial writes")
+}
+
+func Test_dbWrapper_SetDefaultMerge(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"counters"}
+
+	assert.Nil(t, db.Upsert("k", "a", path, nil))
+	assert.NotNil(t, db.Upsert("k", "b", path, nil), "nil merge without a default must fail for existing keys")
+
+	db.SetDefaultMerge(func(a, b []byte) ([]byte, error) {
+		return append(append([]byte{}, a...), b...), nil
+	})
+
+	assert.Nil(t, db.Upsert("k", "b", path, nil))
+	assert.Nil(t, db.UpsertMany([]Entry{{Key: []byte("k"), Value: []byte("c")}}, path, nil))
+	assert.Nil(t, db.Apply([]Op{UpsertOp("k", "d", path, nil)}))
+
+	v, err := db.GetValue("k", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "abcd", string(v))
+}
+
+func Test_dbWrapper_RunBatch(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := db.RunBatch(func(tx *bbolt.Tx) error {
+				bkt, err := getCreateBucket(tx, [][]byte{[]byte("batch")})
+				if err != nil {
+					return err
+				}
+				return bkt.Put([]byte(fmt.Sprintf("k%02d", i)), []byte("v"))
+			})
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	keys, err := db.GetKeys("v", []string{"batch"}, true)
+	assert.Nil(t, err)
+	assert.Len(t, keys, n)
+
+	stats := db.Stats()
+	assert.Equal(t, uint64(n), stats.Writes)
+	assert.Less(t, stats.Commits, uint64(n))
+
+	abort := errors.New("abort")
+	err = db.RunBatch(func(tx *bbolt.Tx) error { return abort })
+	assert.ErrorIs(t, err, abort)
+}
+
+func Test_dbWrapper_InsertValueWith(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"sorted"}
+	for i := 1; i <= 12; i++ {
+		key, err := db.InsertValueWith(fmt.Sprint(i), path, InsertValueOpts{Sortable: true})
+		assert.Nil(t, err)
+
+		seq, err := ParseSortableUint64(key)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(i), seq)
+	}
+
+	buffer := make(chan [2][]byte, 20)
+	assert.Nil(t, db.EntriesAt(path, false, buffer))
+
+	want := uint64(1)
+	for e := range buffer {
+		seq, err := ParseSortableUint64(e[0])
+		assert.Nil(t, err)
+		assert.Equal(t, want, seq)
+		assert.Equal(t, fmt.Sprint(want), string(e[1]))
+		want++
+	}
+	assert.Equal(t, uint64(13), want)
+}
+
+func Test_dbWrapper_InsertValueWithGenerator(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"events"}
+	gen := NewULIDGenerator()
+
+	var keys [][]byte
+	for i := 0; i < 5; i++ {
+		key, err := db.InsertValueWith(i, path, InsertValueOpts{Generator: gen})
+		assert.Nil(t, err)
+		keys = append(keys, key)
+	}
+
+	v, err := db.GetValue(keys[3], path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "3", string(v))
+
+	repeat := func() ([]byte, error) { return keys[0], nil }
+	_, err = db.InsertValueWith("again", path, InsertValueOpts{Generator: repeat})
+	var duplicate ErrDuplicateValue
+	assert.ErrorAs(t, err, &duplicate)
+}
+
+func Test_dbWrapper_InsertValues(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"bulk"}
+	assert.Nil(t, db.InsertValue("first", path))
+
+	const writers, perWriter = 4, 25
+	results := make([][][]byte, writers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			values := make([][]byte, perWriter)
+			for i := range values {
+				values[i] = []byte(fmt.Sprintf("%d-%d", w, i))
+			}
+
+			keys, err := db.InsertValues(values, path)
+			assert.Nil(t, err)
+			results[w] = keys
+		}(w)
+	}
+	wg.Wait()
+
+	for w, keys := range results {
+		assert.Len(t, keys, perWriter)
+
+		first, err := strconv.Atoi(string(keys[0]))
+		assert.Nil(t, err)
+		for i, k := range keys {
+			assert.Equal(t, strconv.Itoa(first+i), string(k), "keys of writer %d should be contiguous", w)
+
+			v, err := db.GetValue(k, path, true)
+			assert.Nil(t, err)
+			assert.Equal(t, fmt.Sprintf("%d-%d", w, i), string(v))
+		}
+	}
+
+	assert.Nil(t, db.InsertValue("last", path))
+	v, err := db.GetValue(strconv.Itoa(writers*perWriter+2), path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "last", string(v))
+}
+
+func Test_dbWrapper_InsertMarshaler(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	assert.Nil(t, db.Insert(quickboltID(2), quickboltID(20), []any{"ids", quickboltID(1)}))
+
+	v, err := db.GetValue(quickboltID(2), []any{"ids", quickboltID(1)}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, SortableInt64(20), v)
+
+	assert.Nil(t, db.Delete(quickboltID(2), []any{"ids", quickboltID(1)}))
+	v, err = db.GetValue(quickboltID(2), []any{"ids", quickboltID(1)}, false)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	var resolution ErrRecordResolution
+	assert.ErrorAs(t, db.Insert(quickboltID(-1), "v", []string{"ids"}), &resolution)
+}
+
+func Test_dbWrapper_DeleteKeys(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"bulk"}
+	for _, k := range []string{"a", "b", "c"} {
+		assert.Nil(t, db.Insert(k, "v", path))
+	}
+	assert.Nil(t, db.InsertBucket("nested", path))
+
+	report, err := db.DeleteKeys([]any{"a", "missing", struct{}{}, "nested", "c"}, path)
+	assert.Nil(t, err)
+	assert.Len(t, report, 5)
+
+	assert.Nil(t, report[0])
+	assert.ErrorIs(t, report[1], ErrLocate{})
+	var resolution ErrRecordResolution
+	assert.ErrorAs(t, report[2], &resolution)
+	assert.ErrorIs(t, report[3], bbolt.ErrIncompatibleValue)
+	assert.Nil(t, report[4])
+
+	for k, want := range map[string]bool{"a": false, "b": true, "c": false} {
+		has, err := db.HasKey(k, path)
+		assert.Nil(t, err)
+		assert.Equal(t, want, has, "presence of %s", k)
+	}
+	has, err := db.HasBucket([]string{"bulk", "nested"})
+	assert.Nil(t, err)
+	assert.True(t, has)
+
+	report, err = db.DeleteKeys([]any{"a"}, []string{"absent"})
+	assert.Nil(t, err)
+	assert.ErrorIs(t, report[0], ErrLocate{})
+}
+
+func Test_dbWrapper_UpsertRetriedInBatch(t *testing.T) {
+	db, err := CreateWith("foo.db", t.TempDir())
+	assert.Nil(t, err)
+
+	defer db.RemoveFile()
+
+	path := []string{"b"}
+	assert.Nil(t, db.RegisterValidator(path, func(k, v []byte) error {
+		if string(k) == "rejected" {
+			return fmt.Errorf("key is rejected")
+		}
+		return nil
+	}))
+
+	sum := func(a, b []byte) ([]byte, error) {
+		x, err := strconv.Atoi(string(a))
+		if err != nil {
+			return nil, err
+		}
+		y, err := strconv.Atoi(string(b))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.Itoa(x + y)), nil
+	}
+
+	const upserts = 200
+
+	// Rejected inserts fail their batches, which bbolt then reruns without them.
+	var wg sync.WaitGroup
+	for i := 0; i < upserts; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, db.Upsert("n", "1", path, sum))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NotNil(t, db.Insert("rejected", "1", path))
+		}()
+	}
+	wg.Wait()
+
+	v, err := db.GetValue("n", path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, strconv.Itoa(upserts), string(v))
+}